@@ -30,8 +30,58 @@ func main() {
 
 	drv, err := driver.NewDriver(
 		driver.WithEndpoint(options.ServerOptions.Endpoint),
+		driver.WithDebugEndpoint(options.ServerOptions.DebugEndpoint),
+		driver.WithMetricsEndpoint(options.ServerOptions.MetricsEndpoint),
+		driver.WithRequireEncryption(options.ServerOptions.RequireEncryption),
+		driver.WithShutdownDrainTimeout(options.ServerOptions.ShutdownDrainTimeout),
 		driver.WithExtraVolumeTags(options.ControllerOptions.ExtraVolumeTags),
+		driver.WithExtraSnapshotTags(options.ControllerOptions.ExtraSnapshotTags),
+		driver.WithInheritSnapshotTags(options.ControllerOptions.InheritSnapshotTags),
+		driver.WithDefaultVolumeParameters(options.ControllerOptions.DefaultVolumeParameters),
+		driver.WithZoneVolumeTypes(options.ControllerOptions.ZoneVolumeTypes),
+		driver.WithClusterID(options.ControllerOptions.ClusterID),
+		driver.WithVolumeNamePrefix(options.ControllerOptions.VolumeNamePrefix),
+		driver.WithDisableSnapshots(options.ControllerOptions.DisableSnapshots),
+		driver.WithRetryBudget(options.ControllerOptions.RetryBudget),
+		driver.WithOAPITimeout(options.ControllerOptions.OAPITimeout),
+		driver.WithOAPIMaxRetries(options.ControllerOptions.OAPIMaxRetries),
+		driver.WithOscProfile(options.ControllerOptions.OscConfigFile, options.ControllerOptions.OscProfile),
+		driver.WithDetachSettleDuration(options.ControllerOptions.DetachSettleDuration),
+		driver.WithDetachBusyRetries(options.ControllerOptions.DetachBusyRetries),
+		driver.WithMaxVolumeSize(options.ControllerOptions.MaxVolumeSize.Value()),
+		driver.WithQuotaRetryInterval(options.ControllerOptions.QuotaRetryInterval),
+		driver.WithVolumeCloning(options.ControllerOptions.EnableVolumeCloning),
+		driver.WithMountProfilesFile(options.ControllerOptions.MountProfilesFile),
+		driver.WithPerformanceClassesFile(options.ControllerOptions.PerformanceClassesFile),
+		driver.WithMaxConcurrentAttachesPerNode(options.ControllerOptions.MaxConcurrentAttachesPerNode),
+		driver.WithSkipDetachStoppedNodes(options.ControllerOptions.SkipDetachStoppedNodes),
 		driver.WithMode(options.DriverMode),
+		driver.WithSnapshotScheduler(options.ControllerOptions.EnableSnapshotScheduler),
+		driver.WithSnapshotScheduleInterval(options.ControllerOptions.SnapshotScheduleInterval),
+		driver.WithSnapshotScheduleRetention(options.ControllerOptions.SnapshotScheduleRetention),
+		driver.WithVolumeReaper(options.ControllerOptions.EnableVolumeReaper),
+		driver.WithVolumeReaperGracePeriod(options.ControllerOptions.VolumeReaperGracePeriod),
+		driver.WithVolumeReaperDryRun(options.ControllerOptions.VolumeReaperDryRun),
+		driver.WithAttachmentReconciler(options.ControllerOptions.EnableAttachmentReconciler),
+		driver.WithAttachmentReconcilerDryRun(options.ControllerOptions.AttachmentReconcilerDryRun),
+		driver.WithHideOrphanedSnapshots(options.ControllerOptions.HideOrphanedSnapshots),
+		driver.WithCascadeDeleteEndpoint(options.ControllerOptions.CascadeDeleteEndpoint),
+		driver.WithDevicePathPollInterval(options.NodeOptions.DevicePathPollInterval),
+		driver.WithDevicePathTimeout(options.NodeOptions.DevicePathTimeout),
+		driver.WithExcludeReservedBlocks(options.NodeOptions.ExcludeReservedBlocks),
+		driver.WithSecretProviderURL(options.NodeOptions.SecretProviderURL),
+		driver.WithFSGroupPolicy(options.NodeOptions.FSGroupPolicy),
+		driver.WithLuksOpenRetries(options.NodeOptions.LuksOpenRetries),
+		driver.WithLuksOpenRetryDelay(options.NodeOptions.LuksOpenRetryDelay),
+		driver.WithDisableStaging(options.NodeOptions.DisableStaging),
+		driver.WithAllowFsTypeMismatch(options.NodeOptions.AllowFsTypeMismatch),
+		driver.WithStrictDeviceSizeCheck(options.NodeOptions.StrictDeviceSizeCheck),
+		driver.WithReportNodeTopology(options.NodeOptions.ReportNodeTopology),
+		driver.WithReportRegionTopology(options.NodeOptions.ReportRegionTopology),
+		driver.WithInFlightMaxAge(options.NodeOptions.InFlightMaxAge),
+		driver.WithMountByUUID(options.NodeOptions.MountByUUID),
+		driver.WithFormatTimeout(options.NodeOptions.FormatTimeout),
+		driver.WithFsResizeTolerance(options.NodeOptions.FsResizeTolerance.Value()),
 	)
 	if err != nil {
 		klog.Fatalln(err)
@@ -18,7 +18,11 @@ package options
 
 import (
 	"flag"
+	"time"
 
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver"
+	"k8s.io/apimachinery/pkg/api/resource"
 	cliflag "k8s.io/component-base/cli/flag"
 )
 
@@ -27,8 +31,101 @@ type ControllerOptions struct {
 	// ExtraVolumeTags is a map of tags that will be attached to each dynamically provisioned
 	// volume.
 	ExtraVolumeTags map[string]string
+	// ExtraSnapshotTags is a map of tags that will be attached to each snapshot created by the driver.
+	ExtraSnapshotTags map[string]string
+	// InheritSnapshotTags are the keys of the tags of the source volume copied onto its snapshots.
+	InheritSnapshotTags []string
+	// DefaultVolumeParameters is a map of parameters merged under the parameters of each CreateVolume request.
+	DefaultVolumeParameters map[string]string
+	// ZoneVolumeTypes maps the availability zones to the default type of their volumes.
+	ZoneVolumeTypes map[string]string
+	// ClusterID is the ID of the Kubernetes cluster, added as a tag on each volume and snapshot.
+	ClusterID string
+	// VolumeNamePrefix is prepended to the name of the volumes in their name tag.
+	VolumeNamePrefix string
+	// DisableSnapshots removes the snapshot capabilities of the controller.
+	DisableSnapshots bool
+	// RetryBudget is the number of retries of throttled requests allowed per minute across all the cloud operations.
+	RetryBudget int
+	// OAPITimeout is the timeout of the HTTP requests sent to the Outscale API.
+	OAPITimeout time.Duration
+	// OAPIMaxRetries is the maximum number of retries of a failed request to the Outscale API.
+	OAPIMaxRetries int
+	// OscConfigFile is the path of the Outscale config file holding the profiles.
+	OscConfigFile string
+	// OscProfile is the profile of the Outscale config file used instead of the environment.
+	OscProfile string
+	// DetachSettleDuration is how long ControllerUnpublishVolume waits once the volume is detached.
+	DetachSettleDuration time.Duration
+	// DetachBusyRetries is the number of retries of the detachment of a volume still in use by its node.
+	DetachBusyRetries int
+	// MaxVolumeSize is the largest volume size accepted by CreateVolume. Zero means no limit.
+	MaxVolumeSize resource.QuantityValue
+	// QuotaRetryInterval is the delay suggested to the provisioner before retrying a volume creation rejected by a quota.
+	QuotaRetryInterval time.Duration
+	// EnableVolumeCloning advertises the CLONE_VOLUME capability.
+	EnableVolumeCloning bool
+	// MountProfilesFile is the path of the JSON file mapping the mount profile names to their mount flags.
+	MountProfilesFile string
+	// PerformanceClassesFile is the path of the JSON file mapping the performance class names to a volume type and IOPS.
+	PerformanceClassesFile string
+	// MaxConcurrentAttachesPerNode is the number of volumes attached concurrently to the same node. Zero means no limit.
+	MaxConcurrentAttachesPerNode int
+	// SkipDetachStoppedNodes returns from ControllerUnpublishVolume without waiting for the detachment when the node is stopped.
+	SkipDetachStoppedNodes bool
+	// EnableSnapshotScheduler enables the periodic snapshots of the PVs annotated with a snapshot schedule.
+	EnableSnapshotScheduler bool
+	// SnapshotScheduleInterval is the interval between two passes of the snapshot scheduler.
+	SnapshotScheduleInterval time.Duration
+	// SnapshotScheduleRetention is the number of scheduled snapshots kept per volume.
+	SnapshotScheduleRetention int
+	// EnableVolumeReaper enables the deletion of the volumes created by the driver but never bound to a PV.
+	EnableVolumeReaper bool
+	// VolumeReaperGracePeriod is the age under which a volume is never reaped.
+	VolumeReaperGracePeriod time.Duration
+	// VolumeReaperDryRun only logs the volumes which would be reaped.
+	VolumeReaperDryRun bool
+	// EnableAttachmentReconciler enables the detection at startup of the volumes attached without VolumeAttachment.
+	EnableAttachmentReconciler bool
+	// AttachmentReconcilerDryRun only logs the zombie attachments.
+	AttachmentReconcilerDryRun bool
+	// HideOrphanedSnapshots excludes from ListSnapshots the snapshots whose source volume does not exist anymore.
+	HideOrphanedSnapshots bool
+	// CascadeDeleteEndpoint is the loopback address serving the deletion of a volume with its snapshots. It is disabled when empty.
+	CascadeDeleteEndpoint string
 }
 
 func (s *ControllerOptions) AddFlags(fs *flag.FlagSet) {
 	fs.Var(cliflag.NewMapStringString(&s.ExtraVolumeTags), "extra-volume-tags", "Extra volume tags to attach to each dynamically provisioned volume. It is a comma separated list of key value pairs like '<key1>=<value1>,<key2>=<value2>'")
+	fs.Var(cliflag.NewMapStringString(&s.ExtraSnapshotTags), "extra-snapshot-tags", "Extra snapshot tags to attach to each snapshot. It is a comma separated list of key value pairs like '<key1>=<value1>,<key2>=<value2>'")
+	fs.Var(cliflag.NewStringSlice(&s.InheritSnapshotTags), "inherit-snapshot-tag", "Key of a tag of the source volume copied onto its snapshots, e.g. 'cost-center'. It can be repeated to copy several tags")
+	fs.Var(cliflag.NewMapStringString(&s.DefaultVolumeParameters), "default-volume-parameters", "Default parameters of the dynamically provisioned volumes, overridden by the StorageClass parameters. It is a comma separated list of key value pairs like '<key1>=<value1>,<key2>=<value2>'")
+	fs.Var(cliflag.NewMapStringString(&s.ZoneVolumeTypes), "zone-volume-types", "Default volume type of each availability zone, used when the StorageClass does not set a type. It is a comma separated list of key value pairs like '<zone1>=<type1>,<zone2>=<type2>'. The other zones use the default volume type")
+	fs.StringVar(&s.ClusterID, "cluster-id", "", "ID of the Kubernetes cluster, added as the '"+cloud.ClusterIDTagKey+"' tag on each volume and snapshot. When set, ListSnapshots only returns the snapshots of this cluster")
+	fs.StringVar(&s.VolumeNamePrefix, "volume-name-prefix", "", "Prefix prepended to the name of the volumes in their '"+cloud.VolumeNameTagKey+"' tag")
+	fs.BoolVar(&s.DisableSnapshots, "disable-snapshots", false, "Disable the creation, deletion and listing of snapshots")
+	fs.IntVar(&s.RetryBudget, "retry-budget", 0, "Number of retries of throttled requests allowed per minute across all the cloud operations. 0 means unlimited")
+	fs.DurationVar(&s.OAPITimeout, "oapi-timeout", 0, "Timeout of the HTTP requests sent to the Outscale API. 0 means no timeout")
+	fs.IntVar(&s.OAPIMaxRetries, "oapi-max-retries", 0, "Maximum number of retries of a failed request to the Outscale API. 0 uses the BACKOFF_STEPS environment variable")
+	fs.StringVar(&s.OscConfigFile, "osc-config-file", "", "Path of the Outscale config file holding the profiles selected by --osc-profile. Defaults to ~/.osc/config.json")
+	fs.StringVar(&s.OscProfile, "osc-profile", "", "Profile of the Outscale config file whose endpoint, region and credentials are used instead of the OSC_ACCESS_KEY and OSC_SECRET_KEY environment variables and of the region of the node")
+	fs.DurationVar(&s.DetachSettleDuration, "detach-settle-duration", 0, "Time to wait once a volume is detached, so that the device is fully released before the volume is attached to another node. 0 does not wait")
+	fs.IntVar(&s.DetachBusyRetries, "detach-busy-retries", 0, "Number of retries of the detachment of a volume still in use by its node, e.g. because its filesystem is not fully unmounted yet. The first retry waits 2s, each next one twice as long")
+	fs.Var(&s.MaxVolumeSize, "max-volume-size", "Largest volume size accepted by CreateVolume, after the size is rounded up to the next GiB (e.g. '2Ti'). The larger requests are rejected with OutOfRange. 0 means no limit")
+	fs.DurationVar(&s.QuotaRetryInterval, "quota-retry-interval", 0, "Delay suggested to the provisioner before retrying a volume creation rejected by a quota. 0 does not suggest any delay")
+	fs.BoolVar(&s.EnableVolumeCloning, "enable-volume-cloning", false, "Advertise the CLONE_VOLUME capability to the external-provisioner")
+	fs.StringVar(&s.MountProfilesFile, "mount-profiles-file", "", "Path of the JSON file mapping the mount profile names to their mount flags, like '{\"<profile>\": [\"<flag1>\", \"<flag2>\"]}'")
+	fs.StringVar(&s.PerformanceClassesFile, "performance-classes-file", "", "Path of the JSON file mapping the performance class names to a volume type and IOPS per GiB, like '{\"<class>\": {\"type\": \"io1\", \"iopsPerGB\": 50}}'")
+	fs.IntVar(&s.MaxConcurrentAttachesPerNode, "max-concurrent-attaches-per-node", 0, "Maximum number of volumes attached concurrently to the same node, the other ControllerPublishVolume calls for the node wait. 0 means no limit")
+	fs.BoolVar(&s.SkipDetachStoppedNodes, "skip-detach-stopped-nodes", false, "Do not wait for the detachment of the volumes of a stopped node, their unlink is only requested")
+	fs.BoolVar(&s.EnableSnapshotScheduler, "enable-snapshot-scheduler", false, "Periodically snapshot the PVs annotated with '"+driver.SnapshotScheduleAnnotation+"'")
+	fs.DurationVar(&s.SnapshotScheduleInterval, "snapshot-schedule-interval", driver.DefaultSnapshotScheduleInterval, "Interval between two passes of the snapshot scheduler")
+	fs.IntVar(&s.SnapshotScheduleRetention, "snapshot-schedule-retention", driver.DefaultSnapshotScheduleRetention, "Number of scheduled snapshots kept per volume. 0 disables the pruning")
+	fs.BoolVar(&s.EnableVolumeReaper, "enable-volume-reaper", false, "Periodically look for the volumes of the cluster created by the driver but never bound to a PV (marked with the '"+cloud.BoundTagKey+"' tag once bound), and delete them unless --volume-reaper-dry-run is set. Requires --cluster-id")
+	fs.DurationVar(&s.VolumeReaperGracePeriod, "volume-reaper-grace-period", driver.DefaultVolumeReaperGracePeriod, "Age under which a volume is never reaped")
+	fs.BoolVar(&s.VolumeReaperDryRun, "volume-reaper-dry-run", true, "Only log the volumes which would be deleted by the volume reaper")
+	fs.BoolVar(&s.EnableAttachmentReconciler, "enable-attachment-reconciler", false, "At startup, look for the volumes of the cluster attached to a node without VolumeAttachment, and detach them unless --attachment-reconciler-dry-run is set. Requires --cluster-id")
+	fs.BoolVar(&s.AttachmentReconcilerDryRun, "attachment-reconciler-dry-run", true, "Only log the attachments which would be detached by the attachment reconciler")
+	fs.BoolVar(&s.HideOrphanedSnapshots, "hide-orphaned-snapshots", false, "Exclude from ListSnapshots the snapshots whose source volume does not exist anymore. It looks up the source volume of each listed snapshot")
+	fs.StringVar(&s.CascadeDeleteEndpoint, "cascade-delete-endpoint", "", "Loopback address of the HTTP endpoint serving 'DELETE /volumes/<volumeID>', deleting the snapshots of the volume then the volume (e.g. 'localhost:8091'). Disabled when empty")
 }
@@ -32,6 +32,151 @@ func TestControllerOptions(t *testing.T) {
 			flag:  "extra-volume-tags",
 			found: true,
 		},
+		{
+			name:  "lookup desired flag",
+			flag:  "extra-snapshot-tags",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "inherit-snapshot-tag",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "disable-snapshots",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "retry-budget",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "enable-volume-cloning",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "mount-profiles-file",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "performance-classes-file",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "oapi-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "oapi-max-retries",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "detach-busy-retries",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "osc-config-file",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "osc-profile",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "detach-settle-duration",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "quota-retry-interval",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "default-volume-parameters",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "zone-volume-types",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "cluster-id",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "volume-name-prefix",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "skip-detach-stopped-nodes",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "enable-snapshot-scheduler",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "enable-volume-reaper",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "cascade-delete-endpoint",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "hide-orphaned-snapshots",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "max-volume-size",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "max-concurrent-attaches-per-node",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "volume-reaper-grace-period",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "volume-reaper-dry-run",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "enable-attachment-reconciler",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "attachment-reconciler-dry-run",
+			found: true,
+		},
 		{
 			name:  "fail for non-desired flag",
 			flag:  "some-other-flag",
@@ -18,9 +18,64 @@ package options
 
 import (
 	"flag"
+	"time"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 // NodeOptions contains options and configuration settings for the node service.
-type NodeOptions struct{}
+type NodeOptions struct {
+	// DevicePathPollInterval is the interval between two lookups of the device path during NodeStageVolume.
+	DevicePathPollInterval time.Duration
+	// DevicePathTimeout is the maximum time to wait for the device path to show up during NodeStageVolume.
+	DevicePathTimeout time.Duration
+	// ExcludeReservedBlocks removes the blocks reserved to root from the total bytes reported by NodeGetVolumeStats.
+	ExcludeReservedBlocks bool
+	// SecretProviderURL is the URL of the external secret store providing the LUKS passphrases.
+	SecretProviderURL string
+	// LuksOpenRetries is how many times NodeStageVolume retries to open a busy LUKS device.
+	LuksOpenRetries int
+	// LuksOpenRetryDelay is the delay between two attempts to open a busy LUKS device.
+	LuksOpenRetryDelay time.Duration
+	// FSGroupPolicy defines when the node applies the fsGroup of the pods to the staged volumes.
+	FSGroupPolicy string
+	// DisableStaging makes the node mount the volumes directly in NodePublishVolume.
+	DisableStaging bool
+	// AllowFsTypeMismatch mounts a volume with its existing filesystem when it does not match the requested fstype.
+	AllowFsTypeMismatch bool
+	// StrictDeviceSizeCheck fails the staging when the device is smaller than the volume.
+	StrictDeviceSizeCheck bool
+	// ReportNodeTopology adds the instance ID of the node to its topology.
+	ReportNodeTopology bool
+	// ReportRegionTopology adds the region of the node to its topology.
+	ReportRegionTopology bool
+	// InFlightMaxAge is the age after which a request still in flight is evicted.
+	InFlightMaxAge time.Duration
+	// MountByUUID mounts the staged volumes by the UUID of their filesystem instead of their device path.
+	MountByUUID bool
+	// FormatTimeout is the maximum duration of the format of a volume, 0 leaves it unbounded.
+	FormatTimeout time.Duration
+	// FsResizeTolerance is the difference between the sizes of the device and of its filesystem under which
+	// NodeExpandVolume considers the filesystem expanded.
+	FsResizeTolerance resource.QuantityValue
+}
 
-func (s *NodeOptions) AddFlags(fs *flag.FlagSet) {}
+func (s *NodeOptions) AddFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&s.DevicePathPollInterval, "device-path-poll-interval", driver.DefaultDevicePathPollInterval, "Interval between two lookups of the device path when staging a volume")
+	fs.DurationVar(&s.DevicePathTimeout, "device-path-timeout", driver.DefaultDevicePathTimeout, "Maximum time to wait for the device path to show up when staging a volume. 0 disables the wait")
+	fs.BoolVar(&s.ExcludeReservedBlocks, "exclude-reserved-blocks", false, "Report the volume capacity without the blocks reserved to root on ext filesystems, to match the space usable by the pods")
+	fs.StringVar(&s.SecretProviderURL, "secret-provider-url", "", "URL of an external secret store providing the LUKS passphrases: GET <url>/<volume ID> must return the passphrase of the volume. By default, the passphrases are read from the request secrets")
+	fs.IntVar(&s.LuksOpenRetries, "luks-open-retries", driver.DefaultLuksOpenRetries, "Number of retries to open a LUKS device still busy after its attachment")
+	fs.DurationVar(&s.LuksOpenRetryDelay, "luks-open-retry-delay", driver.DefaultLuksOpenRetryDelay, "Delay between two attempts to open a busy LUKS device")
+	fs.StringVar(&s.FSGroupPolicy, "fs-group-policy", "", "Policy used by the node to apply the fsGroup of the pods to the staged volumes instead of the kubelet (ReadWriteOnceWithFSType, File or None). Empty leaves the fsGroup to the kubelet")
+	fs.BoolVar(&s.DisableStaging, "disable-staging", false, "Do not advertise the STAGE_UNSTAGE_VOLUME capability: the volumes are formatted and mounted directly at the target path of the pods. Encrypted volumes require staging, and so do --mount-by-uuid and --format-timeout")
+	fs.BoolVar(&s.AllowFsTypeMismatch, "allow-fs-type-mismatch", false, "Mount a volume with its existing filesystem when it does not match the fstype requested by the StorageClass, instead of failing the staging")
+	fs.BoolVar(&s.StrictDeviceSizeCheck, "strict-device-size-check", false, "Fail the staging when the device is smaller than the volume, which reveals a stale device. By default, the mismatch is only logged")
+	fs.BoolVar(&s.ReportNodeTopology, "report-node-topology", false, "Add the instance ID of the node to its topology as '"+driver.TopologyNodeKey+"', so that the volumes created for a pod scheduled on the node are tagged with '"+driver.InitialNodeTagKey+"'")
+	fs.BoolVar(&s.ReportRegionTopology, "report-region-topology", false, "Add the region of the node to its topology as '"+driver.TopologyRegionKey+"'. The topology of a registered node cannot change, the nodes must be re-registered to add or remove it")
+	fs.DurationVar(&s.InFlightMaxAge, "inflight-max-age", 0, "Age after which a request still in flight is evicted with a warning, so that a missed cleanup does not block the operations on a volume forever. It must be longer than the slowest operation. 0 disables the eviction")
+	fs.BoolVar(&s.MountByUUID, "mount-by-uuid", false, "Mount the staged volumes by the UUID of their filesystem (UUID=...) instead of their device path, which may change across reboots on some kernels. A UUID shared with another device of the node, as with the volumes restored from the same snapshot, is mounted by its device path. Encrypted volumes are always mounted by their LUKS device")
+	fs.DurationVar(&s.FormatTimeout, "format-timeout", 0, "Maximum duration of the format of a volume at staging, which then fails with DeadlineExceeded and is reformatted on retry. It should be shorter than the timeout of the kubelet. 0 leaves the format unbounded")
+	fs.Var(&s.FsResizeTolerance, "fs-resize-tolerance", "Difference between the sizes of the device and of its filesystem under which NodeExpandVolume considers the filesystem expanded and skips the resize (e.g. '1Mi'). 0 always resizes the filesystem")
+}
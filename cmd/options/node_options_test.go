@@ -27,6 +27,86 @@ func TestNodeOptions(t *testing.T) {
 		flag  string
 		found bool
 	}{
+		{
+			name:  "lookup desired flag",
+			flag:  "device-path-poll-interval",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "device-path-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "exclude-reserved-blocks",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "secret-provider-url",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "fs-group-policy",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "luks-open-retries",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "luks-open-retry-delay",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "disable-staging",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "allow-fs-type-mismatch",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "strict-device-size-check",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "report-node-topology",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "report-region-topology",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "inflight-max-age",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "mount-by-uuid",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "format-timeout",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "fs-resize-tolerance",
+			found: true,
+		},
 		{
 			name:  "fail for non-desired flag",
 			flag:  "some-flag",
@@ -18,6 +18,7 @@ package options
 
 import (
 	"flag"
+	"time"
 
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver"
 )
@@ -26,8 +27,20 @@ import (
 type ServerOptions struct {
 	// Endpoint is the endpoint that the driver server should listen on.
 	Endpoint string
+	// DebugEndpoint is the address of the HTTP debug endpoint of the controller. It is disabled when empty.
+	DebugEndpoint string
+	// MetricsEndpoint is the address of the HTTP endpoint serving the metrics of the driver. It is disabled when empty.
+	MetricsEndpoint string
+	// RequireEncryption rejects the creation and the staging of the volumes which are not encrypted.
+	RequireEncryption bool
+	// ShutdownDrainTimeout is the maximum time to wait for the calls in progress on SIGTERM.
+	ShutdownDrainTimeout time.Duration
 }
 
 func (s *ServerOptions) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&s.Endpoint, "endpoint", driver.DefaultCSIEndpoint, "Endpoint for the CSI driver server")
+	fs.StringVar(&s.DebugEndpoint, "debug-endpoint", "", "Address of the HTTP debug endpoint of the controller (e.g. 'localhost:8090'). Disabled when empty")
+	fs.StringVar(&s.MetricsEndpoint, "metrics-endpoint", "", "Address of the HTTP endpoint serving the Prometheus metrics of the driver on /metrics (e.g. ':8095'). Disabled when empty")
+	fs.BoolVar(&s.RequireEncryption, "require-encryption", false, "Reject the creation of the volumes without the '"+driver.EncryptedKey+"=true' parameter, and the staging of the volumes which are not encrypted")
+	fs.DurationVar(&s.ShutdownDrainTimeout, "shutdown-drain-timeout", driver.DefaultShutdownDrainTimeout, "On SIGTERM, maximum time to wait for the calls in progress to complete before exiting, while the new calls are rejected. 0 stops immediately")
 }
@@ -32,6 +32,26 @@ func TestServerOptions(t *testing.T) {
 			flag:  "endpoint",
 			found: true,
 		},
+		{
+			name:  "lookup desired flag",
+			flag:  "debug-endpoint",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "metrics-endpoint",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "require-encryption",
+			found: true,
+		},
+		{
+			name:  "lookup desired flag",
+			flag:  "shutdown-drain-timeout",
+			found: true,
+		},
 		{
 			name:  "fail for non-desired flag",
 			flag:  "some-other-flag",
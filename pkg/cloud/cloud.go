@@ -18,9 +18,13 @@ package cloud
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	_nethttp "net/http"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"os"
@@ -70,6 +74,8 @@ const (
 	MaxTagKeyLength = 128
 	// MaxTagValueLength represents the maximum value length for a tag.
 	MaxTagValueLength = 256
+	// MaxResultsPerPage represents the maximum number of results per page of the Read calls.
+	MaxResultsPerPage = 1000
 )
 
 // Defaults
@@ -78,6 +84,8 @@ const (
 	DefaultVolumeSize int64 = 100 * util.GiB
 	// DefaultVolumeType specifies which storage to use for newly created Volumes.
 	DefaultVolumeType = VolumeTypeGP2
+	// DefaultDetachBusyRetryDelay is the delay before the first retry of the detachment of a busy volume, doubled at each retry.
+	DefaultDetachBusyRetryDelay = 2 * time.Second
 )
 
 // Tags
@@ -86,6 +94,10 @@ const (
 	VolumeNameTagKey = "CSIVolumeName"
 	// SnapshotNameTagKey is the key value that refers to the snapshot's name.
 	SnapshotNameTagKey = "CSIVolumeSnapshotName"
+	// ClusterIDTagKey is the key value that refers to the ID of the Kubernetes cluster owning the resource.
+	ClusterIDTagKey = "CSIClusterID"
+	// BoundTagKey is the key value that marks the volumes which have been bound to a PV once.
+	BoundTagKey = "CSIBound"
 	// KubernetesTagKeyPrefix is the prefix of the key value that is reserved for Kubernetes.
 	KubernetesTagKeyPrefix = "kubernetes.io"
 	// OscTagKeyPrefix is the prefix of the key value that is reserved for Outscale.
@@ -111,16 +123,47 @@ var (
 	// with the same ID
 	ErrMultiSnapshots = errors.New("Multiple snapshots with the same name found")
 
+	// ErrSnapshotPending is returned when a snapshot is still not completed
+	// long after its creation
+	ErrSnapshotPending = errors.New("Snapshot is still pending")
+
+	// ErrSnapshotFailed is returned when the creation of a snapshot failed
+	ErrSnapshotFailed = errors.New("Snapshot is in error")
+
+	// ErrMultiVolumes is returned when multiple volumes are found
+	// with the same ID
+	ErrMultiVolumes = errors.New("Multiple volumes with the same ID found")
+
+	// ErrDeviceNameInUse is returned when the requested device name is already in use on the node.
+	ErrDeviceNameInUse = dm.ErrDeviceNameInUse
+
+	// ErrZoneMismatch is returned when a volume is attached to a node of another availability zone.
+	ErrZoneMismatch = errors.New("Volume and node are not in the same availability zone")
+
+	// ErrQuotaExceeded is returned when a resource cannot be created because of the quotas of the account.
+	ErrQuotaExceeded = errors.New("Quota exceeded")
+
 	// ErrInvalidMaxResults is returned when a MaxResults pagination parameter is between 1 and 4
 	ErrInvalidMaxResults = errors.New("MaxResults parameter must be 0 or greater than or equal to 5")
+
+	// ErrInvalidNextToken is returned when the Outscale API rejects the NextPageToken of a listing.
+	ErrInvalidNextToken = errors.New("Invalid pagination token")
 )
 
 // Disk represents a BSU volume
 type Disk struct {
 	VolumeID         string
 	CapacityGiB      int64
+	VolumeType       string
 	AvailabilityZone string
 	SnapshotID       string
+	Tags             map[string]string
+	State            string
+	CreationTime     time.Time
+	// IOPS is the provisioned IOPS of the volume. Outscale does not expose the throughput of a volume.
+	IOPS int64
+	// AttachedNodes are the IDs of the VMs the volume is attached to.
+	AttachedNodes []string
 }
 
 // DiskOptions represents parameters to create an BSU volume
@@ -144,6 +187,10 @@ type Snapshot struct {
 	Size           int64
 	CreationTime   time.Time
 	ReadyToUse     bool
+	State          string
+	// Progress is the completion percentage of the snapshot, as reported by OAPI.
+	Progress int32
+	Tags     map[string]string
 }
 
 // ListSnapshotsResponse is the container for our snapshots along with a pagination token to pass back to the caller
@@ -160,23 +207,31 @@ type SnapshotOptions struct {
 // oscListSnapshotsResponse is a helper struct returned from the Outscale API calling function to the main ListSnapshots function
 type oscListSnapshotsResponse struct {
 	Snapshots []osc.Snapshot
+	NextToken string
 }
 
 type Cloud interface {
 	CreateDisk(ctx context.Context, volumeName string, diskOptions *DiskOptions) (disk Disk, err error)
 	DeleteDisk(ctx context.Context, volumeID string) (success bool, err error)
-	AttachDisk(ctx context.Context, volumeID string, nodeID string) (devicePath string, err error)
-	DetachDisk(ctx context.Context, volumeID string, nodeID string) (err error)
+	AttachDisk(ctx context.Context, volumeID string, nodeID string, deviceName string) (devicePath string, err error)
+	DetachDisk(ctx context.Context, volumeID string, nodeID string, waitDetached bool) (err error)
 	ResizeDisk(ctx context.Context, volumeID string, reqSize int64) (newSize int64, err error)
+	ModifyDisk(ctx context.Context, volumeID string, volumeType string) (err error)
+	TagDisk(ctx context.Context, volumeID string, tags map[string]string) (err error)
 	WaitForAttachmentState(ctx context.Context, volumeID, state string) error
 	GetDiskByName(ctx context.Context, name string, capacityBytes int64) (disk Disk, err error)
 	GetDiskByID(ctx context.Context, volumeID string) (disk Disk, err error)
+	ListDisks(ctx context.Context, tags map[string]string) (disks []Disk, err error)
+	GetDisksByIDs(ctx context.Context, volumeIDs []string) (disks map[string]Disk, err error)
 	IsExistInstance(ctx context.Context, nodeID string) (success bool)
+	IsInstanceStopped(ctx context.Context, nodeID string) (stopped bool, err error)
+	GetAttachedDisks(ctx context.Context, nodeID string) (volumeIDs []string, err error)
 	CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *SnapshotOptions) (snapshot Snapshot, err error)
 	DeleteSnapshot(ctx context.Context, snapshotID string) (success bool, err error)
 	GetSnapshotByName(ctx context.Context, name string) (snapshot Snapshot, err error)
 	GetSnapshotByID(ctx context.Context, snapshotID string) (snapshot Snapshot, err error)
 	ListSnapshots(ctx context.Context, volumeID string, maxResults int64, nextToken string) (listSnapshotsResponse ListSnapshotsResponse, err error)
+	GetDeviceAllocations() (allocations map[string]map[string]string)
 }
 
 type OscInterface interface {
@@ -251,45 +306,265 @@ func (client *OscClient) UpdateVolume(ctx context.Context, localVarOptionals osc
 var _ OscInterface = &OscClient{}
 
 type cloud struct {
-	region string
-	dm     dm.DeviceManager
-	client OscInterface
+	region      string
+	dm          dm.DeviceManager
+	client      OscInterface
+	retryBudget *retryBudget
+	maxRetries  int
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+	// detachSettle is how long DetachDisk waits once the volume is detached
+	detachSettle time.Duration
+	// detachBusyRetries is the number of retries of the detachment of a busy volume
+	detachBusyRetries int
+	// clusterID restricts ListSnapshots to the snapshots tagged with this cluster ID
+	clusterID string
+	// requestTimeout is the timeout of the HTTP requests sent by the OscClient built by NewCloud
+	requestTimeout time.Duration
+	// configFile and profile select the profile of the Outscale config file used by NewCloud
+	configFile string
+	profile    string
+}
+
+// CloudOption configures a cloud returned by NewCloud.
+type CloudOption func(*cloud)
+
+// WithRetryBudget limits the retries of throttled requests to size per window, shared across all the operations.
+// A size lower or equal to 0 disables the limit.
+func WithRetryBudget(size int, window time.Duration) CloudOption {
+	return func(c *cloud) {
+		c.retryBudget = newRetryBudget(size, window)
+	}
+}
+
+// WithRequestTimeout sets the timeout of the HTTP requests sent to the Outscale API.
+// A timeout lower or equal to 0 disables the timeout.
+func WithRequestTimeout(timeout time.Duration) CloudOption {
+	return func(c *cloud) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithProfile reads the endpoint, the region and the credentials from the profile name of the
+// Outscale config file configFile, or of ~/.osc/config.json when configFile is empty.
+// The region of the profile replaces the region passed to NewCloud.
+// An empty name keeps the configuration read from the environment.
+func WithProfile(configFile, name string) CloudOption {
+	return func(c *cloud) {
+		c.configFile = configFile
+		c.profile = name
+	}
+}
+
+// WithMaxRetries limits the number of retries of a failed request.
+// A value lower or equal to 0 keeps the number of steps of the backoff configured by the environment.
+func WithMaxRetries(maxRetries int) CloudOption {
+	return func(c *cloud) {
+		c.maxRetries = maxRetries
+	}
+}
+
+// WithDetachSettleDuration makes DetachDisk wait for duration once the volume is detached,
+// so that the device is fully released before the volume is attached elsewhere.
+func WithDetachSettleDuration(duration time.Duration) CloudOption {
+	return func(c *cloud) {
+		c.detachSettle = duration
+	}
+}
+
+// WithDetachBusyRetries makes DetachDisk retry the detachment of a busy volume retries times,
+// waiting DefaultDetachBusyRetryDelay before the first retry and twice as long before each next one.
+func WithDetachBusyRetries(retries int) CloudOption {
+	return func(c *cloud) {
+		c.detachBusyRetries = retries
+	}
+}
+
+// WithClusterID makes ListSnapshots return only the snapshots created by the cluster clusterID,
+// so that the clusters sharing an account do not list the snapshots of each other.
+func WithClusterID(clusterID string) CloudOption {
+	return func(c *cloud) {
+		c.clusterID = clusterID
+	}
 }
 
 var _ Cloud = &cloud{}
 
 // NewCloud returns a new instance of Outscale cloud
 // It panics if session is invalid
-func NewCloud(region string) (Cloud, error) {
-	return newOscCloud(region)
+func NewCloud(region string, options ...CloudOption) (Cloud, error) {
+	return newOscCloud(region, options...)
 }
 
-func newOscCloud(region string) (Cloud, error) {
+func newOscCloud(region string, options ...CloudOption) (Cloud, error) {
+	c := &cloud{
+		region: region,
+		dm:     dm.NewDeviceManager(),
+		now:    time.Now,
+		sleep:  sleepWithContext,
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	client, err := newOscClient(c.region, c.configFile, c.profile)
+	if err != nil {
+		return nil, err
+	}
+	if c.profile != "" {
+		c.region = client.config.Servers[0].Variables["region"].DefaultValue
+	}
+	if c.requestTimeout > 0 {
+		httpClient := *client.config.HTTPClient
+		httpClient.Timeout = c.requestTimeout
+		client.config.HTTPClient = &httpClient
+	}
+	c.client = client
+	return c, nil
+}
+
+// newOscClient returns a client of the Outscale API of region configured by the environment,
+// or by the profile of configFile when profile is set.
+func newOscClient(region, configFile, profile string) (*OscClient, error) {
 	client := &OscClient{}
 	// Set User-Agent with name and version of the CSI driver
 	version := util.GetVersion()
-	configEnv := osc.NewConfigEnv()
-	config, err := configEnv.Configuration()
-	if err != nil {
-		return nil, err
+	auth := context.WithValue(context.Background(), osc.ContextAWSv4, osc.AWSv4{
+		AccessKey: os.Getenv("OSC_ACCESS_KEY"),
+		SecretKey: os.Getenv("OSC_SECRET_KEY"),
+	})
+	if profile == "" {
+		config, err := osc.NewConfigEnv().Configuration()
+		if err != nil {
+			return nil, err
+		}
+		client.config = config
+	} else {
+		var file *osc.ConfigFile
+		var err error
+		if configFile == "" {
+			file, err = osc.LoadDefaultConfigFile()
+		} else {
+			file, err = osc.LoadConfigFile(&configFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not load the Outscale config file: %w", err)
+		}
+		client.config, err = file.Configuration(profile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load profile %q: %w", profile, err)
+		}
+		// The credentials of the profile take precedence over the environment
+		auth, err = file.Context(auth, profile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load profile %q: %w", profile, err)
+		}
+		region = client.config.Servers[0].Variables["region"].DefaultValue
 	}
-	client.config = config
 	client.config.Debug = true
 	client.config.UserAgent = fmt.Sprintf("osc-bsu-csi-driver/%s", version.DriverVersion)
 	client.api = osc.NewAPIClient(client.config)
 
-	client.auth = context.WithValue(context.Background(), osc.ContextAWSv4, osc.AWSv4{
-		AccessKey: os.Getenv("OSC_ACCESS_KEY"),
-		SecretKey: os.Getenv("OSC_SECRET_KEY"),
-	})
-	client.auth = context.WithValue(client.auth, osc.ContextServerIndex, 0)
+	client.auth = context.WithValue(auth, osc.ContextServerIndex, 0)
 	client.auth = context.WithValue(client.auth, osc.ContextServerVariables, map[string]string{"region": region})
+	return client, nil
+}
 
-	return &cloud{
-		region: region,
-		dm:     dm.NewDeviceManager(),
-		client: client,
-	}, nil
+// backoff returns the backoff of the retried requests.
+func (c *cloud) backoff() wait.Backoff {
+	backoff := util.EnvBackoff()
+	if c.maxRetries > 0 {
+		backoff.Steps = c.maxRetries + 1
+	}
+	return backoff
+}
+
+type attachRetriesKey struct{}
+
+// WithAttachRetries overrides for the AttachDisk calls made with the returned context
+// the number of retries of the attachment set by WithMaxRetries.
+func WithAttachRetries(ctx context.Context, retries int) context.Context {
+	return context.WithValue(ctx, attachRetriesKey{}, retries)
+}
+
+// AttachRetries returns the number of retries of the attachment set on ctx by WithAttachRetries.
+func AttachRetries(ctx context.Context) (int, bool) {
+	retries, ok := ctx.Value(attachRetriesKey{}).(int)
+	return retries, ok
+}
+
+// attachBackoff returns the backoff of the attachment requests.
+func (c *cloud) attachBackoff(ctx context.Context) wait.Backoff {
+	backoff := c.backoff()
+	if retries, ok := AttachRetries(ctx); ok {
+		backoff.Steps = retries + 1
+	}
+	return backoff
+}
+
+// apiError is implemented by osc.GenericOpenAPIError, the errors returned by the Outscale API.
+type apiError interface {
+	error
+	Body() []byte
+	Model() interface{}
+}
+
+// apiErrors returns the errors of the response of the Outscale API which failed with err.
+func apiErrors(err error) []osc.Errors {
+	var apiErr apiError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+	errResp, ok := apiErr.Model().(osc.ErrorResponse)
+	if !ok && json.Unmarshal(apiErr.Body(), &errResp) != nil {
+		return nil
+	}
+	return errResp.GetErrors()
+}
+
+// isQuotaExceededError returns true when the Outscale API rejected a request because of the quotas of the account.
+func isQuotaExceededError(err error) bool {
+	for _, e := range apiErrors(err) {
+		if strings.HasPrefix(e.GetType(), "TooManyResources") {
+			return true
+		}
+	}
+	return false
+}
+
+// isVolumeBusyError returns true when the Outscale API rejected the detachment of a volume still in use by its VM.
+// The other InvalidState errors, such as a volume which is not linked anymore, are not retried.
+func isVolumeBusyError(err error) bool {
+	for _, e := range apiErrors(err) {
+		details := strings.ToLower(e.GetDetails())
+		if strings.Contains(details, "busy") || strings.Contains(details, "in use") {
+			return true
+		}
+	}
+	return false
+}
+
+// isInvalidNextPageTokenError returns true when the Outscale API rejected a parameter of a request, which is
+// its NextPageToken when the other parameters are built by the driver.
+func isInvalidNextPageTokenError(err error) bool {
+	for _, e := range apiErrors(err) {
+		if e.GetType() == "InvalidParameterValue" || strings.Contains(strings.ToLower(e.GetDetails()), "token") {
+			return true
+		}
+	}
+	return false
+}
+
+// sleepWithContext waits for d, or until ctx is done.
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 func IsNilDisk(disk Disk) bool {
@@ -300,6 +575,20 @@ func IsNilSnapshot(snapshot Snapshot) bool {
 	return snapshot.SnapshotID == ""
 }
 
+// CheckCreatedSnapshot returns ErrSnapshotFailed when the snapshot is in error, and ErrSnapshotPending
+// when it is still not completed pendingTimeout after its creation.
+func CheckCreatedSnapshot(snapshot Snapshot, pendingTimeout time.Duration, now time.Time) error {
+	switch snapshot.State {
+	case "error":
+		return ErrSnapshotFailed
+	case "pending", "in-queue":
+		if !snapshot.CreationTime.IsZero() && now.Sub(snapshot.CreationTime) > pendingTimeout {
+			return ErrSnapshotPending
+		}
+	}
+	return nil
+}
+
 func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *DiskOptions) (Disk, error) {
 	klog.Infof("Debug CreateDisk: %+v, %v", volumeName, diskOptions)
 	var (
@@ -372,19 +661,22 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
 					return false, nil
 				}
 			}
+			if isQuotaExceededError(err) {
+				return false, fmt.Errorf("could not create volume in Outscale: %w: %v", ErrQuotaExceeded, err)
+			}
 			return false, fmt.Errorf("could not create volume in Outscale: %v", err)
 		}
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, createVolumeCallBack)
 	if waitErr != nil {
 		return Disk{}, waitErr
@@ -416,7 +708,7 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -428,14 +720,14 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 		return true, nil
 	}
 
-	backoff = util.EnvBackoff()
+	backoff = c.backoff()
 	waitErr = wait.ExponentialBackoff(backoff, createTagsCallBack)
 	if waitErr != nil {
 		return Disk{}, waitErr
 	}
 
 	if err := c.waitForVolume(ctx, volumeID); err != nil {
-		return Disk{}, fmt.Errorf("failed to get an available volume in Outscale: %v", err)
+		return Disk{}, fmt.Errorf("failed to get an available volume in Outscale: %w", err)
 	}
 
 	return Disk{CapacityGiB: int64(size), VolumeID: volumeID, AvailabilityZone: zone, SnapshotID: snapshotID}, nil
@@ -454,7 +746,7 @@ func (c *cloud) DeleteDisk(ctx context.Context, volumeID string) (bool, error) {
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -469,7 +761,7 @@ func (c *cloud) DeleteDisk(ctx context.Context, volumeID string) (bool, error) {
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, deleteVolumeCallBack)
 	if waitErr != nil {
 		return false, waitErr
@@ -478,19 +770,49 @@ func (c *cloud) DeleteDisk(ctx context.Context, volumeID string) (bool, error) {
 	return true, nil
 }
 
-func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string, error) {
-	klog.Infof("Debug AttachDisk: %+v, %v\n", volumeID, nodeID)
+// AttachDisk attaches the volume to the node. When deviceName is empty, the next available device name is used.
+func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID, deviceName string) (string, error) {
+	klog.Infof("Debug AttachDisk: %+v, %v, %v\n", volumeID, nodeID, deviceName)
 	instance, err := c.getInstance(ctx, nodeID)
 	if err != nil {
 		return "", err
 	}
 
-	device, err := c.dm.NewDevice(*instance, volumeID)
+	volume, err := c.getVolume(ctx, osc.ReadVolumesRequest{
+		Filters: &osc.FiltersVolume{
+			VolumeIds: &[]string{volumeID},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	volumeZone, nodeZone := volume.GetSubregionName(), instance.Placement.GetSubregionName()
+	if volumeZone != "" && nodeZone != "" && volumeZone != nodeZone {
+		return "", fmt.Errorf("%w: volume %q is in %q but node %q is in %q", ErrZoneMismatch, volumeID, volumeZone, nodeID, nodeZone)
+	}
+
+	// The volume is already attached to the node, there is nothing to do
+	// unless another device name is requested, which is rejected by the device manager.
+	for _, link := range volume.GetLinkedVolumes() {
+		if link.GetVmId() == nodeID && link.GetState() == "attached" && link.GetDeviceName() != "" &&
+			(deviceName == "" || deviceName == link.GetDeviceName()) {
+			klog.V(5).Infof("AttachVolume volume=%q is already attached to instance=%q on %s", volumeID, nodeID, link.GetDeviceName())
+			return link.GetDeviceName(), nil
+		}
+	}
+
+	var device dm.Device
+	if deviceName != "" {
+		device, err = c.dm.NewDeviceWithName(*instance, volumeID, deviceName)
+	} else {
+		device, err = c.dm.NewDevice(*instance, volumeID)
+	}
 	if err != nil {
 		return "", err
 	}
 	defer device.Release(false)
 
+	start := c.now()
 	if !device.IsAlreadyAssigned {
 		request := osc.LinkVolumeRequest{
 			DeviceName: device.Path,
@@ -506,7 +828,7 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 				if httpRes != nil {
 					fmt.Fprintln(os.Stderr, httpRes.Status)
 					requestStr := fmt.Sprintf("%v", request)
-					if keepRetryWithError(
+					if c.keepRetryWithError(
 						requestStr,
 						httpRes.StatusCode,
 						ThrottlingError) {
@@ -518,9 +840,10 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 			return true, nil
 		}
 
-		backoff := util.EnvBackoff()
+		backoff := c.attachBackoff(ctx)
 		waitErr := wait.ExponentialBackoff(backoff, linkVolumeCallBack)
 		if waitErr != nil {
+			c.observeAttachment("attach", start, waitErr)
 			return "", waitErr
 		}
 
@@ -529,7 +852,9 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 	}
 
 	// This is the only situation where we taint the device
-	if err := c.WaitForAttachmentState(ctx, volumeID, "attached"); err != nil {
+	err = c.WaitForAttachmentState(ctx, volumeID, "attached")
+	c.observeAttachment("attach", start, err)
+	if err != nil {
 		device.Taint()
 		return "", err
 	}
@@ -541,7 +866,9 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 	return device.Path, nil
 }
 
-func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
+// DetachDisk unlinks the volume from the node. When waitDetached is false, it returns once the unlink
+// is accepted, without waiting for the volume to be detached.
+func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string, waitDetached bool) error {
 	klog.Infof("Debug DetachDisk: %+v, %v\n", volumeID, nodeID)
 	{
 		klog.Infof("Check Volume state before detaching")
@@ -576,6 +903,7 @@ func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
 		return ErrNotFound
 	}
 
+	start := c.now()
 	request := osc.UnlinkVolumeRequest{
 		VolumeId: volumeID,
 	}
@@ -587,29 +915,48 @@ func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
 					return false, nil
 				}
 			}
-			return false, fmt.Errorf("could not detach volume %q from node %q: %v", volumeID, nodeID, err)
+			return false, fmt.Errorf("could not detach volume %q from node %q: %w", volumeID, nodeID, err)
 		}
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
-	waitErr := wait.ExponentialBackoff(backoff, unlinkVolumeCallBack)
+	var waitErr error
+	delay := DefaultDetachBusyRetryDelay
+	for retry := 0; ; retry++ {
+		waitErr = wait.ExponentialBackoff(c.backoff(), unlinkVolumeCallBack)
+		if waitErr == nil || retry >= c.detachBusyRetries || !isVolumeBusyError(waitErr) {
+			break
+		}
+		klog.Warningf("DetachDisk: volume %s is busy, retrying in %v (%d/%d)", volumeID, delay, retry+1, c.detachBusyRetries)
+		if err := c.sleep(ctx, delay); err != nil {
+			waitErr = err
+			break
+		}
+		delay *= 2
+	}
 	if waitErr != nil {
+		c.observeAttachment("detach", start, waitErr)
 		return waitErr
 	}
-
-	if err := c.WaitForAttachmentState(ctx, volumeID, "detached"); err != nil {
-		return err
+	if !waitDetached {
+		klog.V(4).Infof("DetachDisk: volume %s unlinked from %s, not waiting for the detachment", volumeID, nodeID)
+		return nil
 	}
 
-	return nil
+	err = c.WaitForAttachmentState(ctx, volumeID, "detached")
+	c.observeAttachment("detach", start, err)
+	if err == nil && c.detachSettle > 0 {
+		klog.V(4).Infof("DetachDisk: waiting %v for volume %s to settle", c.detachSettle, volumeID)
+		err = c.sleep(ctx, c.detachSettle)
+	}
+	return err
 }
 
 // WaitForAttachmentState polls until the attachment status is the expected value.
@@ -619,38 +966,21 @@ func (c *cloud) WaitForAttachmentState(ctx context.Context, volumeID, state stri
 	// By using 1 second starting interval with a backoff of 1.8,
 	// we get [1, 1.8, 3.24, 5.832000000000001, 10.4976].
 	// In total we wait for 2601 seconds.
-	verifyVolumeFunc := func() (bool, error) {
-		request := osc.ReadVolumesRequest{
-			Filters: &osc.FiltersVolume{
-				VolumeIds: &[]string{volumeID},
-			},
-		}
-
-		volume, err := c.getVolume(ctx, request)
-		if err != nil {
-			return false, err
-		}
-
+	return c.waitForVolumeState(ctx, volumeID, func(volume *osc.Volume) bool {
 		if len(volume.GetLinkedVolumes()) == 0 {
-			if state == "detached" {
-				return true, nil
-			}
+			return state == "detached"
 		}
-
 		for _, a := range volume.GetLinkedVolumes() {
 			if a.GetState() == "" {
 				klog.Warningf("Ignoring nil attachment state for volume %q: %v", volumeID, a)
 				continue
 			}
 			if a.GetState() == state {
-				return true, nil
+				return true
 			}
 		}
-		return false, nil
-	}
-
-	backoff := util.EnvBackoff()
-	return wait.ExponentialBackoff(backoff, verifyVolumeFunc)
+		return false
+	}, util.EnvBackoff())
 }
 
 func (c *cloud) GetDiskByName(ctx context.Context, name string, capacityBytes int64) (Disk, error) {
@@ -672,12 +1002,7 @@ func (c *cloud) GetDiskByName(ctx context.Context, name string, capacityBytes in
 		return Disk{}, ErrDiskExistsDiffSize
 	}
 
-	return Disk{
-		VolumeID:         volume.GetVolumeId(),
-		CapacityGiB:      int64(volSizeBytes),
-		AvailabilityZone: volume.GetSubregionName(),
-		SnapshotID:       volume.GetSnapshotId(),
-	}, nil
+	return newDisk(volume), nil
 }
 
 func (c *cloud) GetDiskByID(ctx context.Context, volumeID string) (Disk, error) {
@@ -689,16 +1014,135 @@ func (c *cloud) GetDiskByID(ctx context.Context, volumeID string) (Disk, error)
 	}
 
 	volume, err := c.getVolume(ctx, request)
+	if err == ErrMultiDisks {
+		return Disk{}, ErrMultiVolumes
+	}
 	if err != nil {
 		return Disk{}, err
 	}
 
-	return Disk{
+	return newDisk(volume), nil
+}
+
+// ListDisks returns the volumes created by the driver, optionally restricted to the volumes carrying all the tags.
+func (c *cloud) ListDisks(ctx context.Context, tags map[string]string) ([]Disk, error) {
+	klog.Infof("Debug ListDisks : %+v\n", tags)
+	filters := osc.FiltersVolume{
+		TagKeys: &[]string{VolumeNameTagKey},
+	}
+	if len(tags) > 0 {
+		pairs := make([]string, 0, len(tags))
+		for k, v := range tags {
+			pairs = append(pairs, k+"="+v)
+		}
+		sort.Strings(pairs)
+		filters.Tags = &pairs
+	}
+	volumes, err := c.readVolumes(ctx, osc.ReadVolumesRequest{Filters: &filters})
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []Disk
+	for _, volume := range volumes {
+		disks = append(disks, newDisk(&volume))
+	}
+	return disks, nil
+}
+
+// maxVolumeIDsPerRead is the largest number of volume IDs filtered by a single ReadVolumes.
+var maxVolumeIDsPerRead = 500
+
+// GetDisksByIDs reads the volumes by chunks of IDs and returns them by ID. The volumes not found are missing from the map.
+func (c *cloud) GetDisksByIDs(ctx context.Context, volumeIDs []string) (map[string]Disk, error) {
+	klog.Infof("Debug GetDisksByIDs : %+v\n", volumeIDs)
+	disks := make(map[string]Disk, len(volumeIDs))
+	for chunk := range slices.Chunk(volumeIDs, maxVolumeIDsPerRead) {
+		request := osc.ReadVolumesRequest{
+			Filters: &osc.FiltersVolume{
+				VolumeIds: &chunk,
+			},
+		}
+		volumes, err := c.readVolumes(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, volume := range volumes {
+			disks[volume.GetVolumeId()] = newDisk(&volume)
+		}
+	}
+	return disks, nil
+}
+
+// readVolumes calls ReadVolumes until the last page, retrying on throttling.
+func (c *cloud) readVolumes(ctx context.Context, request osc.ReadVolumesRequest) ([]osc.Volume, error) {
+	request.SetResultsPerPage(MaxResultsPerPage)
+	var volumes []osc.Volume
+	for {
+		response, err := c.readVolumesPage(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, response.GetVolumes()...)
+		if response.GetNextPageToken() == "" {
+			return volumes, nil
+		}
+		request.SetNextPageToken(response.GetNextPageToken())
+	}
+}
+
+// readVolumesPage calls ReadVolumes once, retrying on throttling.
+func (c *cloud) readVolumesPage(ctx context.Context, request osc.ReadVolumesRequest) (osc.ReadVolumesResponse, error) {
+	var response osc.ReadVolumesResponse
+	readVolumesCallBack := func() (bool, error) {
+		var httpRes *_nethttp.Response
+		var err error
+		response, httpRes, err = c.client.ReadVolumes(ctx, request)
+		if err != nil {
+			if httpRes != nil {
+				fmt.Fprintln(os.Stderr, httpRes.Status)
+				requestStr := fmt.Sprintf("%v", request)
+				if c.keepRetryWithError(
+					requestStr,
+					httpRes.StatusCode,
+					ThrottlingError) {
+					return false, nil
+				}
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	backoff := c.backoff()
+	if waitErr := wait.ExponentialBackoff(backoff, readVolumesCallBack); waitErr != nil {
+		return osc.ReadVolumesResponse{}, waitErr
+	}
+	return response, nil
+}
+
+// newDisk converts an Outscale volume into a Disk.
+func newDisk(volume *osc.Volume) Disk {
+	disk := Disk{
 		VolumeID:         volume.GetVolumeId(),
 		CapacityGiB:      int64(volume.GetSize()),
+		VolumeType:       volume.GetVolumeType(),
 		AvailabilityZone: volume.GetSubregionName(),
 		SnapshotID:       volume.GetSnapshotId(),
-	}, nil
+		Tags:             oscTagsToMap(volume.GetTags()),
+		State:            volume.GetState(),
+		IOPS:             int64(volume.GetIops()),
+	}
+	if creationTime, err := time.Parse(time.RFC3339, volume.GetCreationDate()); err == nil {
+		disk.CreationTime = creationTime
+	}
+	for _, link := range volume.GetLinkedVolumes() {
+		if link.GetState() == "attached" {
+			disk.AttachedNodes = append(disk.AttachedNodes, link.GetVmId())
+		}
+	}
+	return disk
 }
 
 func (c *cloud) IsExistInstance(ctx context.Context, nodeID string) bool {
@@ -707,6 +1151,37 @@ func (c *cloud) IsExistInstance(ctx context.Context, nodeID string) bool {
 	return err == nil
 }
 
+// GetDeviceAllocations returns the device names assigned by the device manager, as {"nodeID": {"deviceName": "volumeID"}}.
+func (c *cloud) GetDeviceAllocations() map[string]map[string]string {
+	return c.dm.Snapshot()
+}
+
+// GetAttachedDisks returns the IDs of the volumes attached to the node, except its root volume.
+func (c *cloud) GetAttachedDisks(ctx context.Context, nodeID string) ([]string, error) {
+	klog.Infof("Debug GetAttachedDisks : %+v\n", nodeID)
+	instance, err := c.getInstance(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	var volumeIDs []string
+	for _, blockDevice := range instance.GetBlockDeviceMappings() {
+		if blockDevice.GetDeviceName() == instance.GetRootDeviceName() {
+			continue
+		}
+		volumeIDs = append(volumeIDs, blockDevice.Bsu.GetVolumeId())
+	}
+	return volumeIDs, nil
+}
+
+func (c *cloud) IsInstanceStopped(ctx context.Context, nodeID string) (bool, error) {
+	klog.Infof("Debug IsInstanceStopped : %+v\n", nodeID)
+	instance, err := c.getInstance(ctx, nodeID)
+	if err != nil {
+		return false, err
+	}
+	return instance.GetState() == "stopped", nil
+}
+
 func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *SnapshotOptions) (snapshot Snapshot, err error) {
 	descriptions := "Created by Outscale BSU CSI driver for volume " + volumeID
 	klog.Infof("Debug CreateSnapshot : %+v, %+v\n", volumeID, snapshotOptions)
@@ -732,7 +1207,7 @@ func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOpt
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -744,7 +1219,7 @@ func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOpt
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, createSnapshotCallBack)
 	if waitErr != nil {
 		return Snapshot{}, waitErr
@@ -771,7 +1246,7 @@ func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOpt
 			if httpResTag != nil {
 				fmt.Fprintln(os.Stderr, httpResTag.Status)
 				requestStr := fmt.Sprintf("%v", resTag)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpResTag.StatusCode,
 					ThrottlingError) {
@@ -783,7 +1258,7 @@ func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOpt
 		return true, nil
 	}
 
-	backoff = util.EnvBackoff()
+	backoff = c.backoff()
 	waitErr = wait.ExponentialBackoff(backoff, createTagCallback)
 	if waitErr != nil {
 		return Snapshot{}, waitErr
@@ -806,7 +1281,7 @@ func (c *cloud) DeleteSnapshot(ctx context.Context, snapshotID string) (success
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -821,7 +1296,7 @@ func (c *cloud) DeleteSnapshot(ctx context.Context, snapshotID string) (success
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, deleteSnapshotCallBack)
 	if waitErr != nil {
 		return false, waitErr
@@ -882,9 +1357,22 @@ func (c *cloud) ListSnapshots(ctx context.Context, volumeID string, maxResults i
 			},
 		}
 	}
+	// The pages are read server-side, as the snapshots may be numerous
+	if maxResults > 0 {
+		request.SetResultsPerPage(int32(min(maxResults, MaxResultsPerPage)))
+	}
+	if len(nextToken) != 0 {
+		request.SetNextPageToken(nextToken)
+	}
+	if len(c.clusterID) != 0 {
+		request.Filters.SetTags([]string{ClusterIDTagKey + "=" + c.clusterID})
+	}
 
 	oscSnapshotsResponse, err := c.listSnapshots(ctx, request)
 	if err != nil {
+		if len(nextToken) != 0 && isInvalidNextPageTokenError(err) {
+			return ListSnapshotsResponse{}, fmt.Errorf("%w %q: %v", ErrInvalidNextToken, nextToken, err)
+		}
 		return ListSnapshotsResponse{}, err
 	}
 	var snapshots []Snapshot
@@ -898,10 +1386,22 @@ func (c *cloud) ListSnapshots(ctx context.Context, volumeID string, maxResults i
 
 	return ListSnapshotsResponse{
 		Snapshots: snapshots,
-		NextToken: nextToken,
+		NextToken: oscSnapshotsResponse.NextToken,
 	}, nil
 }
 
+// oscTagsToMap returns the tags of an Outscale resource as a map, or nil if it has no tags.
+func oscTagsToMap(tags []osc.ResourceTag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[tag.GetKey()] = tag.GetValue()
+	}
+	return m
+}
+
 func (c *cloud) oscSnapshotResponseToStruct(oscSnapshot osc.Snapshot) Snapshot {
 	klog.Infof("Debug oscSnapshotResponseToStruct : %+v\n", oscSnapshot)
 	if !oscSnapshot.HasSnapshotId() ||
@@ -914,9 +1414,13 @@ func (c *cloud) oscSnapshotResponseToStruct(oscSnapshot osc.Snapshot) Snapshot {
 		SnapshotID:     oscSnapshot.GetSnapshotId(),
 		SourceVolumeID: oscSnapshot.GetVolumeId(),
 		Size:           snapshotSize,
-		//No StartTime for osc.Snapshot
-		//CreationTime:   oscSnapshot.StartTime,
 	}
+	if creationTime, err := time.Parse(time.RFC3339, oscSnapshot.GetCreationDate()); err == nil {
+		snapshot.CreationTime = creationTime
+	}
+	snapshot.Tags = oscTagsToMap(oscSnapshot.GetTags())
+	snapshot.State = oscSnapshot.GetState()
+	snapshot.Progress = oscSnapshot.GetProgress()
 	if oscSnapshot.GetState() == "completed" {
 		snapshot.ReadyToUse = true
 	} else {
@@ -926,9 +1430,16 @@ func (c *cloud) oscSnapshotResponseToStruct(oscSnapshot osc.Snapshot) Snapshot {
 	return snapshot
 }
 
-func keepRetryWithError(requestStr string, httpCode int, allowedErrors []int) bool {
+func (c *cloud) keepRetryWithError(requestStr string, httpCode int, allowedErrors []int) bool {
 	for _, v := range allowedErrors {
 		if httpCode == v {
+			if !c.retryBudget.take() {
+				klog.Warningf(
+					"Retry budget exhausted, not retrying (%v) error on request (%s)",
+					httpCode,
+					requestStr)
+				return false
+			}
 			klog.Warningf(
 				"Retry even if got (%v) error on request (%s)",
 				httpCode,
@@ -957,7 +1468,7 @@ func (c *cloud) getVolume(ctx context.Context, request osc.ReadVolumesRequest) (
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -978,7 +1489,7 @@ func (c *cloud) getVolume(ctx context.Context, request osc.ReadVolumesRequest) (
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, getVolumeCallback)
 
 	if waitErr != nil {
@@ -1006,7 +1517,7 @@ func (c *cloud) getInstance(ctx context.Context, vmID string) (*osc.Vm, error) {
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -1021,7 +1532,7 @@ func (c *cloud) getInstance(ctx context.Context, vmID string) (*osc.Vm, error) {
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, getInstanceCallback)
 	if waitErr != nil {
 		return nil, waitErr
@@ -1047,7 +1558,7 @@ func (c *cloud) getSnapshot(ctx context.Context, request osc.ReadSnapshotsReques
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -1060,7 +1571,7 @@ func (c *cloud) getSnapshot(ctx context.Context, request osc.ReadSnapshotsReques
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, getSnapshotsCallback)
 	if waitErr != nil {
 		return osc.Snapshot{}, waitErr
@@ -1090,7 +1601,7 @@ func (c *cloud) listSnapshots(ctx context.Context, request osc.ReadSnapshotsRequ
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -1102,7 +1613,7 @@ func (c *cloud) listSnapshots(ctx context.Context, request osc.ReadSnapshotsRequ
 		fmt.Printf("Debug  response : %+v\n", response)
 		return true, nil
 	}
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, listSnapshotsCallBack)
 
 	if waitErr != nil {
@@ -1114,38 +1625,54 @@ func (c *cloud) listSnapshots(ctx context.Context, request osc.ReadSnapshotsRequ
 
 	return oscListSnapshotsResponse{
 		Snapshots: snapshots,
+		NextToken: response.GetNextPageToken(),
 	}, nil
 }
 
-// waitForVolume waits for volume to be in the "available" state.
-// On a random Outscale account (shared among several developers) it took 4s on average.
-func (c *cloud) waitForVolume(ctx context.Context, volumeID string) error {
-	klog.Infof("Debug waitForVolume : %+v\n", volumeID)
-	var (
-		checkInterval = 3 * time.Second
-		// This timeout can be "ovewritten" if the value returned by ctx.Deadline()
-		// comes sooner. That value comes from the external provisioner controller.
-		checkTimeout = 1 * time.Minute
-	)
+var (
+	// createDiskCheckInterval and createDiskCheckTimeout bound the wait for a created volume to be available.
+	// On a random Outscale account (shared among several developers) it took 4s on average.
+	createDiskCheckInterval = 3 * time.Second
+	createDiskCheckTimeout  = 1 * time.Minute
+	// resizeDiskCheckInterval and resizeDiskCheckTimeout bound the wait for a volume expansion.
+	resizeDiskCheckInterval = 1 * time.Second
+	resizeDiskCheckTimeout  = 1 * time.Minute
+)
+
+// pollBackoff returns a backoff checking a condition every interval until timeout.
+func pollBackoff(interval, timeout time.Duration) wait.Backoff {
+	return wait.Backoff{
+		Duration: interval,
+		Factor:   1,
+		Steps:    int(timeout/interval) + 1,
+	}
+}
 
+// waitForVolumeState reads the volume with backoff until target returns true.
+// It stops as soon as ctx is canceled, e.g. when the CO gives up on the call,
+// and returns wait.ErrWaitTimeout once the backoff is exhausted.
+func (c *cloud) waitForVolumeState(ctx context.Context, volumeID string, target func(*osc.Volume) bool, backoff wait.Backoff) error {
 	request := osc.ReadVolumesRequest{
 		Filters: &osc.FiltersVolume{
 			VolumeIds: &[]string{volumeID},
 		},
 	}
-
-	err := wait.Poll(checkInterval, checkTimeout, func() (done bool, err error) {
-		vol, err := c.getVolume(ctx, request)
+	return wait.ExponentialBackoffWithContext(ctx, backoff, func(context.Context) (bool, error) {
+		volume, err := c.getVolume(ctx, request)
 		if err != nil {
-			return true, err
-		}
-		if vol.GetState() != "" {
-			return vol.GetState() == "available", nil
+			return false, err
 		}
-		return false, nil
+		return target(volume), nil
 	})
+}
 
-	return err
+// waitForVolume waits for volume to be in the "available" state.
+func (c *cloud) waitForVolume(ctx context.Context, volumeID string) error {
+	klog.Infof("Debug waitForVolume : %+v\n", volumeID)
+	// The timeout is shortened by the deadline of ctx, set by the external provisioner.
+	return c.waitForVolumeState(ctx, volumeID, func(volume *osc.Volume) bool {
+		return volume.GetState() == "available"
+	}, pollBackoff(createDiskCheckInterval, createDiskCheckTimeout))
 }
 
 // ResizeDisk resizes an BSU volume in GiB increments, rouding up to the next possible allocatable unit.
@@ -1192,7 +1719,7 @@ func (c *cloud) ResizeDisk(ctx context.Context, volumeID string, newSizeBytes in
 			if httpRes != nil {
 				fmt.Fprintln(os.Stderr, httpRes.Status)
 				requestStr := fmt.Sprintf("%v", request)
-				if keepRetryWithError(
+				if c.keepRetryWithError(
 					requestStr,
 					httpRes.StatusCode,
 					ThrottlingError) {
@@ -1204,21 +1731,33 @@ func (c *cloud) ResizeDisk(ctx context.Context, volumeID string, newSizeBytes in
 		return true, nil
 	}
 
-	backoff := util.EnvBackoff()
+	backoff := c.backoff()
 	waitErr := wait.ExponentialBackoff(backoff, updateVolumeCallBack)
 	if waitErr != nil {
 		return 0, waitErr
 	}
-	delay := 5
-	klog.Infof("Waiting %v sec for the effective modification.", delay)
-	time.Sleep(time.Duration(delay) * time.Second)
-	return c.checkDesiredSize(ctx, volumeID, int64(newSizeGiB))
+
+	// Read the size of the volume rather than the modification, to get around eventual consistency issues
+	var sizeGiB int32
+	err = c.waitForVolumeState(ctx, volumeID, func(volume *osc.Volume) bool {
+		sizeGiB = volume.GetSize()
+		return sizeGiB >= newSizeGiB
+	}, pollBackoff(resizeDiskCheckInterval, resizeDiskCheckTimeout))
+	if wait.Interrupted(err) && ctx.Err() == nil {
+		return int64(sizeGiB), fmt.Errorf("volume %q is still being expanded to %d size", volumeID, newSizeGiB)
+	}
+	return int64(sizeGiB), err
 }
 
-// Checks for desired size on volume by also verifying volume size by describing volume.
-// This is to get around potential eventual consistency problems with describing volume modifications
-// objects and ensuring that we read two different objects to verify volume state.
-func (c *cloud) checkDesiredSize(ctx context.Context, volumeID string, newSizeGiB int64) (int64, error) {
+var (
+	// modifyDiskCheckInterval and modifyDiskCheckTimeout bound the wait for a volume type modification.
+	modifyDiskCheckInterval = 3 * time.Second
+	modifyDiskCheckTimeout  = 2 * time.Minute
+)
+
+// ModifyDisk changes the type of an BSU volume.
+// It only returns once ReadVolumes reports the new type, so that callers know the modification is durable.
+func (c *cloud) ModifyDisk(ctx context.Context, volumeID string, volumeType string) error {
 	request := osc.ReadVolumesRequest{
 		Filters: &osc.FiltersVolume{
 			VolumeIds: &[]string{volumeID},
@@ -1226,15 +1765,90 @@ func (c *cloud) checkDesiredSize(ctx context.Context, volumeID string, newSizeGi
 	}
 	volume, err := c.getVolume(ctx, request)
 	if err != nil {
-		return 0, err
+		klog.Errorf("Empty or error during getting the volume %s", volumeID)
+		return err
+	}
+	if volume.GetVolumeType() == volumeType {
+		klog.V(5).Infof("Volume %q is already of type %q", volumeID, volumeType)
+		return nil
 	}
 
-	//resizes in chunks of GiB (not GB)
-	oldSizeGiB := volume.GetSize()
-	if oldSizeGiB >= int32(newSizeGiB) {
-		return int64(oldSizeGiB), nil
+	klog.Infof("modifying volume %q type from %q to %q", volumeID, volume.GetVolumeType(), volumeType)
+	req := osc.UpdateVolumeRequest{
+		VolumeId:   volumeID,
+		VolumeType: &volumeType,
+	}
+
+	updateVolumeCallBack := func() (bool, error) {
+		response, httpRes, err := c.client.UpdateVolume(ctx, req)
+		klog.Infof("Debug response UpdateVolume: response(%+v), err(%v), httpRes(%v)", response, err, httpRes)
+		if err != nil {
+			if httpRes != nil {
+				fmt.Fprintln(os.Stderr, httpRes.Status)
+				requestStr := fmt.Sprintf("%v", req)
+				if c.keepRetryWithError(
+					requestStr,
+					httpRes.StatusCode,
+					ThrottlingError) {
+					return false, nil
+				}
+			}
+			return false, fmt.Errorf("could not modify volume %q: %v", volumeID, err)
+		}
+		return true, nil
+	}
+
+	backoff := c.backoff()
+	waitErr := wait.ExponentialBackoff(backoff, updateVolumeCallBack)
+	if waitErr != nil {
+		return waitErr
+	}
+	return c.waitForVolumeType(ctx, volumeID, volumeType)
+}
+
+// TagDisk adds the tags to the volume, replacing the values of the existing keys.
+func (c *cloud) TagDisk(ctx context.Context, volumeID string, tags map[string]string) error {
+	var resourceTag []osc.ResourceTag
+	for key, value := range tags {
+		resourceTag = append(resourceTag, osc.ResourceTag{Key: key, Value: value})
 	}
-	return int64(oldSizeGiB), fmt.Errorf("volume %q is still being expanded to %d size", volumeID, newSizeGiB)
+	requestTag := osc.CreateTagsRequest{
+		ResourceIds: []string{volumeID},
+		Tags:        resourceTag,
+	}
+
+	createTagsCallBack := func() (bool, error) {
+		resTag, httpRes, err := c.client.CreateTags(ctx, requestTag)
+		klog.Infof("Debug response CreateTags: response(%+v), err(%v), httpRes(%v)", resTag, err, httpRes)
+		if err != nil {
+			if httpRes != nil {
+				fmt.Fprintln(os.Stderr, httpRes.Status)
+				requestStr := fmt.Sprintf("%v", requestTag)
+				if c.keepRetryWithError(
+					requestStr,
+					httpRes.StatusCode,
+					ThrottlingError) {
+					return false, nil
+				}
+			}
+			return false, fmt.Errorf("error creating tags %v of volume %v: %v, http Status: %v", resTag, volumeID, err, httpRes)
+		}
+		return true, nil
+	}
+
+	backoff := c.backoff()
+	return wait.ExponentialBackoff(backoff, createTagsCallBack)
+}
+
+// waitForVolumeType waits for ReadVolumes to report the volume with the given type.
+func (c *cloud) waitForVolumeType(ctx context.Context, volumeID, volumeType string) error {
+	err := c.waitForVolumeState(ctx, volumeID, func(volume *osc.Volume) bool {
+		return volume.GetVolumeType() == volumeType
+	}, pollBackoff(modifyDiskCheckInterval, modifyDiskCheckTimeout))
+	if wait.Interrupted(err) && ctx.Err() == nil {
+		return fmt.Errorf("volume %q is still being modified to type %q", volumeID, volumeType)
+	}
+	return err
 }
 
 // NewCloudWithoutMetadata to instantiate a cloud object outside osc instances
@@ -1243,31 +1857,16 @@ func NewCloudWithoutMetadata(region string) (Cloud, error) {
 		return nil, fmt.Errorf("could not get region")
 	}
 
-	// Set User-Agent with name and version of the CSI driver
-	version := util.GetVersion()
-
-	client := &OscClient{}
-	configEnv := osc.NewConfigEnv()
-	config, err := configEnv.Configuration()
+	client, err := newOscClient(region, "", "")
 	if err != nil {
 		return nil, err
 	}
-	client.config = config
-	client.config.Debug = true
-	client.config.UserAgent = fmt.Sprintf("osc-bsu-csi-driver/%s", version.DriverVersion)
-	client.api = osc.NewAPIClient(client.config)
-
-	client.auth = context.WithValue(context.Background(), osc.ContextAWSv4, osc.AWSv4{
-		AccessKey: os.Getenv("OSC_ACCESS_KEY"),
-		SecretKey: os.Getenv("OSC_SECRET_KEY"),
-	})
-
-	client.auth = context.WithValue(client.auth, osc.ContextServerIndex, 0)
-	client.auth = context.WithValue(client.auth, osc.ContextServerVariables, map[string]string{"region": region})
 
 	return &cloud{
 		region: region,
 		dm:     dm.NewDeviceManager(),
 		client: client,
+		now:    time.Now,
+		sleep:  sleepWithContext,
 	}, nil
 }
@@ -20,9 +20,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	_nethttp "net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/golang/mock/gomock"
@@ -32,6 +36,7 @@ import (
 	dm "github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud/devicemanager"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud/mocks"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/util"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 const (
@@ -213,6 +218,44 @@ func TestCreateDisk(t *testing.T) {
 	}
 }
 
+func TestCreateDiskContextCanceled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	vol := osc.Volume{
+		VolumeId:      osc.PtrString("vol-test"),
+		Size:          osc.PtrInt32(1),
+		State:         osc.PtrString("creating"),
+		SubregionName: osc.PtrString(expZone),
+	}
+	mockOscInterface.EXPECT().CreateVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.CreateVolumeResponse{Volume: &vol}, nil, nil)
+	mockOscInterface.EXPECT().CreateTags(gomock.Eq(ctx), gomock.Any()).DoAndReturn(
+		func(context.Context, osc.CreateTagsRequest) (osc.CreateTagsResponse, *_nethttp.Response, error) {
+			// The CO gives up on the call while the volume is being created
+			time.AfterFunc(100*time.Millisecond, cancel)
+			return osc.CreateTagsResponse{}, nil, nil
+		})
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{Volumes: &[]osc.Volume{vol}}, nil, nil).AnyTimes()
+
+	start := time.Now()
+	_, err := c.CreateDisk(ctx, "vol-test", &DiskOptions{
+		CapacityBytes:    util.GiBToBytes(1),
+		Tags:             map[string]string{VolumeNameTagKey: "vol-test"},
+		AvailabilityZone: expZone,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateDisk() failed: expected error %v, got: %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("CreateDisk() failed: expected a prompt return, took %v", elapsed)
+	}
+}
+
 func TestDeleteDisk(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -307,7 +350,7 @@ func TestAttachDisk(t *testing.T) {
 			mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(newDescribeInstancesOutput(tc.nodeID), nil, nil)
 			mockOscInterface.EXPECT().LinkVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.LinkVolumeResponse{}, nil, tc.expErr)
 
-			devicePath, err := c.AttachDisk(ctx, tc.volumeID, tc.nodeID)
+			devicePath, err := c.AttachDisk(ctx, tc.volumeID, tc.nodeID, "")
 			if err != nil {
 				if tc.expErr == nil {
 					t.Fatalf("AttachDisk() failed: expected no error, got: %v", err)
@@ -326,6 +369,165 @@ func TestAttachDisk(t *testing.T) {
 	}
 }
 
+func TestNewCloudClientOptions(t *testing.T) {
+	t.Setenv("OSC_ACCESS_KEY", "access-key")
+	t.Setenv("OSC_SECRET_KEY", "secret-key")
+
+	c, err := NewCloud("eu-west-2", WithRequestTimeout(30*time.Second), WithMaxRetries(3))
+	if err != nil {
+		t.Fatalf("NewCloud() failed: expected no error, got: %v", err)
+	}
+
+	oscCloud := c.(*cloud)
+	if timeout := oscCloud.client.(*OscClient).config.HTTPClient.Timeout; timeout != 30*time.Second {
+		t.Fatalf("NewCloud() failed: expected request timeout 30s, got %v", timeout)
+	}
+	if _nethttp.DefaultClient.Timeout != 0 {
+		t.Fatal("NewCloud() failed: the default HTTP client was modified")
+	}
+	if steps := oscCloud.backoff().Steps; steps != 4 {
+		t.Fatalf("NewCloud() failed: expected 4 backoff steps, got %d", steps)
+	}
+}
+
+func TestNewCloudWithProfile(t *testing.T) {
+	t.Setenv("OSC_ACCESS_KEY", "env-access-key")
+	t.Setenv("OSC_SECRET_KEY", "env-secret-key")
+
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+	"default": {"access_key": "default-access-key", "secret_key": "default-secret-key", "region": "eu-west-2"},
+	"other": {"access_key": "other-access-key", "secret_key": "other-secret-key", "region": "cloudgouv-eu-west-1", "endpoints": {"api": "api.cloudgouv-eu-west-1.outscale.com/api/v1"}}
+}`
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatalf("could not write the config file: %v", err)
+	}
+
+	c, err := NewCloud("eu-west-2", WithProfile(configFile, "other"), WithRequestTimeout(30*time.Second))
+	if err != nil {
+		t.Fatalf("NewCloud() failed: expected no error, got: %v", err)
+	}
+	oscCloud := c.(*cloud)
+	client := oscCloud.client.(*OscClient)
+	if oscCloud.region != "cloudgouv-eu-west-1" {
+		t.Fatalf("NewCloud() failed: expected region cloudgouv-eu-west-1, got %s", oscCloud.region)
+	}
+	if url := client.config.Servers[0].URL; url != "https://api.cloudgouv-eu-west-1.outscale.com/api/v1" {
+		t.Fatalf("NewCloud() failed: expected the endpoint of the profile, got %s", url)
+	}
+	if variables := client.auth.Value(osc.ContextServerVariables).(map[string]string); variables["region"] != "cloudgouv-eu-west-1" {
+		t.Fatalf("NewCloud() failed: expected region cloudgouv-eu-west-1 in the server variables, got %v", variables)
+	}
+	if auth := client.auth.Value(osc.ContextAWSv4).(osc.AWSv4); auth.AccessKey != "other-access-key" || auth.SecretKey != "other-secret-key" {
+		t.Fatalf("NewCloud() failed: expected the credentials of the profile, got %s", auth.AccessKey)
+	}
+	if timeout := client.config.HTTPClient.Timeout; timeout != 30*time.Second {
+		t.Fatalf("NewCloud() failed: expected request timeout 30s, got %v", timeout)
+	}
+
+	if _, err := NewCloud("eu-west-2", WithProfile(configFile, "missing")); err == nil {
+		t.Fatal("NewCloud() failed: expected an error for a missing profile")
+	}
+}
+
+func TestAttachBackoff(t *testing.T) {
+	c := &cloud{maxRetries: 3}
+	if steps := c.attachBackoff(context.Background()).Steps; steps != 4 {
+		t.Fatalf("expected the 4 backoff steps of the global retries, got %d", steps)
+	}
+	if steps := c.attachBackoff(WithAttachRetries(context.Background(), 9)).Steps; steps != 10 {
+		t.Fatalf("expected the 10 backoff steps of the volume retries, got %d", steps)
+	}
+	if steps := c.attachBackoff(WithAttachRetries(context.Background(), 0)).Steps; steps != 1 {
+		t.Fatalf("expected a single backoff step without retries, got %d", steps)
+	}
+}
+
+func TestGetAttachedDisks(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+
+	nodeID := "node-1234"
+	vms := newDescribeInstancesOutput(nodeID)
+	vms.GetVms()[0].RootDeviceName = osc.PtrString("/dev/sda1")
+	vms.GetVms()[0].BlockDeviceMappings = &[]osc.BlockDeviceMappingCreated{
+		{DeviceName: osc.PtrString("/dev/sda1"), Bsu: &osc.BsuCreated{VolumeId: osc.PtrString("vol-root")}},
+		{DeviceName: osc.PtrString("/dev/xvdb"), Bsu: &osc.BsuCreated{VolumeId: osc.PtrString("vol-data")}},
+	}
+
+	ctx := context.Background()
+	mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(vms, nil, nil)
+
+	volumeIDs, err := c.GetAttachedDisks(ctx, nodeID)
+	if err != nil {
+		t.Fatalf("GetAttachedDisks() failed: expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(volumeIDs, []string{"vol-data"}) {
+		t.Fatalf("GetAttachedDisks() failed: expected [vol-data], got %v", volumeIDs)
+	}
+}
+
+func TestAttachDiskAlreadyAttached(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+
+	volumeID := "vol-test-1234"
+	nodeID := "node-1234"
+	vol := osc.Volume{
+		VolumeId: &volumeID,
+		LinkedVolumes: &[]osc.LinkedVolume{
+			{
+				VmId:       osc.PtrString(nodeID),
+				DeviceName: osc.PtrString("/dev/xvdb"),
+				State:      osc.PtrString("attached"),
+			},
+		},
+	}
+
+	ctx := context.Background()
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{Volumes: &[]osc.Volume{vol}}, nil, nil)
+	mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(newDescribeInstancesOutput(nodeID), nil, nil)
+	mockOscInterface.EXPECT().LinkVolume(gomock.Any(), gomock.Any()).Times(0)
+
+	devicePath, err := c.AttachDisk(ctx, volumeID, nodeID, "")
+	if err != nil {
+		t.Fatalf("AttachDisk() failed: expected no error, got: %v", err)
+	}
+	if devicePath != "/dev/xvdb" {
+		t.Fatalf("AttachDisk() failed: expected device path %q, got %q", "/dev/xvdb", devicePath)
+	}
+}
+
+func TestAttachDiskZoneMismatch(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+
+	volumeID := "vol-test-1234"
+	nodeID := "node-1234"
+	vol := osc.Volume{
+		VolumeId:      &volumeID,
+		SubregionName: osc.PtrString("eu-west-2a"),
+	}
+	vms := newDescribeInstancesOutput(nodeID)
+	vms.GetVms()[0].Placement = &osc.Placement{SubregionName: osc.PtrString("eu-west-2b")}
+
+	ctx := context.Background()
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{Volumes: &[]osc.Volume{vol}}, nil, nil)
+	mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(vms, nil, nil)
+	mockOscInterface.EXPECT().LinkVolume(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := c.AttachDisk(ctx, volumeID, nodeID, "")
+	if !errors.Is(err, ErrZoneMismatch) {
+		t.Fatalf("AttachDisk() failed: expected ErrZoneMismatch, got: %v", err)
+	}
+}
+
 func TestDetachDisk(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -374,7 +576,7 @@ func TestDetachDisk(t *testing.T) {
 			mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(vm, nil, nil)
 			mockOscInterface.EXPECT().UnlinkVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.UnlinkVolumeResponse{}, nil, tc.expErr)
 
-			err := c.DetachDisk(ctx, tc.volumeID, tc.nodeID)
+			err := c.DetachDisk(ctx, tc.volumeID, tc.nodeID, true)
 			if err != nil {
 				if tc.expErr == nil {
 					t.Fatalf("DetachDisk() failed: expected no error, got: %v", err)
@@ -390,6 +592,186 @@ func TestDetachDisk(t *testing.T) {
 	}
 }
 
+func TestDetachDiskSettleDuration(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+	WithDetachSettleDuration(5 * time.Second)(c)
+	var slept []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	volumeID := "vol-test-1234"
+	nodeID := "node-1234"
+	ctx := context.Background()
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{Volumes: &[]osc.Volume{{VolumeId: &volumeID}}}, nil, nil).AnyTimes()
+	vm := newDescribeInstancesOutput(nodeID)
+	devicePath := "/dev/sdb"
+	vm.GetVms()[0].BlockDeviceMappings = &[]osc.BlockDeviceMappingCreated{
+		{
+			DeviceName: &devicePath,
+			Bsu: &osc.BsuCreated{
+				VolumeId: &volumeID,
+			},
+		},
+	}
+	mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(vm, nil, nil)
+	mockOscInterface.EXPECT().UnlinkVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.UnlinkVolumeResponse{}, nil, nil)
+
+	if err := c.DetachDisk(ctx, volumeID, nodeID, true); err != nil {
+		t.Fatalf("DetachDisk() failed: expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(slept, []time.Duration{5 * time.Second}) {
+		t.Fatalf("DetachDisk() failed: expected a settle wait of %v, got %v", 5*time.Second, slept)
+	}
+}
+
+func TestDetachDiskWithoutWait(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+	WithDetachSettleDuration(5 * time.Second)(c)
+	var slept []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	volumeID := "vol-test-1234"
+	nodeID := "node-1234"
+	ctx := context.Background()
+	// the volume stays linked to the stopped VM, only the state check before the unlink reads it
+	linked := []osc.LinkedVolume{{VmId: &nodeID, State: osc.PtrString("attached")}}
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{Volumes: &[]osc.Volume{{VolumeId: &volumeID, State: osc.PtrString("in-use"), LinkedVolumes: &linked}}}, nil, nil).Times(1)
+	vm := newDescribeInstancesOutput(nodeID)
+	devicePath := "/dev/sdb"
+	vm.GetVms()[0].BlockDeviceMappings = &[]osc.BlockDeviceMappingCreated{
+		{
+			DeviceName: &devicePath,
+			Bsu: &osc.BsuCreated{
+				VolumeId: &volumeID,
+			},
+		},
+	}
+	mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(vm, nil, nil)
+	mockOscInterface.EXPECT().UnlinkVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.UnlinkVolumeResponse{}, nil, nil)
+
+	if err := c.DetachDisk(ctx, volumeID, nodeID, false); err != nil {
+		t.Fatalf("DetachDisk() failed: expected no error, got: %v", err)
+	}
+	if len(slept) != 0 {
+		t.Fatalf("DetachDisk() failed: expected no settle wait, got %v", slept)
+	}
+}
+
+// fakeAPIError is an error of the Outscale API with the JSON body body.
+type fakeAPIError struct {
+	body string
+}
+
+func (e fakeAPIError) Error() string      { return "409 Conflict" }
+func (e fakeAPIError) Body() []byte       { return []byte(e.body) }
+func (e fakeAPIError) Model() interface{} { return nil }
+
+func TestDetachDiskBusyRetries(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+	WithDetachBusyRetries(2)(c)
+	var slept []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	volumeID := "vol-test-1234"
+	nodeID := "node-1234"
+	ctx := context.Background()
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{Volumes: &[]osc.Volume{{VolumeId: &volumeID}}}, nil, nil).AnyTimes()
+	vm := newDescribeInstancesOutput(nodeID)
+	devicePath := "/dev/sdb"
+	vm.GetVms()[0].BlockDeviceMappings = &[]osc.BlockDeviceMappingCreated{
+		{
+			DeviceName: &devicePath,
+			Bsu: &osc.BsuCreated{
+				VolumeId: &volumeID,
+			},
+		},
+	}
+	mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(vm, nil, nil)
+	busyErr := fakeAPIError{body: `{"Errors":[{"Type":"InvalidState","Details":"The volume is busy","Code":"6003"}]}`}
+	gomock.InOrder(
+		mockOscInterface.EXPECT().UnlinkVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.UnlinkVolumeResponse{}, &_nethttp.Response{StatusCode: 409, Status: "409 Conflict"}, busyErr),
+		mockOscInterface.EXPECT().UnlinkVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.UnlinkVolumeResponse{}, nil, nil),
+	)
+
+	if err := c.DetachDisk(ctx, volumeID, nodeID, true); err != nil {
+		t.Fatalf("DetachDisk() failed: expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(slept, []time.Duration{DefaultDetachBusyRetryDelay}) {
+		t.Fatalf("DetachDisk() failed: expected a single busy retry after %v, got %v", DefaultDetachBusyRetryDelay, slept)
+	}
+}
+
+func TestDetachDiskBusyRetryCanceled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+	WithDetachBusyRetries(2)(c)
+
+	volumeID := "vol-test-1234"
+	nodeID := "node-1234"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{Volumes: &[]osc.Volume{{VolumeId: &volumeID}}}, nil, nil).AnyTimes()
+	vm := newDescribeInstancesOutput(nodeID)
+	devicePath := "/dev/sdb"
+	vm.GetVms()[0].BlockDeviceMappings = &[]osc.BlockDeviceMappingCreated{
+		{
+			DeviceName: &devicePath,
+			Bsu: &osc.BsuCreated{
+				VolumeId: &volumeID,
+			},
+		},
+	}
+	mockOscInterface.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(vm, nil, nil)
+	busyErr := fakeAPIError{body: `{"Errors":[{"Type":"InvalidState","Details":"The volume is busy","Code":"6003"}]}`}
+	mockOscInterface.EXPECT().UnlinkVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.UnlinkVolumeResponse{}, &_nethttp.Response{StatusCode: 409, Status: "409 Conflict"}, busyErr).Times(1)
+
+	// the retry is not waited for once the context is canceled
+	start := time.Now()
+	if err := c.DetachDisk(ctx, volumeID, nodeID, true); !errors.Is(err, context.Canceled) {
+		t.Fatalf("DetachDisk() failed: expected %v, got: %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed >= DefaultDetachBusyRetryDelay {
+		t.Fatalf("DetachDisk() failed: expected to return before the retry delay of %v, took %v", DefaultDetachBusyRetryDelay, elapsed)
+	}
+}
+
+func TestIsVolumeBusyError(t *testing.T) {
+	if !isVolumeBusyError(fmt.Errorf("could not detach: %w", fakeAPIError{body: `{"Errors":[{"Type":"InvalidState","Details":"The volume is busy"}]}`})) {
+		t.Fatal("expected a busy volume to be a busy error")
+	}
+	if !isVolumeBusyError(fakeAPIError{body: `{"Errors":[{"Type":"InvalidState","Details":"The volume is in use"}]}`}) {
+		t.Fatal("expected a volume in use to be a busy error")
+	}
+	if isVolumeBusyError(fakeAPIError{body: `{"Errors":[{"Type":"InvalidState","Details":"The volume is not linked"}]}`}) {
+		t.Fatal("expected another InvalidState not to be a busy error")
+	}
+	if isVolumeBusyError(fakeAPIError{body: `{"Errors":[{"Type":"InvalidResource"}]}`}) {
+		t.Fatal("expected InvalidResource not to be a busy error")
+	}
+	if isVolumeBusyError(errors.New("generic error")) {
+		t.Fatal("expected a generic error not to be a busy error")
+	}
+}
+
 func TestGetDiskByName(t *testing.T) {
 	testCases := []struct {
 		name             string
@@ -471,6 +853,7 @@ func TestGetDiskByID(t *testing.T) {
 		volumeID         string
 		availabilityZone string
 		snapshotId       *string
+		iops             *int32
 		expErr           error
 	}{
 
@@ -488,6 +871,13 @@ func TestGetDiskByID(t *testing.T) {
 			availabilityZone: expZone,
 			expErr:           nil,
 		},
+		{
+			name:             "success: normal with iops",
+			volumeID:         "vol-test-1234",
+			availabilityZone: expZone,
+			iops:             osc.PtrInt32(1500),
+			expErr:           nil,
+		},
 		{
 			name:       "fail: DescribeVolumes returned generic error",
 			volumeID:   "vol-test-1234",
@@ -510,6 +900,7 @@ func TestGetDiskByID(t *testing.T) {
 							VolumeId:      &tc.volumeID,
 							SubregionName: &tc.availabilityZone,
 							SnapshotId:    tc.snapshotId,
+							Iops:          tc.iops,
 						},
 					},
 				},
@@ -535,6 +926,9 @@ func TestGetDiskByID(t *testing.T) {
 				if tc.snapshotId != nil && *tc.snapshotId != disk.SnapshotID {
 					t.Fatalf("GetDiskByID() failed: expected snapshotId %q, got %q", *tc.snapshotId, disk.SnapshotID)
 				}
+				if tc.iops != nil && int64(*tc.iops) != disk.IOPS {
+					t.Fatalf("GetDiskByID() failed: expected iops %d, got %d", *tc.iops, disk.IOPS)
+				}
 			}
 
 			mockCtrl.Finish()
@@ -542,6 +936,144 @@ func TestGetDiskByID(t *testing.T) {
 	}
 }
 
+func TestGetDiskByIDMultipleVolumes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+
+	ctx := context.Background()
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(
+		osc.ReadVolumesResponse{
+			Volumes: &[]osc.Volume{
+				{VolumeId: osc.PtrString("vol-test-1234")},
+				{VolumeId: osc.PtrString("vol-test-1234")},
+			},
+		},
+		nil,
+		nil,
+	)
+
+	_, err := c.GetDiskByID(ctx, "vol-test-1234")
+	if err != ErrMultiVolumes {
+		t.Fatalf("GetDiskByID() failed: expected error %v, got: %v", ErrMultiVolumes, err)
+	}
+}
+
+func TestGetDisksByIDs(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+
+	ctx := context.Background()
+	mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, request osc.ReadVolumesRequest) (osc.ReadVolumesResponse, *_nethttp.Response, error) {
+			if ids := request.Filters.GetVolumeIds(); !reflect.DeepEqual(ids, []string{"vol-1", "vol-2", "vol-deleted"}) {
+				t.Fatalf("Expected a single read of all the volume IDs, got %v", ids)
+			}
+			return osc.ReadVolumesResponse{
+				Volumes: &[]osc.Volume{
+					{VolumeId: osc.PtrString("vol-1"), Size: osc.PtrInt32(10)},
+					{VolumeId: osc.PtrString("vol-2"), Size: osc.PtrInt32(20)},
+				},
+			}, nil, nil
+		}).Times(1)
+
+	disks, err := c.GetDisksByIDs(ctx, []string{"vol-1", "vol-2", "vol-deleted"})
+	if err != nil {
+		t.Fatalf("GetDisksByIDs() failed: expected no error, got: %v", err)
+	}
+	if len(disks) != 2 || disks["vol-1"].CapacityGiB != 10 || disks["vol-2"].CapacityGiB != 20 {
+		t.Fatalf("GetDisksByIDs() failed: unexpected disks %+v", disks)
+	}
+
+	// Without volume ID, nothing is read
+	disks, err = c.GetDisksByIDs(ctx, nil)
+	if err != nil || len(disks) != 0 {
+		t.Fatalf("GetDisksByIDs() failed: expected no disk, got: %+v, %v", disks, err)
+	}
+}
+
+func TestGetDisksByIDsPages(t *testing.T) {
+	defer func(max int) { maxVolumeIDsPerRead = max }(maxVolumeIDsPerRead)
+	maxVolumeIDsPerRead = 2
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+
+	ctx := context.Background()
+	newRequest := func(nextPageToken *string, volumeIDs ...string) osc.ReadVolumesRequest {
+		return osc.ReadVolumesRequest{
+			Filters:        &osc.FiltersVolume{VolumeIds: &volumeIDs},
+			ResultsPerPage: osc.PtrInt32(MaxResultsPerPage),
+			NextPageToken:  nextPageToken,
+		}
+	}
+	gomock.InOrder(
+		mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Eq(newRequest(nil, "vol-1", "vol-2"))).Return(osc.ReadVolumesResponse{
+			Volumes:       &[]osc.Volume{{VolumeId: osc.PtrString("vol-1")}},
+			NextPageToken: osc.PtrString("token-1"),
+		}, nil, nil),
+		mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Eq(newRequest(osc.PtrString("token-1"), "vol-1", "vol-2"))).Return(osc.ReadVolumesResponse{
+			Volumes: &[]osc.Volume{{VolumeId: osc.PtrString("vol-2")}},
+		}, nil, nil),
+		mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Eq(newRequest(nil, "vol-3"))).Return(osc.ReadVolumesResponse{
+			Volumes: &[]osc.Volume{{VolumeId: osc.PtrString("vol-3")}},
+		}, nil, nil),
+	)
+
+	disks, err := c.GetDisksByIDs(ctx, []string{"vol-1", "vol-2", "vol-3"})
+	if err != nil {
+		t.Fatalf("GetDisksByIDs() failed: expected no error, got: %v", err)
+	}
+	for _, volumeID := range []string{"vol-1", "vol-2", "vol-3"} {
+		if _, ok := disks[volumeID]; !ok {
+			t.Fatalf("GetDisksByIDs() failed: volume %s missing from %+v", volumeID, disks)
+		}
+	}
+}
+
+func TestListDisksPages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOscInterface)
+
+	ctx := context.Background()
+	gomock.InOrder(
+		mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, request osc.ReadVolumesRequest) (osc.ReadVolumesResponse, *_nethttp.Response, error) {
+				if request.HasNextPageToken() {
+					t.Fatalf("Expected no token on the first page, got %q", request.GetNextPageToken())
+				}
+				return osc.ReadVolumesResponse{
+					Volumes:       &[]osc.Volume{{VolumeId: osc.PtrString("vol-1")}},
+					NextPageToken: osc.PtrString("token-1"),
+				}, nil, nil
+			}),
+		mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, request osc.ReadVolumesRequest) (osc.ReadVolumesResponse, *_nethttp.Response, error) {
+				if token := request.GetNextPageToken(); token != "token-1" {
+					t.Fatalf("Expected token %q, got %q", "token-1", token)
+				}
+				return osc.ReadVolumesResponse{
+					Volumes: &[]osc.Volume{{VolumeId: osc.PtrString("vol-2")}},
+				}, nil, nil
+			}),
+	)
+
+	disks, err := c.ListDisks(ctx, map[string]string{ClusterIDTagKey: "cluster-test"})
+	if err != nil {
+		t.Fatalf("ListDisks() failed: expected no error, got: %v", err)
+	}
+	if len(disks) != 2 || disks[0].VolumeID != "vol-1" || disks[1].VolumeID != "vol-2" {
+		t.Fatalf("ListDisks() failed: unexpected disks %+v", disks)
+	}
+}
+
 func TestCreateSnapshot(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -705,6 +1237,32 @@ func TestGetSnapshotByName(t *testing.T) {
 	}
 }
 
+func TestCheckCreatedSnapshot(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name   string
+		state  string
+		age    time.Duration
+		expErr error
+	}{
+		{name: "completed", state: "completed", age: 48 * time.Hour},
+		{name: "recent pending", state: "pending", age: time.Minute},
+		{name: "recent in-queue", state: "in-queue", age: time.Minute},
+		{name: "stuck pending", state: "pending", age: 2 * time.Hour, expErr: ErrSnapshotPending},
+		{name: "stuck in-queue", state: "in-queue", age: 2 * time.Hour, expErr: ErrSnapshotPending},
+		{name: "error", state: "error", age: time.Minute, expErr: ErrSnapshotFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			snapshot := Snapshot{SnapshotID: "snap-test", State: tc.state, CreationTime: now.Add(-tc.age)}
+			if err := CheckCreatedSnapshot(snapshot, time.Hour, now); err != tc.expErr {
+				t.Fatalf("CheckCreatedSnapshot() failed: expected error %v, got %v", tc.expErr, err)
+			}
+		})
+	}
+}
+
 func TestGetSnapshotByID(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -862,6 +1420,141 @@ func TestListSnapshots(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success: with volume ID and pagination",
+			testFunc: func(t *testing.T) {
+				sourceVolumeID := "snap-test-volume"
+				oscsnapshot := []osc.Snapshot{
+					{
+						SnapshotId: osc.PtrString("snap-test-name2"),
+						VolumeId:   &sourceVolumeID,
+						State:      osc.PtrString("completed"),
+					},
+				}
+
+				mockCtrl := gomock.NewController(t)
+				defer mockCtrl.Finish()
+				mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+				c := newCloud(mockOscInterface)
+
+				ctx := context.Background()
+
+				expRequest := osc.ReadSnapshotsRequest{
+					Filters: &osc.FiltersSnapshot{
+						VolumeIds: &[]string{sourceVolumeID},
+					},
+					ResultsPerPage: osc.PtrInt32(1),
+					NextPageToken:  osc.PtrString("token-1"),
+				}
+				mockOscInterface.EXPECT().ReadSnapshots(gomock.Eq(ctx), gomock.Eq(expRequest)).Return(
+					osc.ReadSnapshotsResponse{Snapshots: &oscsnapshot, NextPageToken: osc.PtrString("token-2")}, nil, nil)
+
+				resp, err := c.ListSnapshots(ctx, sourceVolumeID, 1, "token-1")
+				if err != nil {
+					t.Fatalf("ListSnapshots() failed: expected no error, got: %v", err)
+				}
+				if len(resp.Snapshots) != 1 {
+					t.Fatalf("Expected 1 snapshot, got %d", len(resp.Snapshots))
+				}
+				if resp.NextToken != "token-2" {
+					t.Fatalf("Expected next token %q, got %q", "token-2", resp.NextToken)
+				}
+			},
+		},
+		{
+			name: "success: without volume ID and pagination",
+			testFunc: func(t *testing.T) {
+				oscsnapshot := []osc.Snapshot{
+					{
+						SnapshotId: osc.PtrString("snap-test-name2"),
+						VolumeId:   osc.PtrString("snap-test-volume"),
+						State:      osc.PtrString("completed"),
+					},
+				}
+
+				mockCtrl := gomock.NewController(t)
+				defer mockCtrl.Finish()
+				mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+				c := newCloud(mockOscInterface)
+
+				ctx := context.Background()
+
+				expRequest := osc.ReadSnapshotsRequest{
+					Filters: &osc.FiltersSnapshot{
+						VolumeIds: &[]string{},
+					},
+					ResultsPerPage: osc.PtrInt32(MaxResultsPerPage),
+					NextPageToken:  osc.PtrString("token-1"),
+				}
+				mockOscInterface.EXPECT().ReadSnapshots(gomock.Eq(ctx), gomock.Eq(expRequest)).Return(
+					osc.ReadSnapshotsResponse{Snapshots: &oscsnapshot, NextPageToken: osc.PtrString("token-2")}, nil, nil)
+
+				resp, err := c.ListSnapshots(ctx, "", 5000, "token-1")
+				if err != nil {
+					t.Fatalf("ListSnapshots() failed: expected no error, got: %v", err)
+				}
+				if len(resp.Snapshots) != 1 {
+					t.Fatalf("Expected 1 snapshot, got %d", len(resp.Snapshots))
+				}
+				if resp.NextToken != "token-2" {
+					t.Fatalf("Expected next token %q, got %q", "token-2", resp.NextToken)
+				}
+			},
+		},
+		{
+			name: "fail: invalid next token",
+			testFunc: func(t *testing.T) {
+				mockCtrl := gomock.NewController(t)
+				defer mockCtrl.Finish()
+				mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+				c := newCloud(mockOscInterface)
+
+				ctx := context.Background()
+
+				tokenErr := fakeAPIError{body: `{"Errors":[{"Type":"InvalidParameterValue","Details":"Invalid NextPageToken","Code":"4047"}]}`}
+				mockOscInterface.EXPECT().ReadSnapshots(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadSnapshotsResponse{}, &_nethttp.Response{StatusCode: 400, Status: "400 Bad Request"}, tokenErr)
+
+				if _, err := c.ListSnapshots(ctx, "", 0, "token-invalid"); !errors.Is(err, ErrInvalidNextToken) {
+					t.Fatalf("Expected error %v, got %v", ErrInvalidNextToken, err)
+				}
+			},
+		},
+		{
+			name: "success: with cluster ID",
+			testFunc: func(t *testing.T) {
+				oscsnapshot := []osc.Snapshot{
+					{
+						SnapshotId: osc.PtrString("snap-test-name1"),
+						VolumeId:   osc.PtrString("snap-test-volume"),
+						State:      osc.PtrString("completed"),
+					},
+				}
+
+				mockCtrl := gomock.NewController(t)
+				defer mockCtrl.Finish()
+				mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+				c := newCloud(mockOscInterface)
+				WithClusterID("cluster-1")(c)
+
+				ctx := context.Background()
+
+				expRequest := osc.ReadSnapshotsRequest{
+					Filters: &osc.FiltersSnapshot{
+						VolumeIds: &[]string{},
+						Tags:      &[]string{ClusterIDTagKey + "=cluster-1"},
+					},
+				}
+				mockOscInterface.EXPECT().ReadSnapshots(gomock.Eq(ctx), gomock.Eq(expRequest)).Return(osc.ReadSnapshotsResponse{Snapshots: &oscsnapshot}, nil, nil)
+
+				resp, err := c.ListSnapshots(ctx, "", 0, "")
+				if err != nil {
+					t.Fatalf("ListSnapshots() failed: expected no error, got: %v", err)
+				}
+				if len(resp.Snapshots) != 1 {
+					t.Fatalf("Expected 1 snapshot, got %d", len(resp.Snapshots))
+				}
+			},
+		},
 		{
 			name: "fail: Osc ReadSnasphot error",
 			testFunc: func(t *testing.T) {
@@ -912,6 +1605,8 @@ func newCloud(mockOscInterface OscInterface) *cloud {
 		region: defaultRegion,
 		dm:     dm.NewDeviceManager(),
 		client: mockOscInterface,
+		now:    time.Now,
+		sleep:  sleepWithContext,
 	}
 }
 
@@ -1052,3 +1747,164 @@ func TestResizeDisk(t *testing.T) {
 		})
 	}
 }
+
+func TestModifyDisk(t *testing.T) {
+	volumeId := "vol-test"
+	state := "available"
+	oldType := "standard"
+	newType := "gp2"
+	volumeWithType := func(volumeType string) osc.ReadVolumesResponse {
+		return osc.ReadVolumesResponse{
+			Volumes: &[]osc.Volume{
+				{
+					VolumeId:   &volumeId,
+					State:      &state,
+					VolumeType: &volumeType,
+				},
+			},
+		}
+	}
+
+	defer func(interval, timeout time.Duration) {
+		modifyDiskCheckInterval, modifyDiskCheckTimeout = interval, timeout
+	}(modifyDiskCheckInterval, modifyDiskCheckTimeout)
+	modifyDiskCheckInterval = time.Millisecond
+	modifyDiskCheckTimeout = 100 * time.Millisecond
+
+	testCases := []struct {
+		name       string
+		readTypes  []string
+		updateErr  error
+		expErr     bool
+		expUpdated bool
+	}{
+		{
+			name:       "success: type reported on a later read",
+			readTypes:  []string{oldType, oldType, oldType, newType},
+			expUpdated: true,
+		},
+		{
+			name:      "success: already of the requested type",
+			readTypes: []string{newType},
+		},
+		{
+			name:       "fail: update error",
+			readTypes:  []string{oldType},
+			updateErr:  fmt.Errorf("UpdateVolume generic error"),
+			expErr:     true,
+			expUpdated: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := mocks.NewMockOscInterface(mockCtrl)
+			c := newCloud(mockEC2)
+			ctx := context.Background()
+
+			var calls []*gomock.Call
+			for _, readType := range tc.readTypes {
+				calls = append(calls, mockEC2.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(volumeWithType(readType), nil, nil))
+			}
+			gomock.InOrder(calls...)
+			if tc.expUpdated {
+				mockEC2.EXPECT().UpdateVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.UpdateVolumeResponse{}, nil, tc.updateErr)
+			}
+
+			err := c.ModifyDisk(ctx, volumeId, newType)
+			if err != nil {
+				if !tc.expErr {
+					t.Fatalf("ModifyDisk() failed: expected no error, got: %v", err)
+				}
+			} else if tc.expErr {
+				t.Fatal("ModifyDisk() failed: expected error, got nothing")
+			}
+
+			mockCtrl.Finish()
+		})
+	}
+}
+
+func TestWaitForVolumeState(t *testing.T) {
+	volumeID := "vol-test"
+	volumeInState := func(state string) osc.ReadVolumesResponse {
+		return osc.ReadVolumesResponse{
+			Volumes: &[]osc.Volume{{VolumeId: &volumeID, State: osc.PtrString(state)}},
+		}
+	}
+	isAvailable := func(volume *osc.Volume) bool {
+		return volume.GetState() == "available"
+	}
+	backoff := wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+
+	testCases := []struct {
+		name   string
+		states []string
+		expErr error
+	}{
+		{
+			name:   "success: state reached after retries",
+			states: []string{"creating", "creating", "available"},
+		},
+		{
+			name:   "fail: state never reached",
+			states: []string{"creating", "creating", "creating"},
+			expErr: wait.ErrWaitTimeout,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockOscInterface := mocks.NewMockOscInterface(mockCtrl)
+			c := newCloud(mockOscInterface)
+			ctx := context.Background()
+
+			var calls []*gomock.Call
+			for _, state := range tc.states {
+				calls = append(calls, mockOscInterface.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(volumeInState(state), nil, nil))
+			}
+			gomock.InOrder(calls...)
+
+			err := c.waitForVolumeState(ctx, volumeID, isAvailable, backoff)
+			if !errors.Is(err, tc.expErr) {
+				t.Fatalf("waitForVolumeState() failed: expected error %v, got: %v", tc.expErr, err)
+			}
+		})
+	}
+}
+
+func TestModifyDiskTimeout(t *testing.T) {
+	volumeId := "vol-test"
+	state := "available"
+	oldType := "standard"
+
+	defer func(interval, timeout time.Duration) {
+		modifyDiskCheckInterval, modifyDiskCheckTimeout = interval, timeout
+	}(modifyDiskCheckInterval, modifyDiskCheckTimeout)
+	modifyDiskCheckInterval = time.Millisecond
+	modifyDiskCheckTimeout = 10 * time.Millisecond
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockEC2 := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockEC2)
+	ctx := context.Background()
+
+	mockEC2.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{
+		Volumes: &[]osc.Volume{
+			{
+				VolumeId:   &volumeId,
+				State:      &state,
+				VolumeType: &oldType,
+			},
+		},
+	}, nil, nil).MinTimes(2)
+	mockEC2.EXPECT().UpdateVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.UpdateVolumeResponse{}, nil, nil)
+
+	if err := c.ModifyDisk(ctx, volumeId, "gp2"); err == nil {
+		t.Fatal("ModifyDisk() failed: expected error, got nothing")
+	}
+}
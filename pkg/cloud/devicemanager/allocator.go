@@ -40,6 +40,18 @@ type NameAllocator interface {
 	GetNext(existingNames ExistingNames) (name string, err error)
 }
 
+var deviceNames = [40]string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "aa", "ab", "ac", "ad", "ae", "af", "ag", "ah", "ai", "aj", "ak", "al", "am", "an"}
+
+// isValidDeviceName returns true when the name is one of the names the allocator can assign.
+func isValidDeviceName(name string) bool {
+	for i := 1; i < len(deviceNames); i++ {
+		if deviceNames[i] == name {
+			return true
+		}
+	}
+	return false
+}
+
 type nameAllocator struct{}
 
 var _ NameAllocator = &nameAllocator{}
@@ -51,9 +63,8 @@ var _ NameAllocator = &nameAllocator{}
 //
 // and return the first one that is not used yet.
 func (d *nameAllocator) GetNext(existingNames ExistingNames) (string, error) {
-	device_map := [40]string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "aa", "ab", "ac", "ad", "ae", "af", "ag", "ah", "ai", "aj", "ak", "al", "am", "an"}
-	for i := 1; i < 40; i++ {
-		name := device_map[i]
+	for i := 1; i < len(deviceNames); i++ {
+		name := deviceNames[i]
 		if _, found := existingNames[name]; !found {
 			return name, nil
 		}
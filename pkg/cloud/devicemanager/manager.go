@@ -17,6 +17,7 @@ limitations under the License.
 package devicemanager
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -28,6 +29,9 @@ import (
 
 const devPreffix = "/dev/xvd"
 
+// ErrDeviceNameInUse is returned when the requested device name is already assigned to another volume.
+var ErrDeviceNameInUse = errors.New("device name is already in use")
+
 type Device struct {
 	Instance          osc.Vm
 	Path              string
@@ -65,8 +69,17 @@ type DeviceManager interface {
 	// and mark it as unassigned device.
 	NewDevice(instance osc.Vm, volumeID string) (device Device, err error)
 
+	// NewDeviceWithName is like NewDevice but uses the requested device name
+	// (e.g. "/dev/xvdb") instead of the next available one.
+	// It returns ErrDeviceNameInUse when the name is assigned to another volume.
+	NewDeviceWithName(instance osc.Vm, volumeID string, deviceName string) (device Device, err error)
+
 	// GetDevice returns the device already assigned to the volume.
 	GetDevice(instance osc.Vm, volumeID string) (device Device)
+
+	// Snapshot returns a copy of the device names known to be assigned, attached or being attached,
+	// as {"nodeID": {"deviceName": "volumeID"}}. It is meant for debugging.
+	Snapshot() map[string]map[string]string
 }
 
 type deviceManager struct {
@@ -78,6 +91,9 @@ type deviceManager struct {
 	// and then get a second request before we attach the volume.
 	mux      sync.Mutex
 	inFlight inFlightAttaching
+
+	// attached keeps the device names attached to each node, as last reported by the API.
+	attached map[string]map[string]string
 }
 
 var _ DeviceManager = &deviceManager{}
@@ -111,6 +127,7 @@ func NewDeviceManager() DeviceManager {
 	return &deviceManager{
 		nameAllocator: &nameAllocator{},
 		inFlight:      make(inFlightAttaching),
+		attached:      make(map[string]map[string]string),
 	}
 }
 
@@ -146,6 +163,44 @@ func (d *deviceManager) NewDevice(instance osc.Vm, volumeID string) (Device, err
 	return d.newBlockDevice(instance, volumeID, devPreffix+name, false), nil
 }
 
+func (d *deviceManager) NewDeviceWithName(instance osc.Vm, volumeID string, deviceName string) (Device, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if IsNilVm(instance) {
+		return Device{}, fmt.Errorf("instance is nil")
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(deviceName, "/dev/sd"), devPreffix)
+	if !isValidDeviceName(name) {
+		return Device{}, fmt.Errorf("invalid device name %q", deviceName)
+	}
+
+	inUse := d.getDeviceNamesInUse(instance)
+
+	// Check if this volume is already assigned a device on this machine
+	if path := d.getPath(inUse, volumeID); path != "" {
+		if path != devPreffix+name {
+			return Device{}, fmt.Errorf("volume %s is already assigned to %s: %w", volumeID, path, ErrDeviceNameInUse)
+		}
+		return d.newBlockDevice(instance, volumeID, path, true), nil
+	}
+
+	if existingVolumeID, found := inUse[name]; found {
+		return Device{}, fmt.Errorf("%s is assigned to volume %s: %w", devPreffix+name, existingVolumeID, ErrDeviceNameInUse)
+	}
+
+	nodeID, err := getInstanceID(instance)
+	if err != nil {
+		return Device{}, err
+	}
+
+	// Add the chosen device and volume to the "attachments in progress" map
+	d.inFlight.Add(nodeID, volumeID, name)
+
+	return d.newBlockDevice(instance, volumeID, devPreffix+name, false), nil
+}
+
 func (d *deviceManager) GetDevice(instance osc.Vm, volumeID string) Device {
 	d.mux.Lock()
 	defer d.mux.Unlock()
@@ -224,6 +279,11 @@ func (d *deviceManager) getDeviceNamesInUse(instance osc.Vm) map[string]string {
 		}
 		inUse[name] = blockDevice.Bsu.GetVolumeId()
 	}
+	attached := make(map[string]string, len(inUse))
+	for name, volumeID := range inUse {
+		attached[name] = volumeID
+	}
+	d.attached[nodeID] = attached
 
 	klog.V(5).Infof("DeviceNameInUse: APIDevice: %v, CacheDevice: %v", inUse, d.inFlight.GetNames(nodeID))
 	for name, volumeID := range d.inFlight.GetNames(nodeID) {
@@ -233,6 +293,27 @@ func (d *deviceManager) getDeviceNamesInUse(instance osc.Vm) map[string]string {
 	return inUse
 }
 
+func (d *deviceManager) Snapshot() map[string]map[string]string {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	snapshot := map[string]map[string]string{}
+	for _, assignments := range []map[string]map[string]string{d.attached, d.inFlight} {
+		for nodeID, names := range assignments {
+			if len(names) == 0 {
+				continue
+			}
+			if snapshot[nodeID] == nil {
+				snapshot[nodeID] = map[string]string{}
+			}
+			for name, volumeID := range names {
+				snapshot[nodeID][devPreffix+name] = volumeID
+			}
+		}
+	}
+	return snapshot
+}
+
 func (d *deviceManager) getPath(inUse map[string]string, volumeID string) string {
 	for name, volID := range inUse {
 		if volumeID == volID {
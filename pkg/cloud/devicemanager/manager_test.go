@@ -17,6 +17,8 @@ limitations under the License.
 package devicemanager
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 
 	osc "github.com/outscale/osc-sdk-go/v2"
@@ -91,6 +93,76 @@ func TestNewDevice(t *testing.T) {
 	}
 }
 
+func TestNewDeviceWithName(t *testing.T) {
+	testCases := []struct {
+		name       string
+		deviceName string
+		volumeID   string
+		expPath    string
+		expErr     error
+		expInvalid bool
+	}{
+		{
+			name:       "success: free device name",
+			deviceName: "/dev/xvdc",
+			volumeID:   "vol-2",
+			expPath:    "/dev/xvdc",
+		},
+		{
+			name:       "success: short device name",
+			deviceName: "d",
+			volumeID:   "vol-2",
+			expPath:    "/dev/xvdd",
+		},
+		{
+			name:       "fail: device name used by another volume",
+			deviceName: "/dev/xvdb",
+			volumeID:   "vol-2",
+			expErr:     ErrDeviceNameInUse,
+		},
+		{
+			name:       "fail: volume assigned to another device name",
+			deviceName: "/dev/xvdc",
+			volumeID:   "vol-1",
+			expErr:     ErrDeviceNameInUse,
+		},
+		{
+			name:       "fail: invalid device name",
+			deviceName: "/dev/xvdzz",
+			volumeID:   "vol-2",
+			expInvalid: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dm := NewDeviceManager()
+			fakeInstance := newFakeInstance("instance-1", "vol-1", "/dev/xvdb")
+
+			dev, err := dm.NewDeviceWithName(fakeInstance, tc.volumeID, tc.deviceName)
+			if tc.expErr != nil || tc.expInvalid {
+				if err == nil {
+					t.Fatalf("Expected error, got nothing")
+				}
+				if tc.expErr != nil && !errors.Is(err, tc.expErr) {
+					t.Fatalf("Expected error %v, got %v", tc.expErr, err)
+				}
+				return
+			}
+			assertDevice(t, dev, false, err)
+			if dev.Path != tc.expPath {
+				t.Fatalf("Expected path %v, got %v", tc.expPath, dev.Path)
+			}
+
+			// The name is reserved until the device is released
+			if _, err := dm.NewDeviceWithName(fakeInstance, "vol-3", tc.deviceName); !errors.Is(err, ErrDeviceNameInUse) {
+				t.Fatalf("Expected error %v, got %v", ErrDeviceNameInUse, err)
+			}
+			dev.Release(false)
+		})
+	}
+}
+
 func TestGetDevice(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -184,6 +256,31 @@ func TestReleaseDevice(t *testing.T) {
 	}
 }
 
+func TestSnapshot(t *testing.T) {
+	dm := NewDeviceManager()
+	fakeInstance := newFakeInstance("instance-1", "vol-1", "/dev/xvdb")
+
+	dev, err := dm.NewDevice(fakeInstance, "vol-2")
+	assertDevice(t, dev, false, err)
+
+	expected := map[string]map[string]string{
+		"instance-1": {
+			"/dev/xvdb": "vol-1",
+			dev.Path:    "vol-2",
+		},
+	}
+	if snapshot := dm.Snapshot(); !reflect.DeepEqual(snapshot, expected) {
+		t.Fatalf("Expected snapshot %v, got %v", expected, snapshot)
+	}
+
+	// Once released, the in-flight attachment is no longer reported
+	dev.Release(true)
+	expected["instance-1"] = map[string]string{"/dev/xvdb": "vol-1"}
+	if snapshot := dm.Snapshot(); !reflect.DeepEqual(snapshot, expected) {
+		t.Fatalf("Expected snapshot %v, got %v", expected, snapshot)
+	}
+}
+
 func newFakeInstance(instanceID, volumeID, devicePath string) osc.Vm {
 	return osc.Vm{
 		VmId: &instanceID,
@@ -0,0 +1,35 @@
+package cloud
+
+import (
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// attachmentDuration measures the attachments and detachments of volumes, from the request to the Outscale API
+// until the volume reaches the expected state. Unlike the latency of the CSI calls, it leaves out the handling
+// of the calls by the driver.
+var attachmentDuration = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Subsystem:      "osc_bsu_csi",
+		Name:           "attachment_duration_seconds",
+		Help:           "Duration of the volume attachments and detachments, from the request until the volume reaches the expected state",
+		Buckets:        metrics.ExponentialBuckets(0.5, 2, 10),
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"operation", "result"},
+)
+
+func init() {
+	legacyregistry.MustRegister(attachmentDuration)
+}
+
+// observeAttachment records the duration of an attachment or a detachment started at start.
+func (c *cloud) observeAttachment(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	attachmentDuration.WithLabelValues(operation, result).Observe(c.now().Sub(start).Seconds())
+}
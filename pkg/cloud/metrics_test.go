@@ -0,0 +1,90 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	osc "github.com/outscale/osc-sdk-go/v2"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud/mocks"
+)
+
+// fakeClock returns the times in order, one per call.
+func fakeClock(t *testing.T, times ...time.Time) func() time.Time {
+	return func() time.Time {
+		if len(times) == 0 {
+			t.Fatal("unexpected call to the clock")
+		}
+		now := times[0]
+		times = times[1:]
+		return now
+	}
+}
+
+func TestAttachmentDuration(t *testing.T) {
+	start := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		operation string
+		result    string
+		linkErr   error
+	}{
+		{
+			name:      "attach success",
+			operation: "attach",
+			result:    "success",
+		},
+		{
+			name:      "attach error",
+			operation: "attach",
+			result:    "error",
+			linkErr:   fmt.Errorf("LinkVolume generic error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockOsc := mocks.NewMockOscInterface(mockCtrl)
+			c := newCloud(mockOsc)
+			c.now = fakeClock(t, start, start.Add(7*time.Second))
+
+			vol := osc.Volume{
+				VolumeId:      osc.PtrString("vol-test"),
+				LinkedVolumes: &[]osc.LinkedVolume{{State: osc.PtrString("attached")}},
+			}
+			ctx := context.Background()
+			mockOsc.EXPECT().ReadVms(gomock.Eq(ctx), gomock.Any()).Return(newDescribeInstancesOutput("i-test"), nil, nil)
+			mockOsc.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{Volumes: &[]osc.Volume{vol}}, nil, nil).AnyTimes()
+			mockOsc.EXPECT().LinkVolume(gomock.Eq(ctx), gomock.Any()).Return(osc.LinkVolumeResponse{}, nil, tc.linkErr)
+
+			histogram := attachmentDuration.WithLabelValues(tc.operation, tc.result)
+			sumBefore, err := testutil.GetHistogramMetricValue(histogram)
+			if err != nil {
+				t.Fatalf("could not read the metric: %v", err)
+			}
+			countBefore, err := testutil.GetHistogramMetricCount(histogram)
+			if err != nil {
+				t.Fatalf("could not read the metric: %v", err)
+			}
+
+			if _, err := c.AttachDisk(ctx, "vol-test", "i-test", ""); (err != nil) != (tc.linkErr != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sum, _ := testutil.GetHistogramMetricValue(histogram)
+			count, _ := testutil.GetHistogramMetricCount(histogram)
+			if count != countBefore+1 {
+				t.Fatalf("expected 1 observation, got %d", count-countBefore)
+			}
+			if duration := sum - sumBefore; duration != 7 {
+				t.Fatalf("expected a duration of 7s, got %vs", duration)
+			}
+		})
+	}
+}
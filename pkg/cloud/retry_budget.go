@@ -0,0 +1,58 @@
+package cloud
+
+import (
+	"sync"
+	"time"
+)
+
+// retryBudget is a token bucket shared by all the operations of a cloud.
+// Each retry of a throttled request consumes a token, and the bucket is refilled
+// with size tokens per window. When the bucket is empty, the requests fail instead of being retried,
+// so that the driver backs off globally when the API is unhealthy.
+type retryBudget struct {
+	mu     sync.Mutex
+	size   float64
+	window time.Duration
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newRetryBudget returns a budget of size retries per window.
+// A size lower or equal to 0 returns nil, which is an unlimited budget.
+func newRetryBudget(size int, window time.Duration) *retryBudget {
+	if size <= 0 || window <= 0 {
+		return nil
+	}
+	return &retryBudget{
+		size:   float64(size),
+		window: window,
+		tokens: float64(size),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// take consumes a token and returns false when the budget is exhausted.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += b.size * float64(elapsed) / float64(b.window)
+		if b.tokens > b.size {
+			b.tokens = b.size
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
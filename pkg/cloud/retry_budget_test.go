@@ -0,0 +1,79 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	_nethttp "net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	osc "github.com/outscale/osc-sdk-go/v2"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud/mocks"
+)
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	budget := newRetryBudget(2, time.Minute)
+	budget.last = now
+	budget.now = func() time.Time { return now }
+
+	if !budget.take() || !budget.take() {
+		t.Fatal("expected the first retries to be allowed")
+	}
+	if budget.take() {
+		t.Fatal("expected the budget to be exhausted")
+	}
+
+	// Half of the window refills half of the budget
+	now = now.Add(30 * time.Second)
+	if !budget.take() {
+		t.Fatal("expected the budget to be refilled")
+	}
+	if budget.take() {
+		t.Fatal("expected the budget to be exhausted")
+	}
+
+	// The budget never exceeds its size
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if !budget.take() {
+			t.Fatalf("expected retry %d to be allowed", i)
+		}
+	}
+	if budget.take() {
+		t.Fatal("expected the budget to be exhausted")
+	}
+}
+
+func TestRetryBudgetUnlimited(t *testing.T) {
+	budget := newRetryBudget(0, time.Minute)
+	for i := 0; i < 100; i++ {
+		if !budget.take() {
+			t.Fatalf("expected retry %d to be allowed", i)
+		}
+	}
+}
+
+func TestRetryBudgetExhaustedFailsFast(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockOsc := mocks.NewMockOscInterface(mockCtrl)
+	c := newCloud(mockOsc)
+	c.retryBudget = newRetryBudget(1, time.Hour)
+	c.retryBudget.take()
+
+	ctx := context.Background()
+	throttled := &_nethttp.Response{Status: "429 Too Many Requests", StatusCode: 429}
+	mockOsc.EXPECT().ReadVolumes(gomock.Eq(ctx), gomock.Any()).Return(osc.ReadVolumesResponse{}, throttled, fmt.Errorf("throttled")).Times(1)
+
+	start := time.Now()
+	if _, err := c.GetDiskByID(ctx, "vol-test"); err == nil {
+		t.Fatal("GetDiskByID() failed: expected error, got nothing")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("GetDiskByID() failed: expected to fail fast, took %v", elapsed)
+	}
+}
@@ -1,6 +1,30 @@
 package cloud
 
-import osc "github.com/outscale/osc-sdk-go/v2"
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	osc "github.com/outscale/osc-sdk-go/v2"
+)
+
+// MapToInstanceID returns the instance ID of a provider ID such as aws:///eu-west-2a/i-xxxxxxxx,
+// as set on the nodes by the cloud controller manager. Any other node ID is returned as is.
+func MapToInstanceID(nodeID string) (string, error) {
+	if !strings.Contains(nodeID, "://") {
+		return nodeID, nil
+	}
+	u, err := url.Parse(nodeID)
+	if err != nil {
+		return "", fmt.Errorf("invalid provider ID %q: %v", nodeID, err)
+	}
+	tokens := strings.Split(strings.Trim(u.Path, "/"), "/")
+	instanceID := tokens[len(tokens)-1]
+	if !strings.HasPrefix(instanceID, "i-") {
+		return "", fmt.Errorf("invalid provider ID %q: no instance ID found", nodeID)
+	}
+	return instanceID, nil
+}
 
 func extractError(err error) (bool, *osc.ErrorResponse) {
 	genericError, ok := err.(osc.GenericOpenAPIError)
@@ -0,0 +1,51 @@
+package cloud
+
+import "testing"
+
+func TestMapToInstanceID(t *testing.T) {
+	testCases := []struct {
+		name          string
+		nodeID        string
+		expInstanceID string
+		expErr        bool
+	}{
+		{
+			name:          "instance ID",
+			nodeID:        "i-12345678",
+			expInstanceID: "i-12345678",
+		},
+		{
+			name:          "provider ID with zone",
+			nodeID:        "aws:///eu-west-2a/i-12345678",
+			expInstanceID: "i-12345678",
+		},
+		{
+			name:          "provider ID without zone",
+			nodeID:        "aws:////i-12345678",
+			expInstanceID: "i-12345678",
+		},
+		{
+			name:   "provider ID without instance ID",
+			nodeID: "aws:///eu-west-2a/",
+			expErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			instanceID, err := MapToInstanceID(tc.nodeID)
+			if tc.expErr {
+				if err == nil {
+					t.Fatalf("Expected an error for node ID %q, got instance ID %q", tc.nodeID, instanceID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if instanceID != tc.expInstanceID {
+				t.Fatalf("Expected instance ID %q, got %q", tc.expInstanceID, instanceID)
+			}
+		})
+	}
+}
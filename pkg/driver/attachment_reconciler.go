@@ -0,0 +1,149 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// zombieAttachment is a volume attached to a VM without any VolumeAttachment of the driver for this node.
+type zombieAttachment struct {
+	VolumeID string
+	NodeID   string
+}
+
+// attachmentReconciler looks, once at startup, for the volumes of the cluster which are attached to a VM
+// while Kubernetes has no VolumeAttachment for them, e.g. after a detach lost during a controller restart.
+// In dry-run mode, which is the default, the zombie attachments are only logged.
+type attachmentReconciler struct {
+	cloud     cloud.Cloud
+	client    kubernetes.Interface
+	clusterID string
+	dryRun    bool
+}
+
+// newAttachmentReconciler creates a reconciler using the in-cluster Kubernetes configuration.
+func newAttachmentReconciler(c cloud.Cloud, driverOptions *DriverOptions) (*attachmentReconciler, error) {
+	client, err := newInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachmentReconciler{
+		cloud:     c,
+		client:    client,
+		clusterID: driverOptions.clusterID,
+		dryRun:    driverOptions.attachmentReconcilerDryRun,
+	}, nil
+}
+
+// Run reports the zombie attachments, and detaches them unless in dry-run mode.
+func (r *attachmentReconciler) Run(ctx context.Context) {
+	klog.Infof("Starting attachment reconciler (dry-run: %v)", r.dryRun)
+	zombies, err := r.findZombieAttachments(ctx)
+	if err != nil {
+		klog.Errorf("attachmentReconciler: could not look for zombie attachments: %v", err)
+		return
+	}
+
+	for _, zombie := range zombies {
+		if r.dryRun {
+			klog.Warningf("attachmentReconciler: volume %s is attached to node %s without VolumeAttachment, not detached in dry-run mode", zombie.VolumeID, zombie.NodeID)
+			continue
+		}
+		klog.Warningf("attachmentReconciler: detaching volume %s from node %s, attached without VolumeAttachment", zombie.VolumeID, zombie.NodeID)
+		if err := r.cloud.DetachDisk(ctx, zombie.VolumeID, zombie.NodeID, true); err != nil && err != cloud.ErrNotFound {
+			klog.Errorf("attachmentReconciler: could not detach volume %s from node %s: %v", zombie.VolumeID, zombie.NodeID, err)
+		}
+	}
+}
+
+// findZombieAttachments returns the attachments of the volumes of the cluster with no matching VolumeAttachment.
+func (r *attachmentReconciler) findZombieAttachments(ctx context.Context) ([]zombieAttachment, error) {
+	// The Kubernetes objects are listed before the volumes, so that an attachment made in between is always seen with its VolumeAttachment.
+	vas, err := r.client.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pvs, err := r.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	csiNodes, err := r.client.StorageV1().CSINodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := r.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	disks, err := r.cloud.ListDisks(ctx, map[string]string{cloud.ClusterIDTagKey: r.clusterID})
+	if err != nil {
+		return nil, err
+	}
+
+	volumeHandles := make(map[string]string, len(pvs.Items))
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == DriverName {
+			volumeHandles[pv.Name] = pv.Spec.CSI.VolumeHandle
+		}
+	}
+	// The node ID is read from the CSINode, or from the provider ID of the node when the driver
+	// is not registered on it (yet), e.g. while its node plugin is restarting.
+	nodeIDs := make(map[string]string, len(nodes.Items))
+	for _, node := range nodes.Items {
+		if node.Spec.ProviderID == "" {
+			continue
+		}
+		if instanceID, err := cloud.MapToInstanceID(node.Spec.ProviderID); err == nil {
+			nodeIDs[node.Name] = instanceID
+		}
+	}
+	for _, csiNode := range csiNodes.Items {
+		for _, driver := range csiNode.Spec.Drivers {
+			if driver.Name != DriverName {
+				continue
+			}
+			if instanceID, err := cloud.MapToInstanceID(driver.NodeID); err == nil {
+				nodeIDs[csiNode.Name] = instanceID
+			}
+		}
+	}
+
+	expected := make(map[zombieAttachment]bool, len(vas.Items))
+	// unresolved holds the volumes with a VolumeAttachment for a node whose ID is unknown, never reported.
+	unresolved := make(map[string]bool)
+	for _, va := range vas.Items {
+		if va.Spec.Attacher != DriverName || va.Spec.Source.PersistentVolumeName == nil {
+			continue
+		}
+		volumeID, ok := volumeHandles[*va.Spec.Source.PersistentVolumeName]
+		if !ok {
+			continue
+		}
+		nodeID, ok := nodeIDs[va.Spec.NodeName]
+		if !ok {
+			klog.Warningf("attachmentReconciler: could not resolve the ID of node %s, skipping volume %s", va.Spec.NodeName, volumeID)
+			unresolved[volumeID] = true
+			continue
+		}
+		expected[zombieAttachment{VolumeID: volumeID, NodeID: nodeID}] = true
+	}
+
+	var zombies []zombieAttachment
+	for _, disk := range disks {
+		if disk.Tags[cloud.VolumeNameTagKey] == "" || disk.Tags[cloud.ClusterIDTagKey] != r.clusterID || unresolved[disk.VolumeID] {
+			continue
+		}
+		for _, nodeID := range disk.AttachedNodes {
+			attachment := zombieAttachment{VolumeID: disk.VolumeID, NodeID: nodeID}
+			if !expected[attachment] {
+				zombies = append(zombies, attachment)
+			}
+		}
+	}
+	return zombies, nil
+}
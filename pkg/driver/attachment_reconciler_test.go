@@ -0,0 +1,155 @@
+package driver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/mocks"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newVolumeAttachment(name, attacher, pvName, nodeName string) *storagev1.VolumeAttachment {
+	return &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: attacher,
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+			NodeName: nodeName,
+		},
+	}
+}
+
+func newCSINode(nodeName, nodeID string) *storagev1.CSINode {
+	return &storagev1.CSINode{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec: storagev1.CSINodeSpec{
+			Drivers: []storagev1.CSINodeDriver{{Name: DriverName, NodeID: nodeID}},
+		},
+	}
+}
+
+func newNode(nodeName, providerID string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec:       v1.NodeSpec{ProviderID: providerID},
+	}
+}
+
+func TestAttachmentReconcilerFindZombieAttachments(t *testing.T) {
+	newDisk := func(volumeID, clusterID string, attachedNodes ...string) cloud.Disk {
+		return cloud.Disk{
+			VolumeID:      volumeID,
+			AttachedNodes: attachedNodes,
+			Tags: map[string]string{
+				cloud.VolumeNameTagKey: "pvc-" + volumeID,
+				cloud.ClusterIDTagKey:  clusterID,
+			},
+		}
+	}
+	disks := []cloud.Disk{
+		newDisk("vol-attached", "cluster-test", "i-node1"),
+		newDisk("vol-detached", "cluster-test"),
+		newDisk("vol-zombie", "cluster-test", "i-node1"),
+		newDisk("vol-wrong-node", "cluster-test", "i-node2"),
+		newDisk("vol-other-driver", "cluster-test", "i-node1"),
+		newDisk("vol-other-cluster", "cluster-other", "i-node1"),
+		newDisk("vol-provider-id", "cluster-test", "i-node3"),
+		newDisk("vol-unresolved", "cluster-test", "i-node4"),
+	}
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().ListDisks(gomock.Any(), gomock.Eq(map[string]string{cloud.ClusterIDTagKey: "cluster-test"})).Return(disks, nil)
+
+	reconciler := &attachmentReconciler{
+		cloud: mockCloud,
+		client: fake.NewSimpleClientset(
+			newCSIPersistentVolume("pv-attached", DriverName, "vol-attached"),
+			newCSIPersistentVolume("pv-zombie", DriverName, "vol-zombie"),
+			newCSIPersistentVolume("pv-wrong-node", DriverName, "vol-wrong-node"),
+			newCSIPersistentVolume("pv-other-driver", "other.csi.driver", "vol-other-driver"),
+			newVolumeAttachment("va-attached", DriverName, "pv-attached", "node1"),
+			newVolumeAttachment("va-wrong-node", DriverName, "pv-wrong-node", "node1"),
+			newVolumeAttachment("va-other-driver", "other.csi.driver", "pv-other-driver", "node1"),
+			newCSINode("node1", "i-node1"),
+			newCSINode("node2", "i-node2"),
+			newCSIPersistentVolume("pv-provider-id", DriverName, "vol-provider-id"),
+			newVolumeAttachment("va-provider-id", DriverName, "pv-provider-id", "node3"),
+			newNode("node3", "aws:///eu-west-2a/i-node3"),
+			newCSIPersistentVolume("pv-unresolved", DriverName, "vol-unresolved"),
+			newVolumeAttachment("va-unresolved", DriverName, "pv-unresolved", "node4"),
+			newNode("node4", ""),
+		),
+		clusterID: "cluster-test",
+		dryRun:    true,
+	}
+
+	zombies, err := reconciler.findZombieAttachments(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []zombieAttachment{
+		{VolumeID: "vol-zombie", NodeID: "i-node1"},
+		{VolumeID: "vol-wrong-node", NodeID: "i-node2"},
+		{VolumeID: "vol-other-driver", NodeID: "i-node1"},
+	}
+	if !reflect.DeepEqual(zombies, expected) {
+		t.Fatalf("Expected zombie attachments %v, got %v", expected, zombies)
+	}
+}
+
+func TestAttachmentReconcilerDryRun(t *testing.T) {
+	zombie := cloud.Disk{
+		VolumeID:      "vol-zombie",
+		AttachedNodes: []string{"i-node1"},
+		Tags: map[string]string{
+			cloud.VolumeNameTagKey: "pvc-zombie",
+			cloud.ClusterIDTagKey:  "cluster-test",
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		dryRun bool
+	}{
+		{
+			name:   "dry-run does not detach",
+			dryRun: true,
+		},
+		{
+			name:   "detach zombie attachment",
+			dryRun: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().ListDisks(gomock.Any(), gomock.Any()).Return([]cloud.Disk{zombie}, nil)
+			if tc.dryRun {
+				mockCloud.EXPECT().DetachDisk(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			} else {
+				mockCloud.EXPECT().DetachDisk(gomock.Any(), gomock.Eq("vol-zombie"), gomock.Eq("i-node1"), true).Return(nil)
+			}
+
+			reconciler := &attachmentReconciler{
+				cloud:     mockCloud,
+				client:    fake.NewSimpleClientset(),
+				clusterID: "cluster-test",
+				dryRun:    tc.dryRun,
+			}
+			reconciler.Run(context.Background())
+		})
+	}
+}
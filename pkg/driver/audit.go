@@ -0,0 +1,93 @@
+package driver
+
+import (
+	"context"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	klog "k8s.io/klog/v2"
+)
+
+// AuditRecord describes a mutating call on the controller service.
+type AuditRecord struct {
+	// Operation is the name of the CSI RPC.
+	Operation string
+	// Actor is the address of the gRPC client, usually a CSI sidecar.
+	Actor string
+	// Name is the name of the volume or snapshot requested by the CO.
+	Name       string
+	VolumeID   string
+	SnapshotID string
+	NodeID     string
+	// Code is the gRPC code returned to the client.
+	Code    codes.Code
+	Message string
+}
+
+// AuditLogger receives one record per mutating call on the controller service.
+type AuditLogger interface {
+	Audit(record AuditRecord)
+}
+
+// klogAuditLogger writes the audit records as structured klog entries.
+type klogAuditLogger struct{}
+
+func (klogAuditLogger) Audit(record AuditRecord) {
+	klog.InfoS("Audit",
+		"operation", record.Operation,
+		"actor", record.Actor,
+		"name", record.Name,
+		"volumeID", record.VolumeID,
+		"snapshotID", record.SnapshotID,
+		"nodeID", record.NodeID,
+		"code", record.Code.String(),
+		"message", record.Message)
+}
+
+// auditInterceptor returns a gRPC interceptor sending an audit record to logger for each mutating call.
+func auditInterceptor(logger AuditLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if record, ok := newAuditRecord(req, resp); ok {
+			if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+				record.Actor = p.Addr.String()
+			}
+			s := status.Convert(err)
+			record.Code = s.Code()
+			record.Message = s.Message()
+			logger.Audit(record)
+		}
+		return resp, err
+	}
+}
+
+// newAuditRecord returns the audit record of a request, or false if the request is not audited.
+func newAuditRecord(req, resp interface{}) (AuditRecord, bool) {
+	switch r := req.(type) {
+	case *csi.CreateVolumeRequest:
+		record := AuditRecord{Operation: "CreateVolume", Name: r.GetName()}
+		if resp, ok := resp.(*csi.CreateVolumeResponse); ok {
+			record.VolumeID = resp.GetVolume().GetVolumeId()
+		}
+		return record, true
+	case *csi.DeleteVolumeRequest:
+		return AuditRecord{Operation: "DeleteVolume", VolumeID: r.GetVolumeId()}, true
+	case *csi.ControllerPublishVolumeRequest:
+		return AuditRecord{Operation: "ControllerPublishVolume", VolumeID: r.GetVolumeId(), NodeID: r.GetNodeId()}, true
+	case *csi.ControllerUnpublishVolumeRequest:
+		return AuditRecord{Operation: "ControllerUnpublishVolume", VolumeID: r.GetVolumeId(), NodeID: r.GetNodeId()}, true
+	case *csi.CreateSnapshotRequest:
+		record := AuditRecord{Operation: "CreateSnapshot", Name: r.GetName(), VolumeID: r.GetSourceVolumeId()}
+		if resp, ok := resp.(*csi.CreateSnapshotResponse); ok {
+			record.SnapshotID = resp.GetSnapshot().GetSnapshotId()
+		}
+		return record, true
+	case *csi.DeleteSnapshotRequest:
+		return AuditRecord{Operation: "DeleteSnapshot", SnapshotID: r.GetSnapshotId()}, true
+	default:
+		return AuditRecord{}, false
+	}
+}
@@ -0,0 +1,69 @@
+package driver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeAuditLogger struct {
+	records []AuditRecord
+}
+
+func (l *fakeAuditLogger) Audit(record AuditRecord) {
+	l.records = append(l.records, record)
+}
+
+func TestAuditInterceptor(t *testing.T) {
+	testCases := []struct {
+		name       string
+		req        interface{}
+		resp       interface{}
+		err        error
+		expRecords []AuditRecord
+	}{
+		{
+			name: "success CreateVolume",
+			req:  &csi.CreateVolumeRequest{Name: "pvc-test"},
+			resp: &csi.CreateVolumeResponse{Volume: &csi.Volume{VolumeId: "vol-test"}},
+			expRecords: []AuditRecord{
+				{Operation: "CreateVolume", Name: "pvc-test", VolumeID: "vol-test", Code: codes.OK},
+			},
+		},
+		{
+			name: "fail ControllerPublishVolume",
+			req:  &csi.ControllerPublishVolumeRequest{VolumeId: "vol-test", NodeId: "i-test"},
+			err:  status.Error(codes.NotFound, "Volume not found"),
+			expRecords: []AuditRecord{
+				{Operation: "ControllerPublishVolume", VolumeID: "vol-test", NodeID: "i-test", Code: codes.NotFound, Message: "Volume not found"},
+			},
+		},
+		{
+			name: "read-only call is not audited",
+			req:  &csi.ListSnapshotsRequest{},
+			resp: &csi.ListSnapshotsResponse{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &fakeAuditLogger{}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return tc.resp, tc.err
+			}
+
+			_, err := auditInterceptor(logger)(context.Background(), tc.req, &grpc.UnaryServerInfo{}, handler)
+			if err != tc.err {
+				t.Fatalf("Expected error %v, got %v", tc.err, err)
+			}
+			if !reflect.DeepEqual(logger.records, tc.expRecords) {
+				t.Fatalf("Expected records %+v, got %+v", tc.expRecords, logger.records)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"k8s.io/klog/v2"
+)
+
+// CascadeDeleteResult reports the deletion of a volume with its snapshots.
+type CascadeDeleteResult struct {
+	VolumeID string `json:"volumeID"`
+	// DeletedSnapshots are the IDs of the snapshots of the volume which have been deleted.
+	DeletedSnapshots []string `json:"deletedSnapshots"`
+	// FailedSnapshots maps the IDs of the snapshots which could not be deleted to the error.
+	FailedSnapshots map[string]string `json:"failedSnapshots,omitempty"`
+	// VolumeDeleted is false when the volume has been kept, because one of its snapshots is left or its deletion failed.
+	VolumeDeleted bool   `json:"volumeDeleted"`
+	Error         string `json:"error,omitempty"`
+}
+
+// deleteVolumeWithSnapshots deletes all the snapshots of the volume, then the volume. The volume is kept
+// when a snapshot could not be deleted, so that the operation can be retried as a whole.
+// It is never called by DeleteVolume: the snapshots outlive their volume in the CSI model.
+func (d *controllerService) deleteVolumeWithSnapshots(ctx context.Context, volumeID string) CascadeDeleteResult {
+	result := CascadeDeleteResult{VolumeID: volumeID, DeletedSnapshots: []string{}}
+
+	var snapshots []cloud.Snapshot
+	nextToken := ""
+	for {
+		resp, err := d.cloud.ListSnapshots(ctx, volumeID, 0, nextToken)
+		if err == cloud.ErrNotFound {
+			break
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("could not list the snapshots of volume %s: %v", volumeID, err)
+			return result
+		}
+		snapshots = append(snapshots, resp.Snapshots...)
+		if resp.NextToken == "" {
+			break
+		}
+		nextToken = resp.NextToken
+	}
+
+	for _, snapshot := range snapshots {
+		klog.Infof("deleteVolumeWithSnapshots: deleting snapshot %s of volume %s", snapshot.SnapshotID, volumeID)
+		if _, err := d.cloud.DeleteSnapshot(ctx, snapshot.SnapshotID); err != nil && err != cloud.ErrNotFound {
+			if result.FailedSnapshots == nil {
+				result.FailedSnapshots = map[string]string{}
+			}
+			result.FailedSnapshots[snapshot.SnapshotID] = err.Error()
+			continue
+		}
+		result.DeletedSnapshots = append(result.DeletedSnapshots, snapshot.SnapshotID)
+	}
+	if len(result.FailedSnapshots) > 0 {
+		result.Error = fmt.Sprintf("%d snapshots of volume %s could not be deleted, the volume is kept", len(result.FailedSnapshots), volumeID)
+		return result
+	}
+
+	klog.Infof("deleteVolumeWithSnapshots: deleting volume %s", volumeID)
+	if _, err := d.cloud.DeleteDisk(ctx, volumeID); err != nil && err != cloud.ErrNotFound {
+		result.Error = fmt.Sprintf("could not delete volume %s: %v", volumeID, err)
+		return result
+	}
+	result.VolumeDeleted = true
+	return result
+}
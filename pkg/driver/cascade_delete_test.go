@@ -0,0 +1,133 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/mocks"
+)
+
+func TestDeleteVolumeWithSnapshots(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name      string
+		setup     func(mockCloud *mocks.MockCloud)
+		expResult CascadeDeleteResult
+	}{
+		{
+			name: "success with paginated snapshots",
+			setup: func(mockCloud *mocks.MockCloud) {
+				gomock.InOrder(
+					mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq("vol-test"), gomock.Eq(int64(0)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{
+						Snapshots: []cloud.Snapshot{{SnapshotID: "snap-1", SourceVolumeID: "vol-test"}},
+						NextToken: "token",
+					}, nil),
+					mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq("vol-test"), gomock.Eq(int64(0)), gomock.Eq("token")).Return(cloud.ListSnapshotsResponse{
+						Snapshots: []cloud.Snapshot{{SnapshotID: "snap-2", SourceVolumeID: "vol-test"}},
+					}, nil),
+					mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("snap-1")).Return(true, nil),
+					mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("snap-2")).Return(true, nil),
+					mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq("vol-test")).Return(true, nil),
+				)
+			},
+			expResult: CascadeDeleteResult{
+				VolumeID:         "vol-test",
+				DeletedSnapshots: []string{"snap-1", "snap-2"},
+				VolumeDeleted:    true,
+			},
+		},
+		{
+			name: "success without snapshots",
+			setup: func(mockCloud *mocks.MockCloud) {
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq("vol-test"), gomock.Eq(int64(0)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq("vol-test")).Return(true, nil)
+			},
+			expResult: CascadeDeleteResult{
+				VolumeID:         "vol-test",
+				DeletedSnapshots: []string{},
+				VolumeDeleted:    true,
+			},
+		},
+		{
+			name: "fail snapshot deletion keeps the volume",
+			setup: func(mockCloud *mocks.MockCloud) {
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq("vol-test"), gomock.Eq(int64(0)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{
+					Snapshots: []cloud.Snapshot{{SnapshotID: "snap-1"}, {SnapshotID: "snap-2"}},
+				}, nil)
+				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("snap-1")).Return(false, errors.New("snapshot in use"))
+				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("snap-2")).Return(true, nil)
+			},
+			expResult: CascadeDeleteResult{
+				VolumeID:         "vol-test",
+				DeletedSnapshots: []string{"snap-2"},
+				FailedSnapshots:  map[string]string{"snap-1": "snapshot in use"},
+				Error:            "1 snapshots of volume vol-test could not be deleted, the volume is kept",
+			},
+		},
+		{
+			name: "fail listing snapshots",
+			setup: func(mockCloud *mocks.MockCloud) {
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq("vol-test"), gomock.Eq(int64(0)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{}, errors.New("internal error"))
+			},
+			expResult: CascadeDeleteResult{
+				VolumeID:         "vol-test",
+				DeletedSnapshots: []string{},
+				Error:            "could not list the snapshots of volume vol-test: internal error",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			tc.setup(mockCloud)
+
+			d := &controllerService{cloud: mockCloud, driverOptions: &DriverOptions{}}
+			result := d.deleteVolumeWithSnapshots(ctx, "vol-test")
+			if !reflect.DeepEqual(result, tc.expResult) {
+				t.Fatalf("Expected result %+v, got %+v", tc.expResult, result)
+			}
+		})
+	}
+}
+
+func TestCascadeDeleteHandler(t *testing.T) {
+	// The route is not served on the debug endpoint
+	d := &Driver{options: &DriverOptions{cascadeDeleteEndpoint: "localhost:8091"}}
+	rec := httptest.NewRecorder()
+	d.newDebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/debug/volumes/vol-test", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().ListSnapshots(gomock.Any(), gomock.Eq("vol-test"), gomock.Eq(int64(0)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{}, cloud.ErrNotFound)
+	mockCloud.EXPECT().DeleteDisk(gomock.Any(), gomock.Eq("vol-test")).Return(true, nil)
+
+	d = &Driver{
+		controllerService: controllerService{cloud: mockCloud},
+		options:           &DriverOptions{cascadeDeleteEndpoint: "localhost:8091"},
+	}
+	rec = httptest.NewRecorder()
+	d.newCascadeDeleteHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/volumes/vol-test", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	expected := `{"volumeID":"vol-test","deletedSnapshots":[],"volumeDeleted":true}` + "\n"
+	if body := rec.Body.String(); body != expected {
+		t.Fatalf("Expected body %q, got %q", expected, body)
+	}
+}
@@ -16,11 +16,25 @@ limitations under the License.
 
 package driver
 
+import "time"
+
 // constants of keys in PublishContext
 const (
 	// devicePathKey represents key for device path in PublishContext
 	// devicePath is the device path where the volume is attached to
 	DevicePathKey = "devicePath"
+
+	// MountProfileOptionsKey represents key for the comma separated mount flags of the mount profile
+	MountProfileOptionsKey = "mountProfileOptions"
+
+	// VolumeSizeKey represents key for the size in bytes of the volume, checked against the size of the device
+	VolumeSizeKey = "volumeSize"
+)
+
+// constants of keys in VolumeContext
+const (
+	// DeviceNameKey represents key for the device name (e.g. "/dev/xvdb") the volume must be attached to
+	DeviceNameKey = "deviceName"
 )
 
 // constants of keys in volume parameters
@@ -48,9 +62,126 @@ const (
 
 	// LuksPassphraseKey represents the passphrase LUKS
 	LuksPassphraseKey = "key"
+
+	// MountProfileKey represents key for the name of the mount profile applied when staging the volume
+	MountProfileKey = "mount-profile"
+
+	// RepairOnMountKey represents key for whether the filesystem is checked and repaired before mount
+	RepairOnMountKey = "repair-on-mount"
+
+	// JournalModeKey represents key for the journaling mode of ext filesystems, applied as the data= mount flag
+	JournalModeKey = "journal-mode"
+
+	// PreallocateKey represents key for whether the ext filesystem is formatted without discard nor lazy initialization,
+	// so that the blocks of the volume are written once at format time
+	PreallocateKey = "preallocate"
+
+	// AttachRetriesKey represents key for the number of retries of the attachment of the volume,
+	// overriding the --oapi-max-retries of the controller
+	AttachRetriesKey = "attach-retries"
+
+	// PerformanceClassKey represents key for the name of the performance class setting the type and the IOPS
+	// of the volume, among the classes of the --performance-classes-file of the controller
+	PerformanceClassKey = "performance-class"
+
+	// AllowedNodesKey represents key for the comma-separated list of the instance IDs to which the volume may be attached
+	AllowedNodesKey = "allowed-nodes"
+)
+
+// constants of keys in snapshot parameters
+const (
+	// SnapshotTagKeyPrefix is the prefix of the VolumeSnapshotClass parameters converted into snapshot tags
+	// (e.g. "tag/team: storage" adds the tag "team=storage")
+	SnapshotTagKeyPrefix = "tag/"
+
+	// VolumeSnapshotNameKey, VolumeSnapshotNamespaceKey and VolumeSnapshotContentNameKey are the keys
+	// of the parameters added by the external-snapshotter with --extra-create-metadata
+	VolumeSnapshotNameKey        = "csi.storage.k8s.io/volumesnapshot/name"
+	VolumeSnapshotNamespaceKey   = "csi.storage.k8s.io/volumesnapshot/namespace"
+	VolumeSnapshotContentNameKey = "csi.storage.k8s.io/volumesnapshotcontent/name"
+)
+
+// constants of tag keys set from the well-known snapshot parameters
+const (
+	VolumeSnapshotNameTagKey        = "kubernetes.io/created-for/volumesnapshot/name"
+	VolumeSnapshotNamespaceTagKey   = "kubernetes.io/created-for/volumesnapshot/namespace"
+	VolumeSnapshotContentNameTagKey = "kubernetes.io/created-for/volumesnapshotcontent/name"
+)
+
+// InitialNodeTagKey is the key of the tag recording the node which a volume was created for.
+const InitialNodeTagKey = DriverName + "/initial-node"
+
+// GroupSnapshotIDTagKey is the key of the tag grouping the snapshots created by CreateVolumeGroupSnapshot.
+const GroupSnapshotIDTagKey = "CSIVolumeGroupSnapshotID"
+
+// constants of tag keys recording the LUKS context of encrypted volumes. They are copied to the snapshots
+// of these volumes, so that the volumes restored from them are encrypted as well.
+const (
+	EncryptedTagKey   = "CSIEncrypted"
+	LuksCipherTagKey  = "CSILuksCipher"
+	LuksHashTagKey    = "CSILuksHash"
+	LuksKeySizeTagKey = "CSILuksKeySize"
+)
+
+// FSGroupPolicy values, see https://kubernetes-csi.github.io/docs/support-fsgroup.html#supported-modes.
+// They define when the node applies the fsGroup of the pod to the staged volumes.
+const (
+	// FSGroupPolicyReadWriteOnceWithFSType applies the fsGroup to ReadWriteOnce volumes with an fsType
+	FSGroupPolicyReadWriteOnceWithFSType = "ReadWriteOnceWithFSType"
+	// FSGroupPolicyFile always applies the fsGroup
+	FSGroupPolicyFile = "File"
+	// FSGroupPolicyNone never applies the fsGroup
+	FSGroupPolicyNone = "None"
+)
+
+// constants of the journaling modes accepted by JournalModeKey
+const (
+	// JournalModeJournal writes the data to the journal before the filesystem
+	JournalModeJournal = "journal"
+	// JournalModeOrdered writes the data to the filesystem before committing the metadata to the journal
+	JournalModeOrdered = "ordered"
+	// JournalModeWriteback only journals the metadata
+	JournalModeWriteback = "writeback"
 )
 
 // constants for default command line flag values
 const (
 	DefaultCSIEndpoint = "unix://tmp/csi.sock"
+
+	// DefaultShutdownDrainTimeout is the maximum time to wait for the calls in progress on SIGTERM,
+	// under the default termination grace period of the pods
+	DefaultShutdownDrainTimeout = 25 * time.Second
+
+	// DefaultDevicePathPollInterval is the interval between two lookups of the device path
+	DefaultDevicePathPollInterval = 1 * time.Second
+
+	// DefaultDevicePathTimeout is how long NodeStageVolume waits for the device path to show up
+	DefaultDevicePathTimeout = 10 * time.Second
+
+	// DefaultLuksOpenRetries is how many times NodeStageVolume retries to open a busy LUKS device
+	DefaultLuksOpenRetries = 3
+
+	// DefaultLuksOpenRetryDelay is the delay between two attempts to open a busy LUKS device
+	DefaultLuksOpenRetryDelay = 1 * time.Second
+
+	// DefaultSecretProviderTimeout is how long NodeStageVolume waits for the external secret provider
+	DefaultSecretProviderTimeout = 10 * time.Second
+
+	// DefaultSnapshotPendingTimeout is how long a snapshot may stay pending before CreateSnapshot aborts
+	DefaultSnapshotPendingTimeout = 12 * time.Hour
+
+	// DefaultSnapshotScheduleInterval is the interval between two passes of the snapshot scheduler
+	DefaultSnapshotScheduleInterval = 5 * time.Minute
+
+	// DefaultRetryBudgetWindow is the window during which the retry budget is refilled
+	DefaultRetryBudgetWindow = 1 * time.Minute
+
+	// DefaultSnapshotScheduleRetention is the number of scheduled snapshots kept per volume
+	DefaultSnapshotScheduleRetention = 7
+
+	// DefaultVolumeReaperInterval is the interval between two passes of the volume reaper
+	DefaultVolumeReaperInterval = 1 * time.Hour
+
+	// DefaultVolumeReaperGracePeriod is the age under which a volume is never reaped
+	DefaultVolumeReaperGracePeriod = 24 * time.Hour
 )
@@ -18,17 +18,27 @@ package driver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/internal"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
 
@@ -54,8 +64,18 @@ var (
 
 // controllerService represents the controller service of CSI driver
 type controllerService struct {
-	cloud         cloud.Cloud
-	driverOptions *DriverOptions
+	cloud             cloud.Cloud
+	driverOptions     *DriverOptions
+	snapshotScheduler *snapshotScheduler
+	volumeReaper      *volumeReaper
+	// attachmentReconciler is nil unless enabled.
+	attachmentReconciler *attachmentReconciler
+	mountProfiles        mountProfiles
+	perfClasses          performanceClasses
+	// attachLimiter limits the concurrent attaches per node ID, it is nil without limit.
+	attachLimiter *internal.KeyedLimiter
+	// csiNodeInformer caches the CSINodes to read the volume limits reported by the nodes, it is nil out of a cluster.
+	csiNodeInformer cache.SharedIndexInformer
 }
 
 var (
@@ -79,14 +99,75 @@ func newControllerService(driverOptions *DriverOptions) controllerService {
 		region = metadata.GetRegion()
 	}
 
-	cloud, err := NewCloudFunc(region)
+	cloud, err := NewCloudFunc(region,
+		cloud.WithRetryBudget(driverOptions.retryBudget, DefaultRetryBudgetWindow),
+		cloud.WithRequestTimeout(driverOptions.oapiTimeout),
+		cloud.WithMaxRetries(driverOptions.oapiMaxRetries),
+		cloud.WithDetachSettleDuration(driverOptions.detachSettleDuration),
+		cloud.WithDetachBusyRetries(driverOptions.detachBusyRetries),
+		cloud.WithClusterID(driverOptions.clusterID),
+		cloud.WithProfile(driverOptions.oscConfigFile, driverOptions.oscProfile),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	profiles, err := loadMountProfiles(driverOptions.mountProfilesFile)
 	if err != nil {
 		panic(err)
 	}
 
+	perfClasses, err := loadPerformanceClasses(driverOptions.performanceClassesFile)
+	if err != nil {
+		panic(err)
+	}
+
+	var scheduler *snapshotScheduler
+	if driverOptions.enableSnapshotScheduler {
+		scheduler, err = newSnapshotScheduler(cloud, driverOptions)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var reaper *volumeReaper
+	if driverOptions.enableVolumeReaper {
+		reaper, err = newVolumeReaper(cloud, driverOptions)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var reconciler *attachmentReconciler
+	if driverOptions.enableAttachmentReconciler {
+		reconciler, err = newAttachmentReconciler(cloud, driverOptions)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var attachLimiter *internal.KeyedLimiter
+	if driverOptions.maxAttachesPerNode > 0 {
+		attachLimiter = internal.NewKeyedLimiter(driverOptions.maxAttachesPerNode)
+	}
+
+	var csiNodeInformer cache.SharedIndexInformer
+	if client, err := newInClusterClient(); err != nil {
+		klog.Warningf("The volume limit of the nodes is read from MAX_BSU_VOLUMES: %v", err)
+	} else {
+		csiNodeInformer = newCSINodeInformer(client)
+	}
+
 	return controllerService{
-		cloud:         cloud,
-		driverOptions: driverOptions,
+		cloud:                cloud,
+		driverOptions:        driverOptions,
+		snapshotScheduler:    scheduler,
+		volumeReaper:         reaper,
+		attachmentReconciler: reconciler,
+		mountProfiles:        profiles,
+		perfClasses:          perfClasses,
+		attachLimiter:        attachLimiter,
+		csiNodeInformer:      csiNodeInformer,
 	}
 }
 
@@ -101,6 +182,9 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 	if err != nil {
 		return nil, err
 	}
+	if maxSize := d.driverOptions.maxVolumeSize; maxSize > 0 && volSizeBytes > maxSize {
+		return nil, status.Errorf(codes.OutOfRange, "Volume size %d exceeds the maximum volume size %d", volSizeBytes, maxSize)
+	}
 
 	volCaps := req.GetVolumeCapabilities()
 	if len(volCaps) == 0 {
@@ -111,7 +195,9 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		return nil, status.Error(codes.InvalidArgument, "Volume capabilities not supported")
 	}
 
-	disk, err := d.cloud.GetDiskByName(ctx, volName, volSizeBytes)
+	// The volumes are looked up by their name tag, which carries the prefix
+	volNameTag := d.driverOptions.volumeNamePrefix + volName
+	disk, err := d.cloud.GetDiskByName(ctx, volNameTag, volSizeBytes)
 	if err != nil {
 		switch err {
 		case cloud.ErrNotFound:
@@ -132,10 +218,17 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		luksCipher         string
 		luksHash           string
 		luksKeySize        string
+		repairOnMount      bool
+		mountProfile       string
+		journalMode        string
+		preallocate        bool
+		attachRetries      string
+		allowedNodes       string
+		perfClass          *performanceClass
 		volumeContextExtra map[string]string
 	)
 
-	for key, value := range req.GetParameters() {
+	for key, value := range d.volumeParameters(req.GetParameters()) {
 		switch strings.ToLower(key) {
 		case "fstype":
 			klog.Warning("\"fstype\" is deprecated, please use \"csi.storage.k8s.io/fstype\" instead")
@@ -160,11 +253,59 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 			luksKeySize = value
 		case LuksHashKey:
 			luksHash = value
+		case RepairOnMountKey:
+			repairOnMount = value == "true"
+		case MountProfileKey:
+			if _, err := d.mountProfiles.resolve(value); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter %s: %v", MountProfileKey, err)
+			}
+			mountProfile = value
+		case JournalModeKey:
+			err := validateMountFsTypes(volCaps, func(fsType string) error {
+				return validateJournalMode(value, fsType)
+			})
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter %s: %v", JournalModeKey, err)
+			}
+			journalMode = value
+		case PreallocateKey:
+			preallocate = value == "true"
+			if preallocate {
+				if err := validateMountFsTypes(volCaps, validatePreallocate); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter %s: %v", PreallocateKey, err)
+				}
+			}
+		case AttachRetriesKey:
+			if _, err := parseAttachRetries(value); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter %s: %v", AttachRetriesKey, err)
+			}
+			attachRetries = value
+		case AllowedNodesKey:
+			if _, err := parseAllowedNodes(value); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter %s: %v", AllowedNodesKey, err)
+			}
+			allowedNodes = value
+		case PerformanceClassKey:
+			class, err := d.perfClasses.resolve(value)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter %s: %v", PerformanceClassKey, err)
+			}
+			perfClass = &class
 		default:
 			return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter key %s for CreateVolume", key)
 		}
 	}
 
+	// The performance class replaces the type and the IOPS of the default volume parameters,
+	// it is ambiguous along with the ones of the StorageClass
+	if perfClass != nil {
+		if hasParameter(req.GetParameters(), VolumeTypeKey) || hasParameter(req.GetParameters(), IopsPerGBKey) {
+			return nil, status.Errorf(codes.InvalidArgument, "Parameter %s cannot be combined with %s nor %s", PerformanceClassKey, VolumeTypeKey, IopsPerGBKey)
+		}
+		volumeType = perfClass.VolumeType
+		iopsPerGB = perfClass.IopsPerGB
+	}
+
 	// Check for encryption parameters
 	if isEncrypted {
 		volumeContextExtra = map[string]string{
@@ -176,6 +317,24 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 	} else {
 		volumeContextExtra = map[string]string{}
 	}
+	if repairOnMount {
+		volumeContextExtra[RepairOnMountKey] = "true"
+	}
+	if mountProfile != "" {
+		volumeContextExtra[MountProfileKey] = mountProfile
+	}
+	if journalMode != "" {
+		volumeContextExtra[JournalModeKey] = journalMode
+	}
+	if preallocate {
+		volumeContextExtra[PreallocateKey] = "true"
+	}
+	if attachRetries != "" {
+		volumeContextExtra[AttachRetriesKey] = attachRetries
+	}
+	if allowedNodes != "" {
+		volumeContextExtra[AllowedNodesKey] = allowedNodes
+	}
 
 	snapshotID := ""
 	volumeSource := req.GetVolumeContentSource()
@@ -190,6 +349,39 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		snapshotID = sourceSnapshot.GetSnapshotId()
 	}
 
+	// The snapshot is only looked up when the volume must be created: an existing volume
+	// already carries the LUKS context of its snapshot in its tags
+	sourceTags := disk.Tags
+	if snapshotID != "" && cloud.IsNilDisk(disk) {
+		snapshot, err := d.cloud.GetSnapshotByID(ctx, snapshotID)
+		if err != nil {
+			if err == cloud.ErrNotFound {
+				return nil, status.Errorf(codes.NotFound, "Snapshot %s not found", snapshotID)
+			}
+			return nil, status.Errorf(codes.Internal, "Could not get snapshot %s: %v", snapshotID, err)
+		}
+		// a volume restored from a snapshot which is not completed yet may be rejected or incomplete,
+		// let the provisioner retry
+		switch snapshot.State {
+		case "pending", "in-queue":
+			return nil, status.Errorf(codes.Aborted, "Snapshot %s is still %s", snapshotID, snapshot.State)
+		}
+		sourceTags = snapshot.Tags
+	}
+	// volumes restored from a snapshot of an encrypted volume inherit its LUKS context
+	if snapshotID != "" && !isEncrypted {
+		if snapshotContext := luksContext(sourceTags); snapshotContext != nil {
+			isEncrypted = true
+			for k, v := range snapshotContext {
+				volumeContextExtra[k] = v
+			}
+		}
+	}
+
+	if d.driverOptions.requireEncryption && !isEncrypted {
+		return nil, status.Errorf(codes.InvalidArgument, "Encryption is required, the parameter %s must be true", EncryptedKey)
+	}
+
 	// volume exists already
 	if !cloud.IsNilDisk(disk) {
 		if disk.SnapshotID != snapshotID {
@@ -201,12 +393,29 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 	// create a new volume
 	zone := pickAvailabilityZone(req.GetAccessibilityRequirements())
 
+	// The type of the StorageClass or of its performance class takes precedence over the default type of the zone,
+	// which takes precedence over the default volume parameters
+	if zoneType, ok := d.driverOptions.zoneVolumeTypes[zone]; ok && !hasParameter(req.GetParameters(), VolumeTypeKey) && perfClass == nil {
+		volumeType = zoneType
+	}
+
 	volumeTags := map[string]string{
-		cloud.VolumeNameTagKey: volName,
+		cloud.VolumeNameTagKey: volNameTag,
 	}
 	for k, v := range d.driverOptions.extraVolumeTags {
 		volumeTags[k] = v
 	}
+	if isEncrypted {
+		for k, v := range luksTags(volumeContextExtra) {
+			volumeTags[k] = v
+		}
+	}
+	if d.driverOptions.clusterID != "" {
+		volumeTags[cloud.ClusterIDTagKey] = d.driverOptions.clusterID
+	}
+	if node := pickInitialNode(req.GetAccessibilityRequirements()); node != "" {
+		volumeTags[InitialNodeTagKey] = node
+	}
 
 	opts := &cloud.DiskOptions{
 		CapacityBytes:    volSizeBytes,
@@ -221,15 +430,70 @@ func (d *controllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 
 	disk, err = d.cloud.CreateDisk(ctx, volName, opts)
 	if err != nil {
+		if ctx.Err() != nil {
+			// The volume, if created, is found by its name tag when the CO retries the call
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
 		errCode := codes.Internal
 		if err == cloud.ErrNotFound {
 			errCode = codes.NotFound
 		}
+		if errors.Is(err, cloud.ErrQuotaExceeded) {
+			return nil, d.quotaExceededError(volName, err)
+		}
 		return nil, status.Errorf(errCode, "Could not create volume %q: %v", volName, err)
 	}
 	return newCreateVolumeResponse(disk, volumeContextExtra), nil
 }
 
+// hasParameter returns true when the parameters contain key, compared case-insensitively.
+func hasParameter(parameters map[string]string, key string) bool {
+	for k := range parameters {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// volumeParameters merges the default volume parameters of the driver options under the parameters of a
+// CreateVolume request. The keys are compared case-insensitively, a request parameter overriding the default
+// parameter with the same key.
+func (d *controllerService) volumeParameters(parameters map[string]string) map[string]string {
+	if len(d.driverOptions.defaultVolumeParams) == 0 {
+		return parameters
+	}
+	requested := make(map[string]bool, len(parameters))
+	merged := make(map[string]string, len(parameters)+len(d.driverOptions.defaultVolumeParams))
+	for k, v := range parameters {
+		requested[strings.ToLower(k)] = true
+		merged[k] = v
+	}
+	for k, v := range d.driverOptions.defaultVolumeParams {
+		if !requested[strings.ToLower(k)] {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// quotaExceededError returns a ResourceExhausted error, with the suggested delay before retrying
+// the creation of the volume when the quota retry interval is set.
+func (d *controllerService) quotaExceededError(volName string, err error) error {
+	st := status.Newf(codes.ResourceExhausted, "Could not create volume %q: %v", volName, err)
+	if d.driverOptions.quotaRetryInterval <= 0 {
+		return st.Err()
+	}
+	withDetails, detailsErr := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(d.driverOptions.quotaRetryInterval),
+	})
+	if detailsErr != nil {
+		klog.Warningf("Could not add the retry delay to the error: %v", detailsErr)
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
 func (d *controllerService) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
 	klog.V(4).Infof("DeleteVolume: called with args: %+v", *req)
 	volumeID := req.GetVolumeId()
@@ -255,9 +519,9 @@ func (d *controllerService) ControllerPublishVolume(ctx context.Context, req *cs
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
 	}
 
-	nodeID := req.GetNodeId()
-	if len(nodeID) == 0 {
-		return nil, status.Error(codes.InvalidArgument, "Node ID not provided")
+	nodeID, err := instanceIDFromNodeID(req.GetNodeId())
+	if err != nil {
+		return nil, err
 	}
 
 	volCap := req.GetVolumeCapability()
@@ -270,34 +534,149 @@ func (d *controllerService) ControllerPublishVolume(ctx context.Context, req *cs
 		return nil, status.Error(codes.InvalidArgument, "Volume capability not supported")
 	}
 
-	if !d.cloud.IsExistInstance(ctx, nodeID) {
-		return nil, status.Errorf(codes.NotFound, "Instance %q not found", nodeID)
+	if value, ok := req.GetVolumeContext()[AllowedNodesKey]; ok {
+		allowed, err := parseAllowedNodes(value)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid volume context %s: %v", AllowedNodesKey, err)
+		}
+		if !slices.Contains(allowed, nodeID) {
+			return nil, status.Errorf(codes.FailedPrecondition, "Volume %s may not be attached to node %q, allowed nodes: %s", volumeID, nodeID, value)
+		}
+	}
+
+	// A single read of the VM checks that it exists and returns the volumes attached to it.
+	attached, err := d.cloud.GetAttachedDisks(ctx, nodeID)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "Instance %q not found", nodeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get the volumes attached to node %q: %v", nodeID, err)
 	}
 
-	if _, err := d.cloud.GetDiskByID(ctx, volumeID); err != nil {
+	var mountProfileOptions string
+	if profile, ok := req.GetVolumeContext()[MountProfileKey]; ok {
+		options, err := d.mountProfiles.resolve(profile)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		mountProfileOptions = options
+	}
+
+	disk, err := d.cloud.GetDiskByID(ctx, volumeID)
+	if err != nil {
 		if err == cloud.ErrNotFound {
 			return nil, status.Error(codes.NotFound, "Volume not found")
 		}
 		return nil, status.Errorf(codes.Internal, "Could not get volume with ID %q: %v", volumeID, err)
 	}
 
-	devicePath, err := d.cloud.AttachDisk(ctx, volumeID, nodeID)
+	if limit := d.nodeVolumesLimit(req.GetNodeId()); int64(len(attached)) >= limit && !slices.Contains(attached, volumeID) {
+		return nil, status.Errorf(codes.ResourceExhausted, "Node %q has reached its limit of %d attached volumes", nodeID, limit)
+	}
+
+	if d.attachLimiter != nil {
+		if err := d.attachLimiter.Acquire(ctx, nodeID); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		defer d.attachLimiter.Release(nodeID)
+	}
+
+	if value, ok := req.GetVolumeContext()[AttachRetriesKey]; ok {
+		retries, err := parseAttachRetries(value)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid %s: %v", AttachRetriesKey, err)
+		}
+		ctx = cloud.WithAttachRetries(ctx, retries)
+	}
+
+	deviceName := req.GetVolumeContext()[DeviceNameKey]
+	devicePath, err := d.cloud.AttachDisk(ctx, volumeID, nodeID, deviceName)
 	if err != nil {
 		if err == cloud.ErrAlreadyExists {
 			return nil, status.Error(codes.AlreadyExists, err.Error())
 		}
+		if errors.Is(err, cloud.ErrZoneMismatch) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		if errors.Is(err, cloud.ErrDeviceNameInUse) {
+			return nil, status.Errorf(codes.FailedPrecondition, "Could not attach volume %q to node %q with device %q: %v", volumeID, nodeID, deviceName, err)
+		}
 		return nil, status.Errorf(codes.Internal, "Could not attach volume %q to node %q: %v", volumeID, nodeID, err)
 	}
 	klog.V(5).Infof("ControllerPublishVolume: volume %s attached to node %s through device %s", volumeID, nodeID, devicePath)
 
+	// The volume is bound to a PV by now: mark it so that the volume reaper leaves it alone once the PV is deleted.
+	if disk.Tags[cloud.ClusterIDTagKey] != "" && disk.Tags[cloud.BoundTagKey] == "" {
+		if err := d.cloud.TagDisk(ctx, volumeID, map[string]string{cloud.BoundTagKey: "true"}); err != nil {
+			klog.Warningf("ControllerPublishVolume: could not mark volume %s as bound: %v", volumeID, err)
+		}
+	}
+
 	volumeContext := req.GetVolumeContext()
 	if volumeContext == nil {
 		volumeContext = map[string]string{}
 	}
 	volumeContext[DevicePathKey] = devicePath
+	if mountProfileOptions != "" {
+		volumeContext[MountProfileOptionsKey] = mountProfileOptions
+	}
+	if disk.CapacityGiB > 0 {
+		volumeContext[VolumeSizeKey] = strconv.FormatInt(util.GiBToBytes(disk.CapacityGiB), 10)
+	}
 	return &csi.ControllerPublishVolumeResponse{PublishContext: volumeContext}, nil
 }
 
+// csiNodeIDIndex is the name of the index of the CSINodes by the node ID registered by the driver.
+const csiNodeIDIndex = "nodeID"
+
+// newCSINodeInformer returns an informer of the CSINodes, indexed by the node ID registered by the driver.
+func newCSINodeInformer(client kubernetes.Interface) cache.SharedIndexInformer {
+	informer := informers.NewSharedInformerFactory(client, 0).Storage().V1().CSINodes().Informer()
+	err := informer.AddIndexers(cache.Indexers{csiNodeIDIndex: func(obj interface{}) ([]string, error) {
+		csiNode, ok := obj.(*storagev1.CSINode)
+		if !ok {
+			return nil, nil
+		}
+		var nodeIDs []string
+		for _, driver := range csiNode.Spec.Drivers {
+			if driver.Name == DriverName {
+				nodeIDs = append(nodeIDs, driver.NodeID)
+			}
+		}
+		return nodeIDs, nil
+	}})
+	if err != nil {
+		panic(err)
+	}
+	return informer
+}
+
+// nodeVolumesLimit returns the volume limit reported by the node nodeID in its CSINode,
+// or the limit of MAX_BSU_VOLUMES when the CSINode cannot be read.
+func (d *controllerService) nodeVolumesLimit(nodeID string) int64 {
+	if d.csiNodeInformer == nil {
+		return getVolumesLimit()
+	}
+	if !d.csiNodeInformer.HasSynced() {
+		klog.Warningf("The CSINodes are not synced yet, using the volume limit of MAX_BSU_VOLUMES for node %s", nodeID)
+		return getVolumesLimit()
+	}
+	csiNodes, err := d.csiNodeInformer.GetIndexer().ByIndex(csiNodeIDIndex, nodeID)
+	if err != nil {
+		klog.Warningf("Could not look up the CSINode, using the volume limit of MAX_BSU_VOLUMES for node %s: %v", nodeID, err)
+		return getVolumesLimit()
+	}
+	for _, obj := range csiNodes {
+		csiNode := obj.(*storagev1.CSINode)
+		for _, driver := range csiNode.Spec.Drivers {
+			if driver.Name == DriverName && driver.NodeID == nodeID && driver.Allocatable != nil && driver.Allocatable.Count != nil {
+				return int64(*driver.Allocatable.Count)
+			}
+		}
+	}
+	return getVolumesLimit()
+}
+
 func (d *controllerService) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
 	klog.V(4).Infof("ControllerUnpublishVolume: called with args %+v", *req)
 	volumeID := req.GetVolumeId()
@@ -305,12 +684,24 @@ func (d *controllerService) ControllerUnpublishVolume(ctx context.Context, req *
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
 	}
 
-	nodeID := req.GetNodeId()
-	if len(nodeID) == 0 {
-		return nil, status.Error(codes.InvalidArgument, "Node ID not provided")
+	nodeID, err := instanceIDFromNodeID(req.GetNodeId())
+	if err != nil {
+		return nil, err
+	}
+
+	// a stopped VM keeps its volumes linked, they are unlinked without waiting for the detachment
+	waitDetached := true
+	if d.driverOptions.skipDetachStoppedNodes {
+		stopped, err := d.cloud.IsInstanceStopped(ctx, nodeID)
+		if err != nil {
+			klog.Warningf("ControllerUnpublishVolume: could not get the state of node %s, waiting for the detachment: %v", nodeID, err)
+		} else if stopped {
+			klog.V(5).Infof("ControllerUnpublishVolume: node %s is stopped, skip the wait for the detachment of volume %s", nodeID, volumeID)
+			waitDetached = false
+		}
 	}
 
-	if err := d.cloud.DetachDisk(ctx, volumeID, nodeID); err != nil {
+	if err := d.cloud.DetachDisk(ctx, volumeID, nodeID, waitDetached); err != nil {
 		if err == cloud.ErrNotFound {
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
@@ -325,18 +716,32 @@ func (d *controllerService) ControllerGetCapabilities(ctx context.Context, req *
 	klog.V(4).Infof("ControllerGetCapabilities: called with args %+v", *req)
 	var caps []*csi.ControllerServiceCapability
 	for _, cap := range controllerCaps {
-		c := &csi.ControllerServiceCapability{
-			Type: &csi.ControllerServiceCapability_Rpc{
-				Rpc: &csi.ControllerServiceCapability_RPC{
-					Type: cap,
-				},
-			},
+		if d.driverOptions.disableSnapshots && isSnapshotCapability(cap) {
+			continue
 		}
-		caps = append(caps, c)
+		caps = append(caps, newControllerServiceCapability(cap))
+	}
+	// The external-provisioner only sends clone requests to drivers advertising CLONE_VOLUME
+	if d.driverOptions.enableVolumeCloning {
+		caps = append(caps, newControllerServiceCapability(csi.ControllerServiceCapability_RPC_CLONE_VOLUME))
 	}
 	return &csi.ControllerGetCapabilitiesResponse{Capabilities: caps}, nil
 }
 
+func newControllerServiceCapability(cap csi.ControllerServiceCapability_RPC_Type) *csi.ControllerServiceCapability {
+	return &csi.ControllerServiceCapability{
+		Type: &csi.ControllerServiceCapability_Rpc{
+			Rpc: &csi.ControllerServiceCapability_RPC{
+				Type: cap,
+			},
+		},
+	}
+}
+
+func isSnapshotCapability(cap csi.ControllerServiceCapability_RPC_Type) bool {
+	return cap == csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT || cap == csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS
+}
+
 func (d *controllerService) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
 	klog.V(4).Infof("GetCapacity: called with args %+v", *req)
 	return nil, status.Error(codes.Unimplemented, "")
@@ -394,6 +799,23 @@ func (d *controllerService) ControllerExpandVolume(ctx context.Context, req *csi
 		return nil, status.Error(codes.InvalidArgument, "After round-up, volume size exceeds the limit specified")
 	}
 
+	disk, err := d.cloud.GetDiskByID(ctx, volumeID)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, status.Error(codes.NotFound, "Volume not found")
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get volume with ID %q: %v", volumeID, err)
+	}
+	if disk.CapacityGiB == util.RoundUpGiB(newSize) {
+		// The volume may have been resized by a previous call which timed out before the filesystem was expanded,
+		// so the node expansion is still required for the volumes mounted as filesystems.
+		klog.V(4).Infof("ControllerExpandVolume: volume %q already has the requested size of %d GiB", volumeID, disk.CapacityGiB)
+		return &csi.ControllerExpandVolumeResponse{
+			CapacityBytes:         util.GiBToBytes(disk.CapacityGiB),
+			NodeExpansionRequired: req.GetVolumeCapability().GetBlock() == nil,
+		}, nil
+	}
+
 	actualSizeGiB, err := d.cloud.ResizeDisk(ctx, volumeID, newSize)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Could not resize volume %q: %v", volumeID, err)
@@ -405,6 +827,43 @@ func (d *controllerService) ControllerExpandVolume(ctx context.Context, req *csi
 	}, nil
 }
 
+// parseAttachRetries parses the number of retries of the attachment of a volume.
+func parseAttachRetries(value string) (int, error) {
+	retries, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if retries < 0 {
+		return 0, fmt.Errorf("negative number of retries %d", retries)
+	}
+	return retries, nil
+}
+
+// parseAllowedNodes returns the instance IDs of a comma-separated list.
+func parseAllowedNodes(value string) ([]string, error) {
+	var nodes []string
+	for _, node := range strings.Split(value, ",") {
+		node = strings.TrimSpace(node)
+		if node == "" {
+			return nil, fmt.Errorf("empty node ID in %q", value)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// instanceIDFromNodeID returns the instance ID of the node ID of a request, which may be a provider ID.
+func instanceIDFromNodeID(nodeID string) (string, error) {
+	if len(nodeID) == 0 {
+		return "", status.Error(codes.InvalidArgument, "Node ID not provided")
+	}
+	instanceID, err := cloud.MapToInstanceID(nodeID)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	return instanceID, nil
+}
+
 func isValidVolumeCapabilities(volCaps []*csi.VolumeCapability) bool {
 	hasSupport := func(cap *csi.VolumeCapability) bool {
 		for _, c := range volumeCaps {
@@ -424,8 +883,30 @@ func isValidVolumeCapabilities(volCaps []*csi.VolumeCapability) bool {
 	return foundAll
 }
 
+// validateMountFsTypes calls validate with the fstype of each mount capability of volCaps.
+func validateMountFsTypes(volCaps []*csi.VolumeCapability, validate func(fsType string) error) error {
+	for _, volCap := range volCaps {
+		mount := volCap.GetMount()
+		if mount == nil {
+			// raw block volumes are not mounted by the node
+			continue
+		}
+		fsType := mount.GetFsType()
+		if len(fsType) == 0 {
+			fsType = defaultFsType
+		}
+		if err := validate(fsType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
 	klog.V(4).Infof("CreateSnapshot: called with args %+v", req)
+	if d.driverOptions.disableSnapshots {
+		return nil, status.Error(codes.Unimplemented, "Snapshots are disabled")
+	}
 	snapshotName := req.GetName()
 	if len(snapshotName) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Snapshot name not provided")
@@ -444,11 +925,30 @@ func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 		if snapshot.SourceVolumeID != volumeID {
 			return nil, status.Errorf(codes.AlreadyExists, "Snapshot %s already exists for different volume (%s)", snapshotName, snapshot.SourceVolumeID)
 		}
+		switch err := cloud.CheckCreatedSnapshot(snapshot, DefaultSnapshotPendingTimeout, time.Now()); err {
+		case nil:
+		case cloud.ErrSnapshotPending:
+			return nil, status.Errorf(codes.Aborted, "Snapshot %s is still pending: %v", snapshot.SnapshotID, err)
+		case cloud.ErrSnapshotFailed:
+			return nil, status.Errorf(codes.ResourceExhausted, "Snapshot %s failed: %v", snapshot.SnapshotID, err)
+		default:
+			return nil, status.Errorf(codes.Internal, "Could not check snapshot %s: %v", snapshot.SnapshotID, err)
+		}
 		klog.V(4).Infof("Snapshot %s of volume %s already exists; nothing to do", snapshotName, volumeID)
 		return newCreateSnapshotResponse(snapshot)
 	}
+	disk, err := d.cloud.GetDiskByID(ctx, volumeID)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "Source volume %s not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get source volume %s: %v", volumeID, err)
+	}
 	opts := &cloud.SnapshotOptions{
-		Tags: map[string]string{cloud.SnapshotNameTagKey: snapshotName},
+		Tags: d.snapshotTags(snapshotName, req.GetParameters(), disk.Tags),
+	}
+	for k, v := range luksTags(luksContext(disk.Tags)) {
+		opts.Tags[k] = v
 	}
 	snapshot, err = d.cloud.CreateSnapshot(ctx, volumeID, opts)
 
@@ -458,8 +958,49 @@ func (d *controllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 	return newCreateSnapshotResponse(snapshot)
 }
 
+// snapshotTags merges the tags of a new snapshot. From the lowest to the highest precedence:
+//   - the extra snapshot tags of the driver options,
+//   - the tags of the source volume whose keys are inherited according to the driver options,
+//   - the VolumeSnapshotClass parameters prefixed by SnapshotTagKeyPrefix,
+//   - the well-known CSI parameters (VolumeSnapshot name and namespace, VolumeSnapshotContent name),
+//   - the snapshot name tag.
+func (d *controllerService) snapshotTags(snapshotName string, parameters map[string]string, volumeTags map[string]string) map[string]string {
+	tags := map[string]string{}
+	for k, v := range d.driverOptions.extraSnapshotTags {
+		tags[k] = v
+	}
+	for _, k := range d.driverOptions.inheritSnapshotTags {
+		if v, ok := volumeTags[k]; ok {
+			tags[k] = v
+		}
+	}
+	for k, v := range parameters {
+		if key := strings.TrimPrefix(k, SnapshotTagKeyPrefix); key != k && key != "" {
+			tags[key] = v
+		}
+	}
+	wellKnown := map[string]string{
+		VolumeSnapshotNameKey:        VolumeSnapshotNameTagKey,
+		VolumeSnapshotNamespaceKey:   VolumeSnapshotNamespaceTagKey,
+		VolumeSnapshotContentNameKey: VolumeSnapshotContentNameTagKey,
+	}
+	for param, tagKey := range wellKnown {
+		if v, ok := parameters[param]; ok {
+			tags[tagKey] = v
+		}
+	}
+	tags[cloud.SnapshotNameTagKey] = snapshotName
+	if d.driverOptions.clusterID != "" {
+		tags[cloud.ClusterIDTagKey] = d.driverOptions.clusterID
+	}
+	return tags
+}
+
 func (d *controllerService) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
 	klog.V(4).Infof("DeleteSnapshot: called with args %+v", req)
+	if d.driverOptions.disableSnapshots {
+		return nil, status.Error(codes.Unimplemented, "Snapshots are disabled")
+	}
 	snapshotID := req.GetSnapshotId()
 	if len(snapshotID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Snapshot ID not provided")
@@ -478,6 +1019,9 @@ func (d *controllerService) DeleteSnapshot(ctx context.Context, req *csi.DeleteS
 
 func (d *controllerService) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
 	klog.V(4).Infof("ListSnapshots: called with args %+v", req)
+	if d.driverOptions.disableSnapshots {
+		return nil, status.Error(codes.Unimplemented, "Snapshots are disabled")
+	}
 	var snapshots []cloud.Snapshot
 
 	snapshotID := req.GetSnapshotId()
@@ -513,9 +1057,19 @@ func (d *controllerService) ListSnapshots(ctx context.Context, req *csi.ListSnap
 		if err == cloud.ErrInvalidMaxResults {
 			return nil, status.Errorf(codes.InvalidArgument, "Error mapping MaxEntries to OSC MaxResults: %v", err)
 		}
+		if errors.Is(err, cloud.ErrInvalidNextToken) {
+			return nil, status.Errorf(codes.Aborted, "Invalid starting token %q: %v", nextToken, err)
+		}
 		return nil, status.Errorf(codes.Internal, "Could not list snapshots: %v", err)
 	}
 
+	if d.driverOptions.hideOrphanedSnapshots {
+		cloudSnapshots.Snapshots, err = d.withoutOrphanedSnapshots(ctx, cloudSnapshots.Snapshots)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not look up the source volumes of the snapshots: %v", err)
+		}
+	}
+
 	response, err := newListSnapshotsResponse(cloudSnapshots)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Could not build ListSnapshotsResponse: %v", err)
@@ -523,6 +1077,32 @@ func (d *controllerService) ListSnapshots(ctx context.Context, req *csi.ListSnap
 	return response, nil
 }
 
+// withoutOrphanedSnapshots returns the snapshots whose source volume still exists. The source volumes are read
+// in a single call, and the snapshots without source volume, such as the imported ones, are kept.
+func (d *controllerService) withoutOrphanedSnapshots(ctx context.Context, snapshots []cloud.Snapshot) ([]cloud.Snapshot, error) {
+	var volumeIDs []string
+	for _, snapshot := range snapshots {
+		if volumeID := snapshot.SourceVolumeID; volumeID != "" && !slices.Contains(volumeIDs, volumeID) {
+			volumeIDs = append(volumeIDs, volumeID)
+		}
+	}
+	disks, err := d.cloud.GetDisksByIDs(ctx, volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []cloud.Snapshot
+	for _, snapshot := range snapshots {
+		volumeID := snapshot.SourceVolumeID
+		if _, found := disks[volumeID]; volumeID != "" && !found {
+			klog.V(4).Infof("ListSnapshots: source volume %s of snapshot %s not found, snapshot excluded", volumeID, snapshot.SnapshotID)
+			continue
+		}
+		kept = append(kept, snapshot)
+	}
+	return kept, nil
+}
+
 // pickAvailabilityZone selects 1 zone given topology requirement.
 // if not found, empty string is returned.
 func pickAvailabilityZone(requirement *csi.TopologyRequirement) string {
@@ -552,6 +1132,47 @@ func pickAvailabilityZone(requirement *csi.TopologyRequirement) string {
 	return ""
 }
 
+// pickInitialNode returns the node hinted by the first preferred topology, which is the topology of the node
+// selected by the scheduler when the volume binding is delayed until a pod uses it.
+func pickInitialNode(requirement *csi.TopologyRequirement) string {
+	if len(requirement.GetPreferred()) == 0 {
+		return ""
+	}
+	return requirement.GetPreferred()[0].GetSegments()[TopologyNodeKey]
+}
+
+// luksContextTagKeys maps the keys of the LUKS volume context to the tags recording them.
+var luksContextTagKeys = map[string]string{
+	EncryptedKey:   EncryptedTagKey,
+	LuksCipherKey:  LuksCipherTagKey,
+	LuksHashKey:    LuksHashTagKey,
+	LuksKeySizeKey: LuksKeySizeTagKey,
+}
+
+// luksTags returns the tags recording the LUKS context of an encrypted volume.
+func luksTags(volumeContext map[string]string) map[string]string {
+	tags := map[string]string{}
+	for key, tagKey := range luksContextTagKeys {
+		if v := volumeContext[key]; v != "" {
+			tags[tagKey] = v
+		}
+	}
+	return tags
+}
+
+// luksContext returns the LUKS volume context recorded in the tags of a volume or a snapshot,
+// or nil if it is not encrypted.
+func luksContext(tags map[string]string) map[string]string {
+	if tags[EncryptedTagKey] != "true" {
+		return nil
+	}
+	volumeContext := map[string]string{}
+	for key, tagKey := range luksContextTagKeys {
+		volumeContext[key] = tags[tagKey]
+	}
+	return volumeContext
+}
+
 func newCreateVolumeResponse(disk cloud.Disk, volumeContextExtra map[string]string) *csi.CreateVolumeResponse {
 	var src *csi.VolumeContentSource
 	if disk.SnapshotID != "" {
@@ -580,6 +1201,10 @@ func newCreateVolumeResponse(disk cloud.Disk, volumeContextExtra map[string]stri
 }
 
 func newCreateSnapshotResponse(snapshot cloud.Snapshot) (*csi.CreateSnapshotResponse, error) {
+	if !snapshot.ReadyToUse {
+		// The CSI Snapshot has no progress field, the external-snapshotter polls until it is ready to use
+		klog.V(4).Infof("Snapshot %s of volume %s is not ready to use yet (state: %q, progress: %d%%)", snapshot.SnapshotID, snapshot.SourceVolumeID, snapshot.State, snapshot.Progress)
+	}
 	ts := timestamppb.New(snapshot.CreationTime)
 	return &csi.CreateSnapshotResponse{
 		Snapshot: &csi.Snapshot{
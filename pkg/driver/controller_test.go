@@ -23,17 +23,24 @@ import (
 	"math/rand"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/internal"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/mocks"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/util"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 )
 
 const (
@@ -48,8 +55,8 @@ func TestNewControllerService(t *testing.T) {
 		testErr    = errors.New("test error")
 		testRegion = "test-region"
 
-		getNewCloudFunc = func(expectedRegion string) func(region string) (cloud.Cloud, error) {
-			return func(region string) (cloud.Cloud, error) {
+		getNewCloudFunc = func(expectedRegion string) func(region string, options ...cloud.CloudOption) (cloud.Cloud, error) {
+			return func(region string, options ...cloud.CloudOption) (cloud.Cloud, error) {
 				if region != expectedRegion {
 					t.Fatalf("expected region %q but got %q", expectedRegion, region)
 				}
@@ -61,7 +68,7 @@ func TestNewControllerService(t *testing.T) {
 	testCases := []struct {
 		name                  string
 		region                string
-		newCloudFunc          func(string) (cloud.Cloud, error)
+		newCloudFunc          func(string, ...cloud.CloudOption) (cloud.Cloud, error)
 		newMetadataFuncErrors bool
 		expectPanic           bool
 	}{
@@ -73,7 +80,7 @@ func TestNewControllerService(t *testing.T) {
 		{
 			name:   "AWS_REGION variable set, newCloud errors",
 			region: "foo",
-			newCloudFunc: func(region string) (cloud.Cloud, error) {
+			newCloudFunc: func(region string, options ...cloud.CloudOption) (cloud.Cloud, error) {
 				return nil, testErr
 			},
 			expectPanic: true,
@@ -231,6 +238,7 @@ func TestCreateVolume(t *testing.T) {
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
 				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-id")).Return(cloud.Snapshot{SnapshotID: "snapshot-id"}, nil)
 				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(mockDisk, nil)
 
 				oscDriver := controllerService{
@@ -256,6 +264,41 @@ func TestCreateVolume(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "fail restore snapshot still pending",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "random-vol-name",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         nil,
+					VolumeContentSource: &csi.VolumeContentSource{
+						Type: &csi.VolumeContentSource_Snapshot{
+							Snapshot: &csi.VolumeContentSource_SnapshotSource{
+								SnapshotId: "snapshot-id",
+							},
+						},
+					},
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-id")).Return(cloud.Snapshot{SnapshotID: "snapshot-id", State: "pending"}, nil)
+				mockCloud.EXPECT().CreateDisk(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.Aborted)
+			},
+		},
 		{
 			name: "restore snapshot, volume already exists",
 			testFunc: func(t *testing.T) {
@@ -311,6 +354,64 @@ func TestCreateVolume(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "restore encrypted snapshot, volume already exists",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "random-vol-name",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         nil,
+					VolumeContentSource: &csi.VolumeContentSource{
+						Type: &csi.VolumeContentSource_Snapshot{
+							Snapshot: &csi.VolumeContentSource_SnapshotSource{
+								SnapshotId: "snapshot-id",
+							},
+						},
+					},
+				}
+
+				ctx := context.Background()
+
+				// the LUKS context of the snapshot was recorded in the tags of the volume on its creation
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+					SnapshotID:       "snapshot-id",
+					Tags: map[string]string{
+						EncryptedTagKey:  "true",
+						LuksCipherTagKey: "aes-xts-plain64",
+					},
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(mockDisk, nil)
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Any(), gomock.Any()).Times(0)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{requireEncryption: true},
+				}
+
+				rsp, err := oscDriver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				expContext := map[string]string{
+					EncryptedKey:   "true",
+					LuksCipherKey:  "aes-xts-plain64",
+					LuksHashKey:    "",
+					LuksKeySizeKey: "",
+				}
+				if !reflect.DeepEqual(rsp.Volume.VolumeContext, expContext) {
+					t.Fatalf("Expected volume context %v, got %v", expContext, rsp.Volume.VolumeContext)
+				}
+			},
+		},
 		{
 			name: "restore snapshot, volume already exists with different snapshot ID",
 			testFunc: func(t *testing.T) {
@@ -924,83 +1025,95 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "fail with invalid volume parameter",
+			name: "success with repair on mount",
 			testFunc: func(t *testing.T) {
 				req := &csi.CreateVolumeRequest{
 					Name:               "vol-test",
 					CapacityRange:      stdCapRange,
 					VolumeCapabilities: stdVolCap,
 					Parameters: map[string]string{
-						VolumeTypeKey: cloud.VolumeTypeIO1,
-						"unknownKey":  "unknownValue",
+						RepairOnMountKey: "true",
 					},
 				}
 
 				ctx := context.Background()
 
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
 				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(mockDisk, nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
 
-				_, err := oscDriver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatalf("Expected CreateVolume to fail but got no error")
+				volumeResponse, err := oscDriver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
 				}
 
-				srvErr, ok := status.FromError(err)
-				if !ok {
-					t.Fatalf("Could not get error status code from error: %v", srvErr)
-				}
-				if srvErr.Code() != codes.InvalidArgument {
-					t.Fatalf("Expect InvalidArgument but got: %s", srvErr.Code())
-				}
+				assert.Equal(t, "true", volumeResponse.GetVolume().VolumeContext[RepairOnMountKey])
 			},
 		},
 		{
-			name: "success when volume exists and contains VolumeContext and AccessibleTopology",
+			name: "fail with canceled context",
 			testFunc: func(t *testing.T) {
 				req := &csi.CreateVolumeRequest{
-					Name:               "test-vol",
+					Name:               "vol-test",
 					CapacityRange:      stdCapRange,
 					VolumeCapabilities: stdVolCap,
-					Parameters:         map[string]string{},
-					AccessibilityRequirements: &csi.TopologyRequirement{
-						Requisite: []*csi.Topology{
-							{
-								Segments: map[string]string{TopologyKey: expZone},
-							},
-						},
-					},
 				}
-				extraReq := &csi.CreateVolumeRequest{
-					Name:               "test-vol",
-					CapacityRange:      stdCapRange,
-					VolumeCapabilities: stdVolCap,
-					Parameters:         map[string]string{},
-					AccessibilityRequirements: &csi.TopologyRequirement{
-						Requisite: []*csi.Topology{
-							{
-								Segments: map[string]string{TopologyKey: expZone},
-							},
-						},
-					},
+
+				ctx, cancel := context.WithCancel(context.Background())
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, volumeName string, diskOptions *cloud.DiskOptions) (cloud.Disk, error) {
+						cancel()
+						return cloud.Disk{}, fmt.Errorf("failed to get an available volume in Outscale: %w", ctx.Err())
+					})
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
 				}
-				expVol := &csi.Volume{
-					CapacityBytes: stdVolSize,
-					VolumeId:      "vol-test",
-					VolumeContext: map[string]string{},
-					AccessibleTopology: []*csi.Topology{
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.Canceled)
+			},
+		},
+		{
+			name: "success with journal mode",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:          "vol-test",
+					CapacityRange: stdCapRange,
+					VolumeCapabilities: []*csi.VolumeCapability{
 						{
-							Segments: map[string]string{TopologyKey: expZone},
+							AccessType: &csi.VolumeCapability_Mount{
+								Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeExt4},
+							},
+							AccessMode: &csi.VolumeCapability_AccessMode{
+								Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+							},
 						},
 					},
+					Parameters: map[string]string{
+						JournalModeKey: JournalModeWriteback,
+					},
 				}
 
 				ctx := context.Background()
@@ -1023,56 +1136,62 @@ func TestCreateVolume(t *testing.T) {
 					driverOptions: &DriverOptions{},
 				}
 
-				if _, err := oscDriver.CreateVolume(ctx, req); err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					t.Fatalf("Unexpected error: %v", srvErr.Code())
-				}
-
-				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(mockDisk, nil)
-				resp, err := oscDriver.CreateVolume(ctx, extraReq)
+				volumeResponse, err := oscDriver.CreateVolume(ctx, req)
 				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					t.Fatalf("Unexpected error: %v", srvErr.Code())
+					t.Fatalf("Unexpected error: %v", err)
 				}
 
-				vol := resp.GetVolume()
-				if vol == nil {
-					t.Fatalf("Expected volume %v, got nil", expVol)
+				assert.Equal(t, JournalModeWriteback, volumeResponse.GetVolume().VolumeContext[JournalModeKey])
+			},
+		},
+		{
+			name: "fail with journal mode for xfs",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:          "vol-test",
+					CapacityRange: stdCapRange,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						{
+							AccessType: &csi.VolumeCapability_Mount{
+								Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeXfs},
+							},
+							AccessMode: &csi.VolumeCapability_AccessMode{
+								Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+							},
+						},
+					},
+					Parameters: map[string]string{
+						JournalModeKey: JournalModeJournal,
+					},
 				}
 
-				for expKey, expVal := range expVol.GetVolumeContext() {
-					ctx := vol.GetVolumeContext()
-					if gotVal, ok := ctx[expKey]; !ok || gotVal != expVal {
-						t.Fatalf("Expected volume context for key %v: %v, got: %v", expKey, expVal, gotVal)
-					}
-				}
+				ctx := context.Background()
 
-				if expVol.GetAccessibleTopology() != nil {
-					if !reflect.DeepEqual(expVol.GetAccessibleTopology(), vol.GetAccessibleTopology()) {
-						t.Fatalf("Expected AccessibleTopology to be %+v, got: %+v", expVol.GetAccessibleTopology(), vol.GetAccessibleTopology())
-					}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
 				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
 			},
 		},
 		{
-			name: "success with extra tags",
+			name: "success with attach retries",
 			testFunc: func(t *testing.T) {
-				const (
-					volumeName          = "random-vol-name"
-					extraVolumeTagKey   = "extra-tag-key"
-					extraVolumeTagValue = "extra-tag-value"
-				)
 				req := &csi.CreateVolumeRequest{
-					Name:               volumeName,
+					Name:               "vol-test",
 					CapacityRange:      stdCapRange,
 					VolumeCapabilities: stdVolCap,
-					Parameters:         nil,
+					Parameters: map[string]string{
+						AttachRetriesKey: "10",
+					},
 				}
 
 				ctx := context.Background()
@@ -1083,237 +1202,2159 @@ func TestCreateVolume(t *testing.T) {
 					CapacityGiB:      util.BytesToGiB(stdVolSize),
 				}
 
-				diskOptions := &cloud.DiskOptions{
-					CapacityBytes: stdVolSize,
-					Tags: map[string]string{
-						cloud.VolumeNameTagKey: volumeName,
-						extraVolumeTagKey:      extraVolumeTagValue,
-					},
-				}
-
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
 				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
-				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(mockDisk, nil)
 
 				oscDriver := controllerService{
-					cloud: mockCloud,
-					driverOptions: &DriverOptions{
-						extraVolumeTags: map[string]string{
-							extraVolumeTagKey: extraVolumeTagValue,
-						},
-					},
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
 				}
 
-				_, err := oscDriver.CreateVolume(ctx, req)
+				volumeResponse, err := oscDriver.CreateVolume(ctx, req)
 				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					t.Fatalf("Unexpected error: %v", srvErr.Code())
+					t.Fatalf("Unexpected error: %v", err)
 				}
+
+				assert.Equal(t, "10", volumeResponse.GetVolume().VolumeContext[AttachRetriesKey])
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
-	}
-}
-
-func TestDeleteVolume(t *testing.T) {
-	testCases := []struct {
-		name     string
-		testFunc func(t *testing.T)
-	}{
 		{
-			name: "success normal",
+			name: "fail with negative attach retries",
 			testFunc: func(t *testing.T) {
-				req := &csi.DeleteVolumeRequest{
-					VolumeId: "vol-test",
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						AttachRetriesKey: "-1",
+					},
 				}
-				expResp := &csi.DeleteVolumeResponse{}
 
 				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(true, nil)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
-				resp, err := oscDriver.DeleteVolume(ctx, req)
-				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					t.Fatalf("Unexpected error: %v", srvErr.Code())
-				}
-				if !reflect.DeepEqual(resp, expResp) {
-					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
-				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
 			},
 		},
 		{
-			name: "success invalid volume id",
+			name: "success with allowed nodes",
 			testFunc: func(t *testing.T) {
-				req := &csi.DeleteVolumeRequest{
-					VolumeId: "invalid-volume-name",
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						AllowedNodesKey: "i-node1,i-node2",
+					},
 				}
-				expResp := &csi.DeleteVolumeResponse{}
 
 				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(false, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(mockDisk, nil)
+
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
-				resp, err := oscDriver.DeleteVolume(ctx, req)
+
+				volumeResponse, err := oscDriver.CreateVolume(ctx, req)
 				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					t.Fatalf("Unexpected error: %v", srvErr.Code())
-				}
-				if !reflect.DeepEqual(resp, expResp) {
-					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
+					t.Fatalf("Unexpected error: %v", err)
 				}
+
+				assert.Equal(t, "i-node1,i-node2", volumeResponse.GetVolume().VolumeContext[AllowedNodesKey])
 			},
 		},
 		{
-			name: "fail delete disk",
+			name: "fail with empty allowed node",
 			testFunc: func(t *testing.T) {
-				req := &csi.DeleteVolumeRequest{
-					VolumeId: "test-vol",
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						AllowedNodesKey: "i-node1,,i-node2",
+					},
 				}
 
 				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(false, fmt.Errorf("DeleteDisk could not delete volume"))
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
-				resp, err := oscDriver.DeleteVolume(ctx, req)
-				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.Internal {
-						t.Fatalf("Unexpected error: %v", srvErr.Code())
-					}
-				} else {
-					t.Fatalf("Expected error, got nil")
-				}
 
-				if resp != nil {
-					t.Fatalf("Expected resp to be nil, got: %+v", resp)
-				}
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
-	}
-}
-
-func TestPickAvailabilityZone(t *testing.T) {
-	testCases := []struct {
-		name        string
-		requirement *csi.TopologyRequirement
-		expZone     string
-	}{
 		{
-			name: "Pick from preferred",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone},
+			name: "fail with preallocate for xfs",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:          "vol-test",
+					CapacityRange: stdCapRange,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						{
+							AccessType: &csi.VolumeCapability_Mount{
+								Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeXfs},
+							},
+							AccessMode: &csi.VolumeCapability_AccessMode{
+								Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+							},
+						},
 					},
-				},
-				Preferred: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone},
+					Parameters: map[string]string{
+						PreallocateKey: "true",
 					},
-				},
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
 			},
-			expZone: expZone,
 		},
 		{
-			name: "Pick from requisite",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone},
+			name: "fail with unknown mount profile",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						MountProfileKey: "unknown",
 					},
-				},
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound).AnyTimes()
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+					mountProfiles: mountProfiles{"database": {"noatime"}},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
 			},
-			expZone: expZone,
 		},
 		{
-			name: "Pick from requisite topologyK8sKey",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyK8sKey: expZone},
+			name: "success with performance class",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						PerformanceClassKey: "fast",
 					},
-				},
-			},
-			expZone: expZone,
-		},
-		{
-			name: "Pick from multi requisites",
-			requirement: &csi.TopologyRequirement{
-				Requisite: []*csi.Topology{
-					{
-						Segments: map[string]string{TopologyKey: expZone, TopologyK8sKey: expZone},
+				}
+
+				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				diskOptions := &cloud.DiskOptions{
+					CapacityBytes: stdVolSize,
+					Tags:          map[string]string{cloud.VolumeNameTagKey: req.Name},
+					VolumeType:    cloud.VolumeTypeIO1,
+					IOPSPerGB:     50,
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						// The performance class replaces the default type
+						defaultVolumeParams: map[string]string{VolumeTypeKey: cloud.VolumeTypeSTANDARD},
 					},
-				},
+					perfClasses: performanceClasses{
+						"fast": {VolumeType: cloud.VolumeTypeIO1, IopsPerGB: 50},
+						"cold": {VolumeType: cloud.VolumeTypeSTANDARD},
+					},
+				}
+
+				if _, err := oscDriver.CreateVolume(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
 			},
-			expZone: expZone,
 		},
 		{
-			name: "Pick from empty topology",
-			requirement: &csi.TopologyRequirement{
-				Preferred: []*csi.Topology{{}},
-				Requisite: []*csi.Topology{{}},
+			name: "fail with unknown performance class",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						PerformanceClassKey: "unknown",
+					},
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+					perfClasses:   performanceClasses{"fast": {VolumeType: cloud.VolumeTypeIO1, IopsPerGB: 50}},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
 			},
-			expZone: "",
 		},
 		{
-			name:        "Topology Requirement is nil",
-			requirement: nil,
-			expZone:     "",
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			actual := pickAvailabilityZone(tc.requirement)
-			if actual != tc.expZone {
-				t.Fatalf("Expected zone %v, got zone: %v", tc.expZone, actual)
-			}
+			name: "fail with performance class and volume type",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						PerformanceClassKey: "fast",
+						VolumeTypeKey:       cloud.VolumeTypeGP2,
+					},
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+					perfClasses:   performanceClasses{"fast": {VolumeType: cloud.VolumeTypeIO1, IopsPerGB: 50}},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "fail with invalid volume parameter",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						VolumeTypeKey: cloud.VolumeTypeIO1,
+						"unknownKey":  "unknownValue",
+					},
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatalf("Expected CreateVolume to fail but got no error")
+				}
+
+				srvErr, ok := status.FromError(err)
+				if !ok {
+					t.Fatalf("Could not get error status code from error: %v", srvErr)
+				}
+				if srvErr.Code() != codes.InvalidArgument {
+					t.Fatalf("Expect InvalidArgument but got: %s", srvErr.Code())
+				}
+			},
+		},
+		{
+			name: "success when volume exists and contains VolumeContext and AccessibleTopology",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "test-vol",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         map[string]string{},
+					AccessibilityRequirements: &csi.TopologyRequirement{
+						Requisite: []*csi.Topology{
+							{
+								Segments: map[string]string{TopologyKey: expZone},
+							},
+						},
+					},
+				}
+				extraReq := &csi.CreateVolumeRequest{
+					Name:               "test-vol",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         map[string]string{},
+					AccessibilityRequirements: &csi.TopologyRequirement{
+						Requisite: []*csi.Topology{
+							{
+								Segments: map[string]string{TopologyKey: expZone},
+							},
+						},
+					},
+				}
+				expVol := &csi.Volume{
+					CapacityBytes: stdVolSize,
+					VolumeId:      "vol-test",
+					VolumeContext: map[string]string{},
+					AccessibleTopology: []*csi.Topology{
+						{
+							Segments: map[string]string{TopologyKey: expZone},
+						},
+					},
+				}
+
+				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				if _, err := oscDriver.CreateVolume(ctx, req); err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(mockDisk, nil)
+				resp, err := oscDriver.CreateVolume(ctx, extraReq)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+
+				vol := resp.GetVolume()
+				if vol == nil {
+					t.Fatalf("Expected volume %v, got nil", expVol)
+				}
+
+				for expKey, expVal := range expVol.GetVolumeContext() {
+					ctx := vol.GetVolumeContext()
+					if gotVal, ok := ctx[expKey]; !ok || gotVal != expVal {
+						t.Fatalf("Expected volume context for key %v: %v, got: %v", expKey, expVal, gotVal)
+					}
+				}
+
+				if expVol.GetAccessibleTopology() != nil {
+					if !reflect.DeepEqual(expVol.GetAccessibleTopology(), vol.GetAccessibleTopology()) {
+						t.Fatalf("Expected AccessibleTopology to be %+v, got: %+v", expVol.GetAccessibleTopology(), vol.GetAccessibleTopology())
+					}
+				}
+			},
+		},
+		{
+			name: "success with extra tags",
+			testFunc: func(t *testing.T) {
+				const (
+					volumeName          = "random-vol-name"
+					extraVolumeTagKey   = "extra-tag-key"
+					extraVolumeTagValue = "extra-tag-value"
+				)
+				req := &csi.CreateVolumeRequest{
+					Name:               volumeName,
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         nil,
+				}
+
+				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				diskOptions := &cloud.DiskOptions{
+					CapacityBytes: stdVolSize,
+					Tags: map[string]string{
+						cloud.VolumeNameTagKey: volumeName,
+						extraVolumeTagKey:      extraVolumeTagValue,
+					},
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						extraVolumeTags: map[string]string{
+							extraVolumeTagKey: extraVolumeTagValue,
+						},
+					},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+			},
+		},
+		{
+			name: "fail quota exceeded with retry delay",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "random-vol-name",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         nil,
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(cloud.Disk{}, fmt.Errorf("could not create volume in Outscale: %w", cloud.ErrQuotaExceeded))
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						quotaRetryInterval: 5 * time.Minute,
+					},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.ResourceExhausted)
+
+				var retryInfo *errdetails.RetryInfo
+				for _, detail := range status.Convert(err).Details() {
+					if info, ok := detail.(*errdetails.RetryInfo); ok {
+						retryInfo = info
+					}
+				}
+				if retryInfo == nil {
+					t.Fatalf("Expected a retry info detail, got none")
+				}
+				if delay := retryInfo.GetRetryDelay().AsDuration(); delay != 5*time.Minute {
+					t.Fatalf("Expected a retry delay of 5m, got %v", delay)
+				}
+			},
+		},
+		{
+			name: "success with cluster ID tag",
+			testFunc: func(t *testing.T) {
+				const volumeName = "random-vol-name"
+				req := &csi.CreateVolumeRequest{
+					Name:               volumeName,
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         nil,
+				}
+
+				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				diskOptions := &cloud.DiskOptions{
+					CapacityBytes: stdVolSize,
+					Tags: map[string]string{
+						cloud.VolumeNameTagKey: volumeName,
+						cloud.ClusterIDTagKey:  "cluster-test",
+						"extra-tag-key":        "extra-tag-value",
+					},
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						clusterID: "cluster-test",
+						extraVolumeTags: map[string]string{
+							cloud.ClusterIDTagKey: "overridden",
+							"extra-tag-key":       "extra-tag-value",
+						},
+					},
+				}
+
+				if _, err := oscDriver.CreateVolume(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success with initial node tag",
+			testFunc: func(t *testing.T) {
+				const volumeName = "random-vol-name"
+				req := &csi.CreateVolumeRequest{
+					Name:               volumeName,
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         nil,
+					AccessibilityRequirements: &csi.TopologyRequirement{
+						Preferred: []*csi.Topology{
+							{Segments: map[string]string{TopologyKey: expZone, TopologyNodeKey: expInstanceID}},
+							{Segments: map[string]string{TopologyKey: expZone, TopologyNodeKey: "i-other"}},
+						},
+					},
+				}
+
+				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				diskOptions := &cloud.DiskOptions{
+					CapacityBytes: stdVolSize,
+					Tags: map[string]string{
+						cloud.VolumeNameTagKey: volumeName,
+						InitialNodeTagKey:      expInstanceID,
+					},
+					AvailabilityZone: expZone,
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				if _, err := oscDriver.CreateVolume(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success restore encrypted snapshot",
+			testFunc: func(t *testing.T) {
+				const volumeName = "random-vol-name"
+				req := &csi.CreateVolumeRequest{
+					Name:               volumeName,
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         nil,
+					VolumeContentSource: &csi.VolumeContentSource{
+						Type: &csi.VolumeContentSource_Snapshot{
+							Snapshot: &csi.VolumeContentSource_SnapshotSource{
+								SnapshotId: "snapshot-id",
+							},
+						},
+					},
+				}
+
+				ctx := context.Background()
+
+				mockSnapshot := cloud.Snapshot{
+					SnapshotID: "snapshot-id",
+					Tags: map[string]string{
+						EncryptedTagKey:  "true",
+						LuksCipherTagKey: "aes-xts-plain64",
+					},
+				}
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+					SnapshotID:       "snapshot-id",
+				}
+
+				diskOptions := &cloud.DiskOptions{
+					CapacityBytes: stdVolSize,
+					Tags: map[string]string{
+						cloud.VolumeNameTagKey: volumeName,
+						EncryptedTagKey:        "true",
+						LuksCipherTagKey:       "aes-xts-plain64",
+					},
+					Encrypted:  true,
+					SnapshotID: "snapshot-id",
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-id")).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				rsp, err := oscDriver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				expContext := map[string]string{
+					EncryptedKey:   "true",
+					LuksCipherKey:  "aes-xts-plain64",
+					LuksHashKey:    "",
+					LuksKeySizeKey: "",
+				}
+				if !reflect.DeepEqual(rsp.Volume.VolumeContext, expContext) {
+					t.Fatalf("Expected volume context %v, got %v", expContext, rsp.Volume.VolumeContext)
+				}
+			},
+		},
+		{
+			name: "success with default volume parameters",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters: map[string]string{
+						VolumeTypeKey: cloud.VolumeTypeIO1,
+					},
+				}
+
+				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				diskOptions := &cloud.DiskOptions{
+					CapacityBytes: stdVolSize,
+					Tags:          map[string]string{cloud.VolumeNameTagKey: req.Name},
+					VolumeType:    cloud.VolumeTypeIO1,
+					IOPSPerGB:     5,
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						defaultVolumeParams: map[string]string{
+							VolumeTypeKey: cloud.VolumeTypeGP2,
+							IopsPerGBKey:  "5",
+						},
+					},
+				}
+
+				if _, err := oscDriver.CreateVolume(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success with volume name prefix",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+				}
+
+				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				diskOptions := &cloud.DiskOptions{
+					CapacityBytes: stdVolSize,
+					Tags:          map[string]string{cloud.VolumeNameTagKey: "team-a-" + req.Name},
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq("team-a-"+req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(diskOptions)).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{volumeNamePrefix: "team-a-"},
+				}
+
+				if _, err := oscDriver.CreateVolume(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success encryption required and requested",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         map[string]string{EncryptedKey: "true"},
+				}
+
+				ctx := context.Background()
+
+				mockDisk := cloud.Disk{
+					VolumeID:         req.Name,
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(mockDisk, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{requireEncryption: true},
+				}
+
+				resp, err := oscDriver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if resp.GetVolume().GetVolumeContext()[EncryptedKey] != "true" {
+					t.Fatalf("Expected an encrypted volume context, got %v", resp.GetVolume().GetVolumeContext())
+				}
+			},
+		},
+		{
+			name: "fail encryption required but not requested",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{requireEncryption: true},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "fail invalid default volume parameter",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateVolumeRequest{
+					Name:               "vol-test",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						defaultVolumeParams: map[string]string{
+							"unknown": "value",
+						},
+					},
+				}
+
+				_, err := oscDriver.CreateVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestVolumeParameters(t *testing.T) {
+	testCases := []struct {
+		name          string
+		defaults      map[string]string
+		parameters    map[string]string
+		expParameters map[string]string
+	}{
+		{
+			name:          "no defaults",
+			parameters:    map[string]string{VolumeTypeKey: cloud.VolumeTypeIO1},
+			expParameters: map[string]string{VolumeTypeKey: cloud.VolumeTypeIO1},
+		},
+		{
+			name:          "defaults only",
+			defaults:      map[string]string{VolumeTypeKey: cloud.VolumeTypeGP2},
+			expParameters: map[string]string{VolumeTypeKey: cloud.VolumeTypeGP2},
+		},
+		{
+			name:          "merged parameters",
+			defaults:      map[string]string{VolumeTypeKey: cloud.VolumeTypeIO1, IopsPerGBKey: "5"},
+			parameters:    map[string]string{EncryptedKey: "true"},
+			expParameters: map[string]string{VolumeTypeKey: cloud.VolumeTypeIO1, IopsPerGBKey: "5", EncryptedKey: "true"},
+		},
+		{
+			name:          "request parameters override defaults",
+			defaults:      map[string]string{VolumeTypeKey: cloud.VolumeTypeGP2, IopsPerGBKey: "5"},
+			parameters:    map[string]string{VolumeTypeKey: cloud.VolumeTypeIO1, "IOPSPERGB": "10"},
+			expParameters: map[string]string{VolumeTypeKey: cloud.VolumeTypeIO1, "IOPSPERGB": "10"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &controllerService{
+				driverOptions: &DriverOptions{
+					defaultVolumeParams: tc.defaults,
+				},
+			}
+			parameters := d.volumeParameters(tc.parameters)
+			if !reflect.DeepEqual(parameters, tc.expParameters) {
+				t.Fatalf("Expected parameters %v, got %v", tc.expParameters, parameters)
+			}
+		})
+	}
+}
+
+func TestDeleteVolume(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success normal",
+			testFunc: func(t *testing.T) {
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: "vol-test",
+				}
+				expResp := &csi.DeleteVolumeResponse{}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(true, nil)
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := oscDriver.DeleteVolume(ctx, req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
+				}
+			},
+		},
+		{
+			name: "success invalid volume id",
+			testFunc: func(t *testing.T) {
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: "invalid-volume-name",
+				}
+				expResp := &csi.DeleteVolumeResponse{}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(false, cloud.ErrNotFound)
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := oscDriver.DeleteVolume(ctx, req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					t.Fatalf("Unexpected error: %v", srvErr.Code())
+				}
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
+				}
+			},
+		},
+		{
+			name: "fail delete disk",
+			testFunc: func(t *testing.T) {
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: "test-vol",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().DeleteDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId)).Return(false, fmt.Errorf("DeleteDisk could not delete volume"))
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := oscDriver.DeleteVolume(ctx, req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.Internal {
+						t.Fatalf("Unexpected error: %v", srvErr.Code())
+					}
+				} else {
+					t.Fatalf("Expected error, got nil")
+				}
+
+				if resp != nil {
+					t.Fatalf("Expected resp to be nil, got: %+v", resp)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestCreateVolumeZoneVolumeTypes(t *testing.T) {
+	stdVolSize := int64(5 * 1024 * 1024 * 1024)
+	zoneVolumeTypes := map[string]string{"eu-west-2b": cloud.VolumeTypeIO1}
+	testCases := []struct {
+		name          string
+		zone          string
+		parameters    map[string]string
+		expVolumeType string
+	}{
+		{
+			name:          "success: default type of the zone",
+			zone:          "eu-west-2b",
+			expVolumeType: cloud.VolumeTypeIO1,
+		},
+		{
+			name:          "success: global default for a zone without default type",
+			zone:          "eu-west-2a",
+			expVolumeType: "",
+		},
+		{
+			name:          "success: type of the StorageClass",
+			zone:          "eu-west-2b",
+			parameters:    map[string]string{VolumeTypeKey: cloud.VolumeTypeSTANDARD},
+			expVolumeType: cloud.VolumeTypeSTANDARD,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &csi.CreateVolumeRequest{
+				Name:               "vol-test",
+				CapacityRange:      &csi.CapacityRange{RequiredBytes: stdVolSize},
+				VolumeCapabilities: []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}, AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER}}},
+				Parameters:         tc.parameters,
+				AccessibilityRequirements: &csi.TopologyRequirement{
+					Requisite: []*csi.Topology{{Segments: map[string]string{TopologyKey: tc.zone}}},
+				},
+			}
+
+			ctx := context.Background()
+
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(stdVolSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+			mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, volumeName string, diskOptions *cloud.DiskOptions) (cloud.Disk, error) {
+					if diskOptions.VolumeType != tc.expVolumeType {
+						t.Fatalf("Expected volume type %q, got %q", tc.expVolumeType, diskOptions.VolumeType)
+					}
+					return cloud.Disk{VolumeID: "vol-test", AvailabilityZone: tc.zone, CapacityGiB: util.BytesToGiB(stdVolSize)}, nil
+				})
+
+			oscDriver := controllerService{
+				cloud:         mockCloud,
+				driverOptions: &DriverOptions{zoneVolumeTypes: zoneVolumeTypes},
+			}
+
+			if _, err := oscDriver.CreateVolume(ctx, req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateVolumeMaxVolumeSize(t *testing.T) {
+	maxVolumeSize := util.GiBToBytes(10)
+	testCases := []struct {
+		name          string
+		requiredBytes int64
+		expErr        bool
+	}{
+		{
+			name:          "success: size at the limit",
+			requiredBytes: maxVolumeSize,
+		},
+		{
+			name:          "success: size rounded up to the limit",
+			requiredBytes: maxVolumeSize - 1,
+		},
+		{
+			name:          "fail: size rounded up above the limit",
+			requiredBytes: maxVolumeSize + 1,
+			expErr:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &csi.CreateVolumeRequest{
+				Name:               "vol-test",
+				CapacityRange:      &csi.CapacityRange{RequiredBytes: tc.requiredBytes},
+				VolumeCapabilities: []*csi.VolumeCapability{{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}, AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER}}},
+			}
+
+			ctx := context.Background()
+
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			if !tc.expErr {
+				mockCloud.EXPECT().GetDiskByName(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Eq(maxVolumeSize)).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.Name), gomock.Any()).Return(cloud.Disk{VolumeID: "vol-test", CapacityGiB: 10}, nil)
+			}
+
+			oscDriver := controllerService{
+				cloud:         mockCloud,
+				driverOptions: &DriverOptions{maxVolumeSize: maxVolumeSize},
+			}
+
+			_, err := oscDriver.CreateVolume(ctx, req)
+			if tc.expErr {
+				expectErr(t, err, codes.OutOfRange)
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPickAvailabilityZone(t *testing.T) {
+	testCases := []struct {
+		name        string
+		requirement *csi.TopologyRequirement
+		expZone     string
+	}{
+		{
+			name: "Pick from preferred",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone},
+					},
+				},
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone},
+					},
+				},
+			},
+			expZone: expZone,
+		},
+		{
+			name: "Pick from requisite",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone},
+					},
+				},
+			},
+			expZone: expZone,
+		},
+		{
+			name: "Pick from preferred with region segment",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone, TopologyRegionKey: "eu-west-2"},
+					},
+				},
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone, TopologyRegionKey: "eu-west-2"},
+					},
+				},
+			},
+			expZone: expZone,
+		},
+		{
+			name: "Pick from requisite topologyK8sKey",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyK8sKey: expZone},
+					},
+				},
+			},
+			expZone: expZone,
+		},
+		{
+			name: "Pick from multi requisites",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{TopologyKey: expZone, TopologyK8sKey: expZone},
+					},
+				},
+			},
+			expZone: expZone,
+		},
+		{
+			name: "Pick from empty topology",
+			requirement: &csi.TopologyRequirement{
+				Preferred: []*csi.Topology{{}},
+				Requisite: []*csi.Topology{{}},
+			},
+			expZone: "",
+		},
+		{
+			name:        "Topology Requirement is nil",
+			requirement: nil,
+			expZone:     "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := pickAvailabilityZone(tc.requirement)
+			if actual != tc.expZone {
+				t.Fatalf("Expected zone %v, got zone: %v", tc.expZone, actual)
+			}
 		})
 	}
 }
 
-func TestCreateSnapshot(t *testing.T) {
+func TestCreateSnapshot(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success normal",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					Parameters:     nil,
+					SourceVolumeId: "vol-test",
+				}
+				expSnapshot := &csi.Snapshot{
+					ReadyToUse: true,
+				}
+
+				ctx := context.Background()
+				mockSnapshot := cloud.Snapshot{
+					SnapshotID:     fmt.Sprintf("snapshot-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()),
+					SourceVolumeID: req.SourceVolumeId,
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId)).Return(cloud.Disk{VolumeID: req.SourceVolumeId}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Any()).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := oscDriver.CreateSnapshot(context.Background(), req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if snap := resp.GetSnapshot(); snap == nil {
+					t.Fatalf("Expected snapshot %v, got nil", expSnapshot)
+				}
+			},
+		},
+		{
+			name: "success with merged tags",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name: "test-snapshot",
+					Parameters: map[string]string{
+						SnapshotTagKeyPrefix + "team":                   "class-team",
+						SnapshotTagKeyPrefix + "env":                    "class-env",
+						SnapshotTagKeyPrefix + VolumeSnapshotNameTagKey: "class-name",
+						SnapshotTagKeyPrefix + cloud.SnapshotNameTagKey: "class-snapshot-name",
+						VolumeSnapshotNameKey:                           "snap-name",
+						VolumeSnapshotNamespaceKey:                      "snap-namespace",
+						VolumeSnapshotContentNameKey:                    "snapcontent-name",
+						"unknown":                                       "ignored",
+					},
+					SourceVolumeId: "vol-test",
+				}
+				expOpts := &cloud.SnapshotOptions{
+					Tags: map[string]string{
+						"owner":                         "operator",
+						"team":                          "class-team",
+						"env":                           "class-env",
+						VolumeSnapshotNameTagKey:        "snap-name",
+						VolumeSnapshotNamespaceTagKey:   "snap-namespace",
+						VolumeSnapshotContentNameTagKey: "snapcontent-name",
+						cloud.SnapshotNameTagKey:        "test-snapshot",
+					},
+				}
+
+				ctx := context.Background()
+				mockSnapshot := cloud.Snapshot{
+					SnapshotID:     "snapshot-test",
+					SourceVolumeID: req.SourceVolumeId,
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId)).Return(cloud.Disk{VolumeID: req.SourceVolumeId}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Eq(expOpts)).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						extraSnapshotTags: map[string]string{
+							"owner": "operator",
+							"team":  "operator-team",
+							"env":   "operator-env",
+						},
+					},
+				}
+				if _, err := oscDriver.CreateSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success with tags inherited from the source volume",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name: "test-snapshot",
+					Parameters: map[string]string{
+						SnapshotTagKeyPrefix + "team": "class-team",
+					},
+					SourceVolumeId: "vol-test",
+				}
+				expOpts := &cloud.SnapshotOptions{
+					Tags: map[string]string{
+						"cost-center":            "cc-42",
+						"team":                   "class-team",
+						cloud.SnapshotNameTagKey: "test-snapshot",
+					},
+				}
+
+				ctx := context.Background()
+				mockSnapshot := cloud.Snapshot{
+					SnapshotID:     "snapshot-test",
+					SourceVolumeID: req.SourceVolumeId,
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId)).Return(cloud.Disk{
+					VolumeID: req.SourceVolumeId,
+					Tags: map[string]string{
+						"cost-center":          "cc-42",
+						"team":                 "volume-team",
+						"not-inherited":        "ignored",
+						cloud.VolumeNameTagKey: "pvc-test",
+					},
+				}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Eq(expOpts)).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						inheritSnapshotTags: []string{"cost-center", "team", "missing"},
+					},
+				}
+				if _, err := oscDriver.CreateSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success with cluster ID tag",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name: "test-snapshot",
+					Parameters: map[string]string{
+						SnapshotTagKeyPrefix + cloud.ClusterIDTagKey: "class-cluster",
+					},
+					SourceVolumeId: "vol-test",
+				}
+				expOpts := &cloud.SnapshotOptions{
+					Tags: map[string]string{
+						cloud.ClusterIDTagKey:    "cluster-test",
+						cloud.SnapshotNameTagKey: "test-snapshot",
+					},
+				}
+
+				ctx := context.Background()
+				mockSnapshot := cloud.Snapshot{
+					SnapshotID:     "snapshot-test",
+					SourceVolumeID: req.SourceVolumeId,
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId)).Return(cloud.Disk{VolumeID: req.SourceVolumeId}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Eq(expOpts)).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud: mockCloud,
+					driverOptions: &DriverOptions{
+						clusterID: "cluster-test",
+						extraSnapshotTags: map[string]string{
+							cloud.ClusterIDTagKey: "overridden",
+						},
+					},
+				}
+				if _, err := oscDriver.CreateSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success with encrypted source volume",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					SourceVolumeId: "vol-test",
+				}
+				expOpts := &cloud.SnapshotOptions{
+					Tags: map[string]string{
+						cloud.SnapshotNameTagKey: "test-snapshot",
+						EncryptedTagKey:          "true",
+						LuksHashTagKey:           "sha256",
+					},
+				}
+
+				ctx := context.Background()
+				mockDisk := cloud.Disk{
+					VolumeID: req.SourceVolumeId,
+					Tags: map[string]string{
+						cloud.VolumeNameTagKey: "random-vol-name",
+						EncryptedTagKey:        "true",
+						LuksHashTagKey:         "sha256",
+					},
+				}
+				mockSnapshot := cloud.Snapshot{
+					SnapshotID:     "snapshot-test",
+					SourceVolumeID: req.SourceVolumeId,
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId)).Return(mockDisk, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Eq(expOpts)).Return(mockSnapshot, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				if _, err := oscDriver.CreateSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail no name",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Parameters:     nil,
+					SourceVolumeId: "vol-test",
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				if _, err := oscDriver.CreateSnapshot(context.Background(), req); err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.InvalidArgument {
+						t.Fatalf("Expected error code %d, got %d message %s", codes.InvalidArgument, srvErr.Code(), srvErr.Message())
+					}
+				} else {
+					t.Fatalf("Expected error %v, got no error", codes.InvalidArgument)
+				}
+			},
+		},
+		{
+			name: "fail same name different volume ID",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					Parameters:     nil,
+					SourceVolumeId: "vol-test",
+				}
+				extraReq := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					Parameters:     nil,
+					SourceVolumeId: "vol-xxx",
+				}
+				expSnapshot := &csi.Snapshot{
+					ReadyToUse: true,
+				}
+
+				ctx := context.Background()
+				mockSnapshot := cloud.Snapshot{
+					SnapshotID:     fmt.Sprintf("snapshot-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()),
+					SourceVolumeID: req.SourceVolumeId,
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId)).Return(cloud.Disk{VolumeID: req.SourceVolumeId}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Any()).Return(mockSnapshot, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := oscDriver.CreateSnapshot(context.Background(), req)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.OK {
+						t.Fatalf("Expected error code %d, got %d message %s", codes.OK, srvErr.Code(), srvErr.Message())
+					}
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				snap := resp.GetSnapshot()
+				if snap == nil {
+					t.Fatalf("Expected snapshot %v, got nil", expSnapshot)
+				}
+
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(extraReq.GetName())).Return(mockSnapshot, nil)
+				_, err = oscDriver.CreateSnapshot(ctx, extraReq)
+				if err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.AlreadyExists {
+						t.Fatalf("Expected error code %d, got %d message %s", codes.AlreadyExists, srvErr.Code(), srvErr.Message())
+					}
+				} else {
+					t.Fatalf("Expected error %v, got no error", codes.AlreadyExists)
+				}
+			},
+		},
+		{
+			name: "success same name same volume ID",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					Parameters:     nil,
+					SourceVolumeId: "vol-test",
+				}
+				extraReq := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					Parameters:     nil,
+					SourceVolumeId: "vol-test",
+				}
+				expSnapshot := &csi.Snapshot{
+					ReadyToUse: true,
+				}
+
+				ctx := context.Background()
+				mockSnapshot := cloud.Snapshot{
+					SnapshotID:     fmt.Sprintf("snapshot-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()),
+					SourceVolumeID: req.SourceVolumeId,
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId)).Return(cloud.Disk{VolumeID: req.SourceVolumeId}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Any()).Return(mockSnapshot, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := oscDriver.CreateSnapshot(context.Background(), req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				snap := resp.GetSnapshot()
+				if snap == nil {
+					t.Fatalf("Expected snapshot %v, got nil", expSnapshot)
+				}
+
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(extraReq.GetName())).Return(mockSnapshot, nil)
+				_, err = oscDriver.CreateSnapshot(ctx, extraReq)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail existing snapshot stuck in pending",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					SourceVolumeId: "vol-test",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{
+					SnapshotID:     "snap-test",
+					SourceVolumeID: req.SourceVolumeId,
+					CreationTime:   time.Now().Add(-2 * DefaultSnapshotPendingTimeout),
+					State:          "pending",
+				}, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				_, err := oscDriver.CreateSnapshot(ctx, req)
+				expectErr(t, err, codes.Aborted)
+			},
+		},
+		{
+			name: "success existing snapshot in progress",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					SourceVolumeId: "vol-test",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{
+					SnapshotID:     "snap-test",
+					SourceVolumeID: req.SourceVolumeId,
+					CreationTime:   time.Now(),
+					State:          "pending",
+					Progress:       42,
+				}, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				resp, err := oscDriver.CreateSnapshot(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if snap := resp.GetSnapshot(); snap.GetSnapshotId() != "snap-test" || snap.GetReadyToUse() {
+					t.Fatalf("Expected snapshot snap-test not ready to use, got %+v", snap)
+				}
+			},
+		},
+		{
+			name: "fail source volume not found",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					SourceVolumeId: "vol-deleted",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq("vol-deleted")).Return(cloud.Disk{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				_, err := oscDriver.CreateSnapshot(ctx, req)
+				expectErr(t, err, codes.NotFound)
+				if msg := status.Convert(err).Message(); !strings.Contains(msg, "vol-deleted") {
+					t.Fatalf("Expected the error to name the source volume, got %q", msg)
+				}
+			},
+		},
+		{
+			name: "fail existing snapshot in error",
+			testFunc: func(t *testing.T) {
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					SourceVolumeId: "vol-test",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{
+					SnapshotID:     "snap-test",
+					SourceVolumeID: req.SourceVolumeId,
+					CreationTime:   time.Now(),
+					State:          "error",
+				}, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+				_, err := oscDriver.CreateSnapshot(ctx, req)
+				expectErr(t, err, codes.ResourceExhausted)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestDeleteSnapshot(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success normal",
+			testFunc: func(t *testing.T) {
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.DeleteSnapshotRequest{
+					SnapshotId: "xxx",
+				}
+
+				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("xxx")).Return(true, nil)
+				if _, err := oscDriver.DeleteSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success not found",
+			testFunc: func(t *testing.T) {
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.DeleteSnapshotRequest{
+					SnapshotId: "xxx",
+				}
+
+				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("xxx")).Return(false, cloud.ErrNotFound)
+				if _, err := oscDriver.DeleteSnapshot(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success normal",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{}
+				mockCloudSnapshotsResponse := cloud.ListSnapshotsResponse{
+					Snapshots: []cloud.Snapshot{
+						{
+							SnapshotID:     "snapshot-1",
+							SourceVolumeID: "test-vol",
+							Size:           1,
+							CreationTime:   time.Now(),
+						},
+						{
+							SnapshotID:     "snapshot-2",
+							SourceVolumeID: "test-vol",
+							Size:           1,
+							CreationTime:   time.Now(),
+						},
+					},
+					NextToken: "",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(0)), gomock.Eq("")).Return(mockCloudSnapshotsResponse, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				resp, err := oscDriver.ListSnapshots(context.Background(), req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if len(resp.GetEntries()) != len(mockCloudSnapshotsResponse.Snapshots) {
+					t.Fatalf("Expected %d entries, got %d", len(mockCloudSnapshotsResponse.Snapshots), len(resp.GetEntries()))
+				}
+			},
+		},
+		{
+			name: "success hide orphaned snapshots",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{}
+				mockCloudSnapshotsResponse := cloud.ListSnapshotsResponse{
+					Snapshots: []cloud.Snapshot{
+						{
+							SnapshotID:     "snapshot-1",
+							SourceVolumeID: "test-vol",
+							Size:           1,
+							CreationTime:   time.Now(),
+						},
+						{
+							SnapshotID:     "snapshot-2",
+							SourceVolumeID: "deleted-vol",
+							Size:           1,
+							CreationTime:   time.Now(),
+						},
+						{
+							SnapshotID:     "snapshot-3",
+							SourceVolumeID: "test-vol",
+							Size:           1,
+							CreationTime:   time.Now(),
+						},
+					},
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(0)), gomock.Eq("")).Return(mockCloudSnapshotsResponse, nil)
+				mockCloud.EXPECT().GetDisksByIDs(gomock.Eq(ctx), gomock.Eq([]string{"test-vol", "deleted-vol"})).Return(map[string]cloud.Disk{"test-vol": {VolumeID: "test-vol"}}, nil).Times(1)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{hideOrphanedSnapshots: true},
+				}
+
+				resp, err := oscDriver.ListSnapshots(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				var snapshotIDs []string
+				for _, entry := range resp.GetEntries() {
+					snapshotIDs = append(snapshotIDs, entry.GetSnapshot().GetSnapshotId())
+				}
+				if expected := []string{"snapshot-1", "snapshot-3"}; !reflect.DeepEqual(snapshotIDs, expected) {
+					t.Fatalf("Expected snapshots %v, got %v", expected, snapshotIDs)
+				}
+			},
+		},
+		{
+			name: "success no snapshots",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{}
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(0)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				resp, err := oscDriver.ListSnapshots(context.Background(), req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(resp, &csi.ListSnapshotsResponse{}) {
+					t.Fatalf("Expected empty response, got %+v", resp)
+				}
+			},
+		},
+		{
+			name: "success snapshot ID",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{
+					SnapshotId: "snapshot-1",
+				}
+				mockCloudSnapshotsResponse := cloud.Snapshot{
+					SnapshotID:     "snapshot-1",
+					SourceVolumeID: "test-vol",
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-1")).Return(mockCloudSnapshotsResponse, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				resp, err := oscDriver.ListSnapshots(context.Background(), req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if len(resp.GetEntries()) != 1 {
+					t.Fatalf("Expected %d entry, got %d", 1, len(resp.GetEntries()))
+				}
+			},
+		},
+		{
+			name: "success snapshot ID not found",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{
+					SnapshotId: "snapshot-1",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-1")).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				resp, err := oscDriver.ListSnapshots(context.Background(), req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(resp, &csi.ListSnapshotsResponse{}) {
+					t.Fatalf("Expected empty response, got %+v", resp)
+				}
+			},
+		},
+		{
+			name: "fail snapshot ID multiple found",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{
+					SnapshotId: "snapshot-1",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-1")).Return(cloud.Snapshot{}, cloud.ErrMultiSnapshots)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				if _, err := oscDriver.ListSnapshots(context.Background(), req); err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.Internal {
+						t.Fatalf("Expected error code %d, got %d message %s", codes.Internal, srvErr.Code(), srvErr.Message())
+					}
+				} else {
+					t.Fatalf("Expected error code %d, got no error", codes.Internal)
+				}
+			},
+		},
+		{
+			name: "fail 0 < MaxEntries < 5",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{
+					MaxEntries: 4,
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(4)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{}, cloud.ErrInvalidMaxResults)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				if _, err := oscDriver.ListSnapshots(context.Background(), req); err != nil {
+					srvErr, ok := status.FromError(err)
+					if !ok {
+						t.Fatalf("Could not get error status code from error: %v", srvErr)
+					}
+					if srvErr.Code() != codes.InvalidArgument {
+						t.Fatalf("Expected error code %d, got %d message %s", codes.InvalidArgument, srvErr.Code(), srvErr.Message())
+					}
+				} else {
+					t.Fatalf("Expected error code %d, got no error", codes.InvalidArgument)
+				}
+			},
+		},
+		{
+			name: "fail invalid starting token",
+			testFunc: func(t *testing.T) {
+				req := &csi.ListSnapshotsRequest{
+					StartingToken: "token-invalid",
+				}
+
+				ctx := context.Background()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(0)), gomock.Eq("token-invalid")).Return(cloud.ListSnapshotsResponse{}, fmt.Errorf("%w: test error", cloud.ErrInvalidNextToken))
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				_, err := oscDriver.ListSnapshots(ctx, req)
+				expectErr(t, err, codes.Aborted)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestControllerPublishVolume(t *testing.T) {
+	stdVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		},
+	}
+	expDevicePath := "/dev/xvda"
+
 	testCases := []struct {
 		name     string
 		testFunc func(t *testing.T)
@@ -1321,492 +3362,571 @@ func TestCreateSnapshot(t *testing.T) {
 		{
 			name: "success normal",
 			testFunc: func(t *testing.T) {
-				req := &csi.CreateSnapshotRequest{
-					Name:           "test-snapshot",
-					Parameters:     nil,
-					SourceVolumeId: "vol-test",
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
 				}
-				expSnapshot := &csi.Snapshot{
-					ReadyToUse: true,
+				expResp := &csi.ControllerPublishVolumeResponse{
+					PublishContext: map[string]string{DevicePathKey: expDevicePath},
 				}
 
 				ctx := context.Background()
-				mockSnapshot := cloud.Snapshot{
-					SnapshotID:     fmt.Sprintf("snapshot-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()),
-					SourceVolumeID: req.SourceVolumeId,
-					Size:           1,
-					CreationTime:   time.Now(),
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("")).Return(expDevicePath, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				resp, err := oscDriver.ControllerPublishVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
+				}
+			},
+		},
+		{
+			name: "success marking the volume of the cluster as bound",
+			testFunc: func(t *testing.T) {
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+				}
+				disk := cloud.Disk{
+					VolumeID: "vol-test",
+					Tags:     map[string]string{cloud.ClusterIDTagKey: "cluster-test"},
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(disk, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("")).Return(expDevicePath, nil)
+				mockCloud.EXPECT().TagDisk(gomock.Eq(ctx), gomock.Eq("vol-test"), gomock.Eq(map[string]string{cloud.BoundTagKey: "true"})).Return(nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				if _, err := oscDriver.ControllerPublishVolume(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success with provider ID",
+			testFunc: func(t *testing.T) {
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           "aws:///eu-west-2a/" + expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+				}
+				expResp := &csi.ControllerPublishVolumeResponse{
+					PublishContext: map[string]string{DevicePathKey: expDevicePath},
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(expInstanceID)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(expInstanceID), gomock.Eq("")).Return(expDevicePath, nil)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				resp, err := oscDriver.ControllerPublishVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
+				}
+			},
+		},
+		{
+			name: "success with attach retries",
+			testFunc: func(t *testing.T) {
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil).Times(2)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(expInstanceID)).Return(nil, nil).Times(2)
+				var attachRetries []int
+				mockCloud.EXPECT().AttachDisk(gomock.Any(), gomock.Any(), gomock.Eq(expInstanceID), gomock.Eq("")).DoAndReturn(
+					func(ctx context.Context, volumeID, nodeID, deviceName string) (string, error) {
+						retries, ok := cloud.AttachRetries(ctx)
+						if !ok {
+							retries = -1
+						}
+						attachRetries = append(attachRetries, retries)
+						return expDevicePath, nil
+					}).Times(2)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				// The volume overrides the global number of retries
+				_, err := oscDriver.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+					VolumeContext:    map[string]string{AttachRetriesKey: "10"},
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				// The global number of retries is kept
+				_, err = oscDriver.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(attachRetries, []int{10, -1}) {
+					t.Fatalf("Expected the attach retries [10 -1], got %v", attachRetries)
+				}
+			},
+		},
+		{
+			name: "success with allowed node",
+			testFunc: func(t *testing.T) {
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+					VolumeContext:    map[string]string{AllowedNodesKey: "i-other, " + expInstanceID},
 				}
+
+				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Any()).Return(mockSnapshot, nil)
-				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("")).Return(expDevicePath, nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
-				resp, err := oscDriver.CreateSnapshot(context.Background(), req)
+
+				resp, err := oscDriver.ControllerPublishVolume(ctx, req)
 				if err != nil {
 					t.Fatalf("Unexpected error: %v", err)
 				}
-
-				if snap := resp.GetSnapshot(); snap == nil {
-					t.Fatalf("Expected snapshot %v, got nil", expSnapshot)
+				if devicePath := resp.GetPublishContext()[DevicePathKey]; devicePath != expDevicePath {
+					t.Fatalf("Expected device path %q, got %q", expDevicePath, devicePath)
 				}
 			},
 		},
 		{
-			name: "fail no name",
+			name: "fail node not allowed",
 			testFunc: func(t *testing.T) {
-				req := &csi.CreateSnapshotRequest{
-					Parameters:     nil,
-					SourceVolumeId: "vol-test",
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+					VolumeContext:    map[string]string{AllowedNodesKey: "i-node1,i-node2"},
 				}
 
+				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().AttachDisk(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
-				if _, err := oscDriver.CreateSnapshot(context.Background(), req); err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.InvalidArgument {
-						t.Fatalf("Expected error code %d, got %d message %s", codes.InvalidArgument, srvErr.Code(), srvErr.Message())
-					}
-				} else {
-					t.Fatalf("Expected error %v, got no error", codes.InvalidArgument)
-				}
+
+				_, err := oscDriver.ControllerPublishVolume(ctx, req)
+				expectErr(t, err, codes.FailedPrecondition)
 			},
 		},
 		{
-			name: "fail same name different volume ID",
+			name: "success with volume size",
 			testFunc: func(t *testing.T) {
-				req := &csi.CreateSnapshotRequest{
-					Name:           "test-snapshot",
-					Parameters:     nil,
-					SourceVolumeId: "vol-test",
-				}
-				extraReq := &csi.CreateSnapshotRequest{
-					Name:           "test-snapshot",
-					Parameters:     nil,
-					SourceVolumeId: "vol-xxx",
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
 				}
-				expSnapshot := &csi.Snapshot{
-					ReadyToUse: true,
+				expResp := &csi.ControllerPublishVolumeResponse{
+					PublishContext: map[string]string{
+						DevicePathKey: expDevicePath,
+						VolumeSizeKey: "10737418240",
+					},
 				}
 
 				ctx := context.Background()
-				mockSnapshot := cloud.Snapshot{
-					SnapshotID:     fmt.Sprintf("snapshot-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()),
-					SourceVolumeID: req.SourceVolumeId,
-					Size:           1,
-					CreationTime:   time.Now(),
-				}
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
-				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Any()).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{VolumeID: "vol-test", CapacityGiB: 10}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("")).Return(expDevicePath, nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
-				resp, err := oscDriver.CreateSnapshot(context.Background(), req)
+
+				resp, err := oscDriver.ControllerPublishVolume(ctx, req)
 				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.OK {
-						t.Fatalf("Expected error code %d, got %d message %s", codes.OK, srvErr.Code(), srvErr.Message())
-					}
 					t.Fatalf("Unexpected error: %v", err)
 				}
-				snap := resp.GetSnapshot()
-				if snap == nil {
-					t.Fatalf("Expected snapshot %v, got nil", expSnapshot)
-				}
 
-				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(extraReq.GetName())).Return(mockSnapshot, nil)
-				_, err = oscDriver.CreateSnapshot(ctx, extraReq)
-				if err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.AlreadyExists {
-						t.Fatalf("Expected error code %d, got %d message %s", codes.AlreadyExists, srvErr.Code(), srvErr.Message())
-					}
-				} else {
-					t.Fatalf("Expected error %v, got no error", codes.AlreadyExists)
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
 				}
 			},
 		},
 		{
-			name: "success same name same volume ID",
+			name: "success with explicit device name",
 			testFunc: func(t *testing.T) {
-				req := &csi.CreateSnapshotRequest{
-					Name:           "test-snapshot",
-					Parameters:     nil,
-					SourceVolumeId: "vol-test",
-				}
-				extraReq := &csi.CreateSnapshotRequest{
-					Name:           "test-snapshot",
-					Parameters:     nil,
-					SourceVolumeId: "vol-test",
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+					VolumeContext:    map[string]string{DeviceNameKey: "/dev/xvdc"},
 				}
-				expSnapshot := &csi.Snapshot{
-					ReadyToUse: true,
+				expResp := &csi.ControllerPublishVolumeResponse{
+					PublishContext: map[string]string{
+						DeviceNameKey: "/dev/xvdc",
+						DevicePathKey: "/dev/xvdc",
+					},
 				}
 
 				ctx := context.Background()
-				mockSnapshot := cloud.Snapshot{
-					SnapshotID:     fmt.Sprintf("snapshot-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()),
-					SourceVolumeID: req.SourceVolumeId,
-					Size:           1,
-					CreationTime:   time.Now(),
-				}
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(cloud.Snapshot{}, cloud.ErrNotFound)
-				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.SourceVolumeId), gomock.Any()).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("/dev/xvdc")).Return("/dev/xvdc", nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
-				resp, err := oscDriver.CreateSnapshot(context.Background(), req)
+
+				resp, err := oscDriver.ControllerPublishVolume(ctx, req)
 				if err != nil {
 					t.Fatalf("Unexpected error: %v", err)
 				}
-				snap := resp.GetSnapshot()
-				if snap == nil {
-					t.Fatalf("Expected snapshot %v, got nil", expSnapshot)
-				}
 
-				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(extraReq.GetName())).Return(mockSnapshot, nil)
-				_, err = oscDriver.CreateSnapshot(ctx, extraReq)
-				if err != nil {
-					t.Fatalf("Unexpected error: %v", err)
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
 				}
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
-	}
-}
-
-func TestDeleteSnapshot(t *testing.T) {
-	testCases := []struct {
-		name     string
-		testFunc func(t *testing.T)
-	}{
 		{
-			name: "success normal",
+			name: "success with mount profile",
 			testFunc: func(t *testing.T) {
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+					VolumeContext:    map[string]string{MountProfileKey: "database"},
+				}
+				expResp := &csi.ControllerPublishVolumeResponse{
+					PublishContext: map[string]string{
+						MountProfileKey:        "database",
+						MountProfileOptionsKey: "noatime,nodiratime",
+						DevicePathKey:          expDevicePath,
+					},
+				}
+
 				ctx := context.Background()
 
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
+
 				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("")).Return(expDevicePath, nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
+					mountProfiles: mountProfiles{"database": {"noatime", "nodiratime"}},
 				}
 
-				req := &csi.DeleteSnapshotRequest{
-					SnapshotId: "xxx",
+				resp, err := oscDriver.ControllerPublishVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
 				}
 
-				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("xxx")).Return(true, nil)
-				if _, err := oscDriver.DeleteSnapshot(ctx, req); err != nil {
-					t.Fatalf("Unexpected error: %v", err)
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
 				}
 			},
 		},
 		{
-			name: "success not found",
+			name: "fail unknown mount profile",
 			testFunc: func(t *testing.T) {
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+					VolumeContext:    map[string]string{MountProfileKey: "unknown"},
+				}
+
 				ctx := context.Background()
 
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
+
 				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
+					mountProfiles: mountProfiles{"database": {"noatime", "nodiratime"}},
 				}
 
-				req := &csi.DeleteSnapshotRequest{
-					SnapshotId: "xxx",
-				}
-
-				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("xxx")).Return(false, cloud.ErrNotFound)
-				if _, err := oscDriver.DeleteSnapshot(ctx, req); err != nil {
-					t.Fatalf("Unexpected error: %v", err)
-				}
+				_, err := oscDriver.ControllerPublishVolume(ctx, req)
+				expectErr(t, err, codes.InvalidArgument)
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
-	}
-}
-
-func TestListSnapshots(t *testing.T) {
-	testCases := []struct {
-		name     string
-		testFunc func(t *testing.T)
-	}{
 		{
-			name: "success normal",
+			name: "fail explicit device name in use",
 			testFunc: func(t *testing.T) {
-				req := &csi.ListSnapshotsRequest{}
-				mockCloudSnapshotsResponse := cloud.ListSnapshotsResponse{
-					Snapshots: []cloud.Snapshot{
-						{
-							SnapshotID:     "snapshot-1",
-							SourceVolumeID: "test-vol",
-							Size:           1,
-							CreationTime:   time.Now(),
-						},
-						{
-							SnapshotID:     "snapshot-2",
-							SourceVolumeID: "test-vol",
-							Size:           1,
-							CreationTime:   time.Now(),
-						},
-					},
-					NextToken: "",
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+					VolumeContext:    map[string]string{DeviceNameKey: "/dev/xvdb"},
 				}
 
 				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(0)), gomock.Eq("")).Return(mockCloudSnapshotsResponse, nil)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("/dev/xvdb")).Return("", fmt.Errorf("/dev/xvdb is assigned to volume vol-other: %w", cloud.ErrDeviceNameInUse))
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
 
-				resp, err := oscDriver.ListSnapshots(context.Background(), req)
-				if err != nil {
-					t.Fatalf("Unexpected error: %v", err)
-				}
-
-				if len(resp.GetEntries()) != len(mockCloudSnapshotsResponse.Snapshots) {
-					t.Fatalf("Expected %d entries, got %d", len(mockCloudSnapshotsResponse.Snapshots), len(resp.GetEntries()))
-				}
+				_, err := oscDriver.ControllerPublishVolume(ctx, req)
+				expectErr(t, err, codes.FailedPrecondition)
 			},
 		},
 		{
-			name: "success no snapshots",
+			name: "fail node at attachment limit",
 			testFunc: func(t *testing.T) {
-				req := &csi.ListSnapshotsRequest{}
+				t.Setenv("MAX_BSU_VOLUMES", "2")
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+				}
+
 				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(0)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return([]string{"vol-1", "vol-2"}, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
 
-				resp, err := oscDriver.ListSnapshots(context.Background(), req)
-				if err != nil {
-					t.Fatalf("Unexpected error: %v", err)
-				}
-
-				if !reflect.DeepEqual(resp, &csi.ListSnapshotsResponse{}) {
-					t.Fatalf("Expected empty response, got %+v", resp)
-				}
+				_, err := oscDriver.ControllerPublishVolume(ctx, req)
+				expectErr(t, err, codes.ResourceExhausted)
 			},
 		},
 		{
-			name: "success snapshot ID",
+			name: "fail node at the attachment limit of its CSINode",
 			testFunc: func(t *testing.T) {
-				req := &csi.ListSnapshotsRequest{
-					SnapshotId: "snapshot-1",
-				}
-				mockCloudSnapshotsResponse := cloud.Snapshot{
-					SnapshotID:     "snapshot-1",
-					SourceVolumeID: "test-vol",
-					Size:           1,
-					CreationTime:   time.Now(),
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
 				}
 
 				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-1")).Return(mockCloudSnapshotsResponse, nil)
-
-				oscDriver := controllerService{
-					cloud:         mockCloud,
-					driverOptions: &DriverOptions{},
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return([]string{"vol-1", "vol-2"}, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				// The node reports a lower limit than the default MAX_BSU_VOLUMES of the controller
+				count := int32(2)
+				csiNode := newCSINode("node1", expInstanceID)
+				csiNode.Spec.Drivers[0].Allocatable = &storagev1.VolumeNodeResources{Count: &count}
+				csiNodeInformer := newCSINodeInformer(fake.NewSimpleClientset(csiNode))
+				stopCh := make(chan struct{})
+				defer close(stopCh)
+				go csiNodeInformer.Run(stopCh)
+				if !cache.WaitForCacheSync(stopCh, csiNodeInformer.HasSynced) {
+					t.Fatalf("Could not sync the CSINodes")
 				}
-
-				resp, err := oscDriver.ListSnapshots(context.Background(), req)
-				if err != nil {
-					t.Fatalf("Unexpected error: %v", err)
+				oscDriver := controllerService{
+					cloud:           mockCloud,
+					driverOptions:   &DriverOptions{},
+					csiNodeInformer: csiNodeInformer,
 				}
 
-				if len(resp.GetEntries()) != 1 {
-					t.Fatalf("Expected %d entry, got %d", 1, len(resp.GetEntries()))
-				}
+				_, err := oscDriver.ControllerPublishVolume(ctx, req)
+				expectErr(t, err, codes.ResourceExhausted)
 			},
 		},
 		{
-			name: "success snapshot ID not found",
+			name: "success node at attachment limit with the volume already attached",
 			testFunc: func(t *testing.T) {
-				req := &csi.ListSnapshotsRequest{
-					SnapshotId: "snapshot-1",
+				t.Setenv("MAX_BSU_VOLUMES", "2")
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
 				}
 
 				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-1")).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return([]string{"vol-1", "vol-test"}, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(req.VolumeId), gomock.Eq(req.NodeId), gomock.Eq("")).Return(expDevicePath, nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
 
-				resp, err := oscDriver.ListSnapshots(context.Background(), req)
-				if err != nil {
+				if _, err := oscDriver.ControllerPublishVolume(ctx, req); err != nil {
 					t.Fatalf("Unexpected error: %v", err)
 				}
-
-				if !reflect.DeepEqual(resp, &csi.ListSnapshotsResponse{}) {
-					t.Fatalf("Expected empty response, got %+v", resp)
-				}
 			},
 		},
 		{
-			name: "fail snapshot ID multiple found",
+			name: "fail volume and node in different zones",
 			testFunc: func(t *testing.T) {
-				req := &csi.ListSnapshotsRequest{
-					SnapshotId: "snapshot-1",
+				req := &csi.ControllerPublishVolumeRequest{
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
 				}
 
 				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snapshot-1")).Return(cloud.Snapshot{}, cloud.ErrMultiSnapshots)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("")).Return("", fmt.Errorf("volume is in eu-west-2a: %w", cloud.ErrZoneMismatch))
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
 
-				if _, err := oscDriver.ListSnapshots(context.Background(), req); err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.Internal {
-						t.Fatalf("Expected error code %d, got %d message %s", codes.Internal, srvErr.Code(), srvErr.Message())
-					}
-				} else {
-					t.Fatalf("Expected error code %d, got no error", codes.Internal)
-				}
+				_, err := oscDriver.ControllerPublishVolume(ctx, req)
+				expectErr(t, err, codes.FailedPrecondition)
 			},
 		},
 		{
-			name: "fail 0 < MaxEntries < 5",
+			name: "success when resource is not found",
 			testFunc: func(t *testing.T) {
-				req := &csi.ListSnapshotsRequest{
-					MaxEntries: 4,
+				req := &csi.ControllerUnpublishVolumeRequest{
+					NodeId:   expInstanceID,
+					VolumeId: "vol-test",
 				}
+				expResp := &csi.ControllerUnpublishVolumeResponse{}
 
 				ctx := context.Background()
+
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
+
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().ListSnapshots(gomock.Eq(ctx), gomock.Eq(""), gomock.Eq(int64(4)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{}, cloud.ErrInvalidMaxResults)
+				mockCloud.EXPECT().DetachDisk(gomock.Eq(ctx), req.VolumeId, req.NodeId, true).Return(cloud.ErrNotFound)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
 					driverOptions: &DriverOptions{},
 				}
+				resp, err := oscDriver.ControllerUnpublishVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
 
-				if _, err := oscDriver.ListSnapshots(context.Background(), req); err != nil {
-					srvErr, ok := status.FromError(err)
-					if !ok {
-						t.Fatalf("Could not get error status code from error: %v", srvErr)
-					}
-					if srvErr.Code() != codes.InvalidArgument {
-						t.Fatalf("Expected error code %d, got %d message %s", codes.InvalidArgument, srvErr.Code(), srvErr.Message())
-					}
-				} else {
-					t.Fatalf("Expected error code %d, got no error", codes.InvalidArgument)
+				if !reflect.DeepEqual(resp, expResp) {
+					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
 				}
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
-	}
-}
-
-func TestControllerPublishVolume(t *testing.T) {
-	stdVolCap := &csi.VolumeCapability{
-		AccessType: &csi.VolumeCapability_Mount{
-			Mount: &csi.VolumeCapability_MountVolume{},
-		},
-		AccessMode: &csi.VolumeCapability_AccessMode{
-			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
-		},
-	}
-	expDevicePath := "/dev/xvda"
-
-	testCases := []struct {
-		name     string
-		testFunc func(t *testing.T)
-	}{
 		{
-			name: "success normal",
+			name: "success skip detach wait on stopped node",
 			testFunc: func(t *testing.T) {
-				req := &csi.ControllerPublishVolumeRequest{
-					NodeId:           expInstanceID,
-					VolumeCapability: stdVolCap,
-					VolumeId:         "vol-test",
-				}
-				expResp := &csi.ControllerPublishVolumeResponse{
-					PublishContext: map[string]string{DevicePathKey: expDevicePath},
+				req := &csi.ControllerUnpublishVolumeRequest{
+					NodeId:   expInstanceID,
+					VolumeId: "vol-test",
 				}
+				expResp := &csi.ControllerUnpublishVolumeResponse{}
 
 				ctx := context.Background()
 
@@ -1814,16 +3934,14 @@ func TestControllerPublishVolume(t *testing.T) {
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().IsExistInstance(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(true)
-				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
-				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId)).Return(expDevicePath, nil)
+				mockCloud.EXPECT().IsInstanceStopped(gomock.Eq(ctx), req.NodeId).Return(true, nil)
+				mockCloud.EXPECT().DetachDisk(gomock.Eq(ctx), req.VolumeId, req.NodeId, false).Return(nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
-					driverOptions: &DriverOptions{},
+					driverOptions: &DriverOptions{skipDetachStoppedNodes: true},
 				}
-
-				resp, err := oscDriver.ControllerPublishVolume(ctx, req)
+				resp, err := oscDriver.ControllerUnpublishVolume(ctx, req)
 				if err != nil {
 					t.Fatalf("Unexpected error: %v", err)
 				}
@@ -1834,13 +3952,12 @@ func TestControllerPublishVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "success when resource is not found",
+			name: "success detach on running node",
 			testFunc: func(t *testing.T) {
 				req := &csi.ControllerUnpublishVolumeRequest{
 					NodeId:   expInstanceID,
 					VolumeId: "vol-test",
 				}
-				expResp := &csi.ControllerUnpublishVolumeResponse{}
 
 				ctx := context.Background()
 
@@ -1848,16 +3965,15 @@ func TestControllerPublishVolume(t *testing.T) {
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().DetachDisk(gomock.Eq(ctx), req.VolumeId, req.NodeId).Return(cloud.ErrNotFound)
+				mockCloud.EXPECT().IsInstanceStopped(gomock.Eq(ctx), req.NodeId).Return(false, nil)
+				mockCloud.EXPECT().DetachDisk(gomock.Eq(ctx), req.VolumeId, req.NodeId, true).Return(nil)
 
-				oscDriver := controllerService{cloud: mockCloud}
-				resp, err := oscDriver.ControllerUnpublishVolume(ctx, req)
-				if err != nil {
-					t.Fatalf("Unexpected error: %v", err)
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{skipDetachStoppedNodes: true},
 				}
-
-				if !reflect.DeepEqual(resp, expResp) {
-					t.Fatalf("Expected resp to be %+v, got: %+v", expResp, resp)
+				if _, err := oscDriver.ControllerUnpublishVolume(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
 				}
 			},
 		},
@@ -2009,7 +4125,7 @@ func TestControllerPublishVolume(t *testing.T) {
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().IsExistInstance(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(false)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, cloud.ErrNotFound)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
@@ -2044,7 +4160,7 @@ func TestControllerPublishVolume(t *testing.T) {
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().IsExistInstance(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(true)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
 				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, cloud.ErrNotFound)
 
 				oscDriver := controllerService{
@@ -2065,6 +4181,33 @@ func TestControllerPublishVolume(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "fail multiple volumes with the same ID",
+			testFunc: func(t *testing.T) {
+				req := &csi.ControllerPublishVolumeRequest{
+					VolumeId:         "vol-test",
+					NodeId:           expInstanceID,
+					VolumeCapability: stdVolCap,
+				}
+
+				ctx := context.Background()
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, cloud.ErrMultiVolumes)
+
+				oscDriver := controllerService{
+					cloud:         mockCloud,
+					driverOptions: &DriverOptions{},
+				}
+
+				_, err := oscDriver.ControllerPublishVolume(ctx, req)
+				expectErr(t, err, codes.Internal)
+			},
+		},
 		{
 			name: "fail attach disk with already exists error",
 			testFunc: func(t *testing.T) {
@@ -2080,9 +4223,9 @@ func TestControllerPublishVolume(t *testing.T) {
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().IsExistInstance(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(true)
 				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
-				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId)).Return("", cloud.ErrAlreadyExists)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("")).Return("", cloud.ErrAlreadyExists)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
@@ -2132,9 +4275,9 @@ func TestControllerPublishVolume(t *testing.T) {
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().IsExistInstance(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(true)
 				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil)
-				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId)).Return(expDevicePath, nil)
+				mockCloud.EXPECT().GetAttachedDisks(gomock.Eq(ctx), gomock.Eq(req.NodeId)).Return(nil, nil)
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Any(), gomock.Eq(req.NodeId), gomock.Eq("")).Return(expDevicePath, nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
@@ -2158,6 +4301,74 @@ func TestControllerPublishVolume(t *testing.T) {
 	}
 }
 
+func TestControllerPublishVolumeAttachLimit(t *testing.T) {
+	stdVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		},
+	}
+	ctx := context.Background()
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	var mux sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().GetDiskByID(gomock.Any(), gomock.Any()).Return(cloud.Disk{}, nil).AnyTimes()
+	mockCloud.EXPECT().GetAttachedDisks(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockCloud.EXPECT().AttachDisk(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Eq("")).DoAndReturn(
+		func(ctx context.Context, volumeID, nodeID, deviceName string) (string, error) {
+			mux.Lock()
+			running[nodeID]++
+			if running[nodeID] > maxRunning[nodeID] {
+				maxRunning[nodeID] = running[nodeID]
+			}
+			mux.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mux.Lock()
+			running[nodeID]--
+			mux.Unlock()
+			return "/dev/xvdb", nil
+		}).Times(6)
+
+	oscDriver := controllerService{
+		cloud:         mockCloud,
+		driverOptions: &DriverOptions{maxAttachesPerNode: 1},
+		attachLimiter: internal.NewKeyedLimiter(1),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		nodeID := fmt.Sprintf("i-%d", i%2)
+		req := &csi.ControllerPublishVolumeRequest{
+			NodeId:           nodeID,
+			VolumeCapability: stdVolCap,
+			VolumeId:         fmt.Sprintf("vol-%d", i),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := oscDriver.ControllerPublishVolume(ctx, req); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, nodeID := range []string{"i-0", "i-1"} {
+		if maxRunning[nodeID] != 1 {
+			t.Fatalf("Expected at most 1 concurrent attach to node %s, got %d", nodeID, maxRunning[nodeID])
+		}
+	}
+}
+
 func TestControllerUnpublishVolume(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -2178,7 +4389,7 @@ func TestControllerUnpublishVolume(t *testing.T) {
 				defer mockCtl.Finish()
 
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockCloud.EXPECT().DetachDisk(gomock.Eq(ctx), req.VolumeId, req.NodeId).Return(nil)
+				mockCloud.EXPECT().DetachDisk(gomock.Eq(ctx), req.VolumeId, req.NodeId, true).Return(nil)
 
 				oscDriver := controllerService{
 					cloud:         mockCloud,
@@ -2263,3 +4474,208 @@ func TestControllerUnpublishVolume(t *testing.T) {
 		t.Run(tc.name, tc.testFunc)
 	}
 }
+
+func TestControllerExpandVolume(t *testing.T) {
+	volumeID := "vol-test"
+	testCases := []struct {
+		name             string
+		requiredBytes    int64
+		volCap           *csi.VolumeCapability
+		setup            func(ctx context.Context, mockCloud *mocks.MockCloud)
+		expCapacityBytes int64
+		expNodeExpansion bool
+		expErr           codes.Code
+	}{
+		{
+			name:          "success: expand the volume",
+			requiredBytes: util.GiBToBytes(20),
+			setup: func(ctx context.Context, mockCloud *mocks.MockCloud) {
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(volumeID)).Return(cloud.Disk{VolumeID: volumeID, CapacityGiB: 10}, nil)
+				mockCloud.EXPECT().ResizeDisk(gomock.Eq(ctx), gomock.Eq(volumeID), gomock.Eq(util.GiBToBytes(20))).Return(int64(20), nil)
+			},
+			expCapacityBytes: util.GiBToBytes(20),
+			expNodeExpansion: true,
+		},
+		{
+			name:          "success: skip the resize when the volume already has the requested size",
+			requiredBytes: util.GiBToBytes(10),
+			setup: func(ctx context.Context, mockCloud *mocks.MockCloud) {
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(volumeID)).Return(cloud.Disk{VolumeID: volumeID, CapacityGiB: 10}, nil)
+			},
+			expCapacityBytes: util.GiBToBytes(10),
+			expNodeExpansion: true,
+		},
+		{
+			name:          "success: no-op when the block volume already has the requested size",
+			requiredBytes: util.GiBToBytes(10),
+			volCap: &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}},
+			},
+			setup: func(ctx context.Context, mockCloud *mocks.MockCloud) {
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(volumeID)).Return(cloud.Disk{VolumeID: volumeID, CapacityGiB: 10}, nil)
+			},
+			expCapacityBytes: util.GiBToBytes(10),
+			expNodeExpansion: false,
+		},
+		{
+			name:          "fail: volume not found",
+			requiredBytes: util.GiBToBytes(10),
+			setup: func(ctx context.Context, mockCloud *mocks.MockCloud) {
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(volumeID)).Return(cloud.Disk{}, cloud.ErrNotFound)
+			},
+			expErr: codes.NotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			tc.setup(ctx, mockCloud)
+
+			oscDriver := controllerService{
+				cloud:         mockCloud,
+				driverOptions: &DriverOptions{},
+			}
+
+			resp, err := oscDriver.ControllerExpandVolume(ctx, &csi.ControllerExpandVolumeRequest{
+				VolumeId:         volumeID,
+				CapacityRange:    &csi.CapacityRange{RequiredBytes: tc.requiredBytes},
+				VolumeCapability: tc.volCap,
+			})
+			if tc.expErr != codes.OK {
+				expectErr(t, err, tc.expErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resp.GetCapacityBytes() != tc.expCapacityBytes {
+				t.Fatalf("Expected capacity %d, got %d", tc.expCapacityBytes, resp.GetCapacityBytes())
+			}
+			if resp.GetNodeExpansionRequired() != tc.expNodeExpansion {
+				t.Fatalf("Expected node expansion required %t, got %t", tc.expNodeExpansion, resp.GetNodeExpansionRequired())
+			}
+		})
+	}
+}
+
+func TestControllerExpandVolumeRetryAfterTimeout(t *testing.T) {
+	volumeID := "vol-test"
+	ctx := context.Background()
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	// The first call times out after the resize has been requested, the retry finds the volume resized
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	gomock.InOrder(
+		mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(volumeID)).Return(cloud.Disk{VolumeID: volumeID, CapacityGiB: 10}, nil),
+		mockCloud.EXPECT().ResizeDisk(gomock.Eq(ctx), gomock.Eq(volumeID), gomock.Eq(util.GiBToBytes(20))).Return(int64(0), context.DeadlineExceeded),
+		mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(volumeID)).Return(cloud.Disk{VolumeID: volumeID, CapacityGiB: 20}, nil),
+	)
+
+	oscDriver := controllerService{
+		cloud:         mockCloud,
+		driverOptions: &DriverOptions{},
+	}
+	req := &csi.ControllerExpandVolumeRequest{
+		VolumeId:      volumeID,
+		CapacityRange: &csi.CapacityRange{RequiredBytes: util.GiBToBytes(20)},
+	}
+
+	_, err := oscDriver.ControllerExpandVolume(ctx, req)
+	expectErr(t, err, codes.Internal)
+
+	resp, err := oscDriver.ControllerExpandVolume(ctx, req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.GetCapacityBytes() != util.GiBToBytes(20) {
+		t.Fatalf("Expected capacity %d, got %d", util.GiBToBytes(20), resp.GetCapacityBytes())
+	}
+	if !resp.GetNodeExpansionRequired() {
+		t.Fatal("Expected node expansion to be required after the retry")
+	}
+}
+
+func TestControllerGetCapabilities(t *testing.T) {
+	testCases := []struct {
+		name                string
+		disableSnapshots    bool
+		enableVolumeCloning bool
+		expSnapshotCaps     bool
+		expCloneCap         bool
+	}{
+		{
+			name:            "success normal",
+			expSnapshotCaps: true,
+		},
+		{
+			name:             "success snapshots disabled",
+			disableSnapshots: true,
+			expSnapshotCaps:  false,
+		},
+		{
+			name:                "success volume cloning enabled",
+			enableVolumeCloning: true,
+			expSnapshotCaps:     true,
+			expCloneCap:         true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oscDriver := controllerService{
+				driverOptions: &DriverOptions{
+					disableSnapshots:    tc.disableSnapshots,
+					enableVolumeCloning: tc.enableVolumeCloning,
+				},
+			}
+
+			resp, err := oscDriver.ControllerGetCapabilities(context.Background(), &csi.ControllerGetCapabilitiesRequest{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			hasSnapshotCaps := false
+			hasCloneCap := false
+			for _, cap := range resp.GetCapabilities() {
+				if isSnapshotCapability(cap.GetRpc().GetType()) {
+					hasSnapshotCaps = true
+				}
+				if cap.GetRpc().GetType() == csi.ControllerServiceCapability_RPC_CLONE_VOLUME {
+					hasCloneCap = true
+				}
+			}
+			assert.Equal(t, tc.expSnapshotCaps, hasSnapshotCaps)
+			assert.Equal(t, tc.expCloneCap, hasCloneCap)
+		})
+	}
+}
+
+func TestSnapshotsDisabled(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	oscDriver := controllerService{
+		cloud: mocks.NewMockCloud(mockCtl),
+		driverOptions: &DriverOptions{
+			disableSnapshots: true,
+		},
+	}
+	ctx := context.Background()
+
+	_, err := oscDriver.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{Name: "test-snapshot", SourceVolumeId: "vol-test"})
+	expectErr(t, err, codes.Unimplemented)
+
+	_, err = oscDriver.DeleteSnapshot(ctx, &csi.DeleteSnapshotRequest{SnapshotId: "snap-test"})
+	expectErr(t, err, codes.Unimplemented)
+
+	_, err = oscDriver.ListSnapshots(ctx, &csi.ListSnapshotsRequest{})
+	expectErr(t, err, codes.Unimplemented)
+}
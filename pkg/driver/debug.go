@@ -0,0 +1,161 @@
+package driver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"k8s.io/component-base/metrics/legacyregistry"
+	klog "k8s.io/klog/v2"
+)
+
+// newDebugHandler returns the handler of the debug endpoint.
+// /debug/devices returns the device names assigned by the device manager, as {"nodeID": {"deviceName": "volumeID"}}.
+// /debug/inventory returns the volumes and the snapshots created by the driver, as an InventoryReport.
+// /debug/config returns the options of the driver, with the secrets redacted.
+func (d *Driver) newDebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/devices", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.controllerService.cloud.GetDeviceAllocations()); err != nil {
+			klog.Errorf("Could not write device allocations: %v", err)
+		}
+	})
+	mux.HandleFunc("/debug/inventory", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		report, err := d.controllerService.inventory(r.Context())
+		if err != nil {
+			klog.Errorf("Could not build the inventory: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			klog.Errorf("Could not write the inventory: %v", err)
+		}
+	})
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.options.redacted()); err != nil {
+			klog.Errorf("Could not write driver options: %v", err)
+		}
+	})
+	return mux
+}
+
+// newCascadeDeleteHandler returns the handler of the cascade delete endpoint, served apart from the debug endpoint.
+// DELETE /volumes/<volumeID> deletes the snapshots of the volume then the volume, as a CascadeDeleteResult.
+func (d *Driver) newCascadeDeleteHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /volumes/{volumeID}", func(w http.ResponseWriter, r *http.Request) {
+		result := d.controllerService.deleteVolumeWithSnapshots(r.Context(), r.PathValue("volumeID"))
+		w.Header().Set("Content-Type", "application/json")
+		if result.Error != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			klog.Errorf("Could not write cascade delete result: %v", err)
+		}
+	})
+	return mux
+}
+
+// newMetricsHandler returns the handler of the metrics endpoint.
+// /metrics returns the metrics of the driver, such as attachment_duration_seconds, in the Prometheus format.
+func newMetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", legacyregistry.Handler())
+	return mux
+}
+
+// redactedValue replaces the secrets in the redacted view of the options.
+const redactedValue = "REDACTED"
+
+// redacted returns the options as a JSON-friendly view, without the credentials which may be part of them.
+func (o *DriverOptions) redacted() map[string]interface{} {
+	return map[string]interface{}{
+		"endpoint":                   o.endpoint,
+		"debugEndpoint":              o.debugEndpoint,
+		"metricsEndpoint":            o.metricsEndpoint,
+		"mode":                       o.mode,
+		"requireEncryption":          o.requireEncryption,
+		"shutdownDrainTimeout":       o.shutdownDrainTimeout.String(),
+		"extraVolumeTags":            o.extraVolumeTags,
+		"extraSnapshotTags":          o.extraSnapshotTags,
+		"inheritSnapshotTags":        o.inheritSnapshotTags,
+		"defaultVolumeParams":        o.defaultVolumeParams,
+		"zoneVolumeTypes":            o.zoneVolumeTypes,
+		"clusterID":                  o.clusterID,
+		"volumeNamePrefix":           o.volumeNamePrefix,
+		"devicePathPollInterval":     o.devicePathPollInterval.String(),
+		"devicePathTimeout":          o.devicePathTimeout.String(),
+		"excludeReservedBlocks":      o.excludeReservedBlocks,
+		"secretProviderURL":          redactURL(o.secretProviderURL),
+		"fsGroupPolicy":              o.fsGroupPolicy,
+		"luksOpenRetries":            o.luksOpenRetries,
+		"luksOpenRetryDelay":         o.luksOpenRetryDelay.String(),
+		"disableStaging":             o.disableStaging,
+		"allowFsTypeMismatch":        o.allowFsTypeMismatch,
+		"strictDeviceSizeCheck":      o.strictDeviceSizeCheck,
+		"reportNodeTopology":         o.reportNodeTopology,
+		"reportRegionTopology":       o.reportRegionTopology,
+		"inFlightMaxAge":             o.inFlightMaxAge.String(),
+		"mountByUUID":                o.mountByUUID,
+		"formatTimeout":              o.formatTimeout.String(),
+		"fsResizeTolerance":          o.fsResizeTolerance,
+		"disableSnapshots":           o.disableSnapshots,
+		"hideOrphanedSnapshots":      o.hideOrphanedSnapshots,
+		"retryBudget":                o.retryBudget,
+		"oapiTimeout":                o.oapiTimeout.String(),
+		"oapiMaxRetries":             o.oapiMaxRetries,
+		"oscConfigFile":              o.oscConfigFile,
+		"oscProfile":                 o.oscProfile,
+		"detachSettleDuration":       o.detachSettleDuration.String(),
+		"detachBusyRetries":          o.detachBusyRetries,
+		"quotaRetryInterval":         o.quotaRetryInterval.String(),
+		"maxVolumeSize":              o.maxVolumeSize,
+		"enableVolumeCloning":        o.enableVolumeCloning,
+		"mountProfilesFile":          o.mountProfilesFile,
+		"performanceClassesFile":     o.performanceClassesFile,
+		"skipDetachStoppedNodes":     o.skipDetachStoppedNodes,
+		"maxAttachesPerNode":         o.maxAttachesPerNode,
+		"enableSnapshotScheduler":    o.enableSnapshotScheduler,
+		"snapshotScheduleInterval":   o.snapshotScheduleInterval.String(),
+		"snapshotScheduleRetention":  o.snapshotScheduleRetention,
+		"enableVolumeReaper":         o.enableVolumeReaper,
+		"volumeReaperGracePeriod":    o.volumeReaperGracePeriod.String(),
+		"volumeReaperDryRun":         o.volumeReaperDryRun,
+		"enableAttachmentReconciler": o.enableAttachmentReconciler,
+		"attachmentReconcilerDryRun": o.attachmentReconcilerDryRun,
+		"cascadeDeleteEndpoint":      o.cascadeDeleteEndpoint,
+	}
+}
+
+// redactURL hides the user info and the query of rawURL, which may carry credentials.
+func redactURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedValue
+	}
+	if u.User != nil {
+		u.User = url.User(redactedValue)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redactedValue
+	}
+	return u.String()
+}
@@ -21,10 +21,16 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/util"
 	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/util/wait"
 	klog "k8s.io/klog/v2"
 )
 
@@ -44,6 +50,11 @@ const (
 	DriverName     = "bsu.csi.outscale.com"
 	TopologyKey    = "topology." + DriverName + "/zone"
 	TopologyK8sKey = "topology.kubernetes.io/zone"
+	// TopologyRegionKey is only reported by the nodes started with --report-region-topology, the volumes are constrained by zone
+	TopologyRegionKey = "topology." + DriverName + "/region"
+	// TopologyNodeKey is only reported by the nodes started with --report-node-topology,
+	// so that CreateVolume knows the node selected by the scheduler
+	TopologyNodeKey = "topology." + DriverName + "/node"
 )
 
 type Driver struct {
@@ -55,17 +66,87 @@ type Driver struct {
 }
 
 type DriverOptions struct {
-	endpoint        string
-	extraVolumeTags map[string]string
-	mode            Mode
+	endpoint               string
+	debugEndpoint          string
+	metricsEndpoint        string
+	requireEncryption      bool
+	shutdownDrainTimeout   time.Duration
+	extraVolumeTags        map[string]string
+	extraSnapshotTags      map[string]string
+	inheritSnapshotTags    []string
+	defaultVolumeParams    map[string]string
+	zoneVolumeTypes        map[string]string
+	clusterID              string
+	volumeNamePrefix       string
+	mode                   Mode
+	devicePathPollInterval time.Duration
+	devicePathTimeout      time.Duration
+	excludeReservedBlocks  bool
+	secretProviderURL      string
+	fsGroupPolicy          string
+	luksOpenRetries        int
+	luksOpenRetryDelay     time.Duration
+	disableStaging         bool
+	allowFsTypeMismatch    bool
+	strictDeviceSizeCheck  bool
+	reportNodeTopology     bool
+	reportRegionTopology   bool
+	inFlightMaxAge         time.Duration
+	mountByUUID            bool
+	formatTimeout          time.Duration
+	fsResizeTolerance      int64
+	disableSnapshots       bool
+	hideOrphanedSnapshots  bool
+	retryBudget            int
+	oapiTimeout            time.Duration
+	oapiMaxRetries         int
+	oscConfigFile          string
+	oscProfile             string
+	detachSettleDuration   time.Duration
+	detachBusyRetries      int
+	quotaRetryInterval     time.Duration
+	maxVolumeSize          int64
+	enableVolumeCloning    bool
+	mountProfilesFile      string
+	performanceClassesFile string
+	skipDetachStoppedNodes bool
+	maxAttachesPerNode     int
+	auditLogger            AuditLogger
+
+	enableSnapshotScheduler   bool
+	snapshotScheduleInterval  time.Duration
+	snapshotScheduleRetention int
+
+	enableVolumeReaper      bool
+	volumeReaperGracePeriod time.Duration
+	volumeReaperDryRun      bool
+
+	enableAttachmentReconciler bool
+	attachmentReconcilerDryRun bool
+
+	cascadeDeleteEndpoint string
 }
 
 func NewDriver(options ...func(*DriverOptions)) (*Driver, error) {
 	klog.Infof("Driver: %v Version: %v", DriverName, util.GetVersion().DriverVersion)
 
 	driverOptions := DriverOptions{
-		endpoint: DefaultCSIEndpoint,
-		mode:     AllMode,
+		endpoint:               DefaultCSIEndpoint,
+		shutdownDrainTimeout:   DefaultShutdownDrainTimeout,
+		mode:                   AllMode,
+		devicePathPollInterval: DefaultDevicePathPollInterval,
+		devicePathTimeout:      DefaultDevicePathTimeout,
+		luksOpenRetries:        DefaultLuksOpenRetries,
+		luksOpenRetryDelay:     DefaultLuksOpenRetryDelay,
+		auditLogger:            klogAuditLogger{},
+
+		snapshotScheduleInterval:  DefaultSnapshotScheduleInterval,
+		snapshotScheduleRetention: DefaultSnapshotScheduleRetention,
+
+		volumeReaperGracePeriod: DefaultVolumeReaperGracePeriod,
+		volumeReaperDryRun:      true,
+
+		attachmentReconcilerDryRun: true,
 	}
 	for _, option := range options {
 		option(&driverOptions)
@@ -83,10 +164,10 @@ func NewDriver(options ...func(*DriverOptions)) (*Driver, error) {
 	case ControllerMode:
 		driver.controllerService = newControllerService(&driverOptions)
 	case NodeMode:
-		driver.nodeService = newNodeService()
+		driver.nodeService = newNodeService(&driverOptions)
 	case AllMode:
 		driver.controllerService = newControllerService(&driverOptions)
-		driver.nodeService = newNodeService()
+		driver.nodeService = newNodeService(&driverOptions)
 	default:
 		return nil, fmt.Errorf("unknown mode: %s", driverOptions.mode)
 	}
@@ -115,7 +196,7 @@ func (d *Driver) Run() error {
 		return resp, err
 	}
 	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(logErr),
+		grpc.ChainUnaryInterceptor(logErr, auditInterceptor(d.options.auditLogger)),
 	}
 	d.srv = grpc.NewServer(opts...)
 
@@ -124,15 +205,72 @@ func (d *Driver) Run() error {
 	switch d.options.mode {
 	case ControllerMode:
 		csi.RegisterControllerServer(d.srv, d)
+		csi.RegisterGroupControllerServer(d.srv, d)
 	case NodeMode:
 		csi.RegisterNodeServer(d.srv, d)
 	case AllMode:
 		csi.RegisterControllerServer(d.srv, d)
+		csi.RegisterGroupControllerServer(d.srv, d)
 		csi.RegisterNodeServer(d.srv, d)
 	default:
 		return fmt.Errorf("unknown mode: %s", d.options.mode)
 	}
 
+	if d.snapshotScheduler != nil {
+		go d.snapshotScheduler.Run(context.Background())
+	}
+
+	if d.volumeReaper != nil {
+		go d.volumeReaper.Run(context.Background())
+	}
+
+	if d.attachmentReconciler != nil {
+		go d.attachmentReconciler.Run(context.Background())
+	}
+
+	if d.csiNodeInformer != nil {
+		go d.csiNodeInformer.Run(wait.NeverStop)
+	}
+
+	if d.nodeService.inFlight != nil && d.options.inFlightMaxAge > 0 {
+		go d.nodeService.inFlight.RunJanitor(context.Background(), d.options.inFlightMaxAge, d.options.inFlightMaxAge/2)
+	}
+
+	if d.options.debugEndpoint != "" && d.controllerService.cloud != nil {
+		go func() {
+			klog.Infof("Listening for debug requests on address: %s", d.options.debugEndpoint)
+			if err := http.ListenAndServe(d.options.debugEndpoint, d.newDebugHandler()); err != nil {
+				klog.Errorf("Debug endpoint stopped: %v", err)
+			}
+		}()
+	}
+
+	if d.options.metricsEndpoint != "" {
+		go func() {
+			klog.Infof("Listening for metrics requests on address: %s", d.options.metricsEndpoint)
+			if err := http.ListenAndServe(d.options.metricsEndpoint, newMetricsHandler()); err != nil {
+				klog.Errorf("Metrics endpoint stopped: %v", err)
+			}
+		}()
+	}
+
+	if d.options.cascadeDeleteEndpoint != "" && d.controllerService.cloud != nil {
+		go func() {
+			klog.Infof("Listening for cascade delete requests on address: %s", d.options.cascadeDeleteEndpoint)
+			if err := http.ListenAndServe(d.options.cascadeDeleteEndpoint, d.newCascadeDeleteHandler()); err != nil {
+				klog.Errorf("Cascade delete endpoint stopped: %v", err)
+			}
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-signals
+		klog.Infof("Received signal %v, draining the calls in progress", sig)
+		d.Shutdown(d.options.shutdownDrainTimeout)
+	}()
+
 	klog.Infof("Listening for connections on address: %#v", listener.Addr())
 	return d.srv.Serve(listener)
 }
@@ -142,20 +280,389 @@ func (d *Driver) Stop() {
 	d.srv.Stop()
 }
 
+// Shutdown rejects the new calls and waits for the calls in progress to complete, then stops the server.
+// The calls still in progress after timeout are cancelled.
+func (d *Driver) Shutdown(timeout time.Duration) {
+	drained := make(chan struct{})
+	go func() {
+		d.srv.GracefulStop()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		klog.Infof("Server stopped after draining the calls in progress")
+	case <-time.After(timeout):
+		klog.Warningf("Calls still in progress after %v, stopping the server", timeout)
+		d.Stop()
+		<-drained
+	}
+}
+
 func WithEndpoint(endpoint string) func(*DriverOptions) {
 	return func(o *DriverOptions) {
 		o.endpoint = endpoint
 	}
 }
 
+func WithDebugEndpoint(debugEndpoint string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.debugEndpoint = debugEndpoint
+	}
+}
+
+// WithMetricsEndpoint sets the address of the HTTP endpoint serving the metrics, disabled when empty.
+func WithMetricsEndpoint(metricsEndpoint string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.metricsEndpoint = metricsEndpoint
+	}
+}
+
+// WithShutdownDrainTimeout sets the maximum time to wait for the calls in progress on SIGTERM.
+func WithShutdownDrainTimeout(timeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.shutdownDrainTimeout = timeout
+	}
+}
+
+// WithRequireEncryption rejects the creation and the staging of the volumes which are not encrypted.
+func WithRequireEncryption(required bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.requireEncryption = required
+	}
+}
+
 func WithExtraVolumeTags(extraVolumeTags map[string]string) func(*DriverOptions) {
 	return func(o *DriverOptions) {
 		o.extraVolumeTags = extraVolumeTags
 	}
 }
 
+func WithExtraSnapshotTags(extraSnapshotTags map[string]string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.extraSnapshotTags = extraSnapshotTags
+	}
+}
+
+// WithInheritSnapshotTags sets the keys of the tags of the source volume copied onto its snapshots.
+func WithInheritSnapshotTags(keys []string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.inheritSnapshotTags = keys
+	}
+}
+
+// WithDefaultVolumeParameters sets the parameters merged under the parameters of each CreateVolume request.
+func WithDefaultVolumeParameters(parameters map[string]string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.defaultVolumeParams = parameters
+	}
+}
+
+// WithZoneVolumeTypes sets the default volume type of each availability zone, used when the
+// StorageClass does not set a type.
+func WithZoneVolumeTypes(types map[string]string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.zoneVolumeTypes = types
+	}
+}
+
+func WithClusterID(clusterID string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.clusterID = clusterID
+	}
+}
+
+// WithVolumeNamePrefix prepends prefix to the name of the volumes in their name tag.
+func WithVolumeNamePrefix(prefix string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.volumeNamePrefix = prefix
+	}
+}
+
 func WithMode(mode Mode) func(*DriverOptions) {
 	return func(o *DriverOptions) {
 		o.mode = mode
 	}
 }
+
+func WithDevicePathPollInterval(interval time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.devicePathPollInterval = interval
+	}
+}
+
+func WithDevicePathTimeout(timeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.devicePathTimeout = timeout
+	}
+}
+
+func WithExcludeReservedBlocks(exclude bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.excludeReservedBlocks = exclude
+	}
+}
+
+// WithSecretProviderURL makes the node fetch the LUKS passphrases from an external secret store
+// instead of the request secrets.
+func WithSecretProviderURL(url string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.secretProviderURL = url
+	}
+}
+
+// WithFSGroupPolicy makes the node apply the fsGroup of the pods to the staged volumes according to policy,
+// instead of the kubelet. An empty policy leaves the fsGroup to the kubelet.
+func WithFSGroupPolicy(policy string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.fsGroupPolicy = policy
+	}
+}
+
+// WithDisableStaging makes the node mount the volumes directly at the target path of the pods,
+// without the STAGE_UNSTAGE_VOLUME capability. It cannot be combined with the encryption of the volumes,
+// the mount by filesystem UUID nor the format timeout, which require staging.
+func WithDisableStaging(disable bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.disableStaging = disable
+	}
+}
+
+// WithAllowFsTypeMismatch makes the node mount a volume with its existing filesystem when it does not
+// match the requested fstype, instead of failing.
+func WithAllowFsTypeMismatch(allow bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.allowFsTypeMismatch = allow
+	}
+}
+
+// WithStrictDeviceSizeCheck makes the node fail the staging when the device is smaller than
+// the volume, instead of logging a warning.
+func WithStrictDeviceSizeCheck(strict bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.strictDeviceSizeCheck = strict
+	}
+}
+
+// WithReportNodeTopology adds the instance ID of the node to its topology, as a hint for CreateVolume.
+func WithReportNodeTopology(enabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.reportNodeTopology = enabled
+	}
+}
+
+// WithReportRegionTopology adds the region of the node to its topology.
+func WithReportRegionTopology(enabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.reportRegionTopology = enabled
+	}
+}
+
+// WithInFlightMaxAge evicts the node requests in flight for more than maxAge, so that a missed cleanup
+// does not block the operations on a volume forever. A maxAge lower or equal to 0 disables the eviction.
+func WithInFlightMaxAge(maxAge time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.inFlightMaxAge = maxAge
+	}
+}
+
+// WithMountByUUID makes the node mount the staged volumes by the UUID of their filesystem,
+// recorded in the staging metadata and reused when the volume is staged again. A UUID shared with
+// another device, as with the volumes restored from the same snapshot, is mounted by its device path.
+func WithMountByUUID(enabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.mountByUUID = enabled
+	}
+}
+
+// WithFormatTimeout bounds the duration of the format of a volume at staging. A timeout lower or equal to 0
+// leaves the format unbounded.
+func WithFormatTimeout(timeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.formatTimeout = timeout
+	}
+}
+
+// WithFsResizeTolerance makes the node skip the resize of a filesystem whose size is within tolerance bytes
+// of the size of its device. Zero always resizes the filesystem.
+func WithFsResizeTolerance(tolerance int64) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.fsResizeTolerance = tolerance
+	}
+}
+
+func WithLuksOpenRetries(retries int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.luksOpenRetries = retries
+	}
+}
+
+func WithLuksOpenRetryDelay(delay time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.luksOpenRetryDelay = delay
+	}
+}
+
+func WithDisableSnapshots(disabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.disableSnapshots = disabled
+	}
+}
+
+// WithHideOrphanedSnapshots excludes from ListSnapshots the snapshots whose source volume does not exist anymore.
+func WithHideOrphanedSnapshots(hide bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.hideOrphanedSnapshots = hide
+	}
+}
+
+func WithRetryBudget(retryBudget int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.retryBudget = retryBudget
+	}
+}
+
+func WithOAPITimeout(timeout time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.oapiTimeout = timeout
+	}
+}
+
+func WithOAPIMaxRetries(maxRetries int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.oapiMaxRetries = maxRetries
+	}
+}
+
+// WithOscProfile reads the endpoint, the region and the credentials of the Outscale API from the profile
+// of the Outscale config file configFile instead of the environment.
+func WithOscProfile(configFile, profile string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.oscConfigFile = configFile
+		o.oscProfile = profile
+	}
+}
+
+// WithDetachSettleDuration makes ControllerUnpublishVolume wait for duration once the volume is detached.
+func WithDetachSettleDuration(duration time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.detachSettleDuration = duration
+	}
+}
+
+// WithDetachBusyRetries sets the number of retries of the detachment of a volume still in use by its node.
+func WithDetachBusyRetries(retries int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.detachBusyRetries = retries
+	}
+}
+
+// WithMaxVolumeSize rejects the creation of the volumes larger than maxSize bytes. Zero means no limit.
+func WithMaxVolumeSize(maxSize int64) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.maxVolumeSize = maxSize
+	}
+}
+
+func WithQuotaRetryInterval(interval time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.quotaRetryInterval = interval
+	}
+}
+
+func WithVolumeCloning(enabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.enableVolumeCloning = enabled
+	}
+}
+
+func WithMountProfilesFile(path string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.mountProfilesFile = path
+	}
+}
+
+// WithPerformanceClassesFile sets the path of the JSON file mapping the performance class names to a volume type and IOPS.
+func WithPerformanceClassesFile(path string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.performanceClassesFile = path
+	}
+}
+
+// WithAuditLogger replaces the default audit logger, which writes the audit records in the logs.
+func WithAuditLogger(logger AuditLogger) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.auditLogger = logger
+	}
+}
+
+// WithMaxConcurrentAttachesPerNode limits the number of volumes attached concurrently to the same node. Zero means no limit.
+func WithMaxConcurrentAttachesPerNode(limit int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.maxAttachesPerNode = limit
+	}
+}
+
+func WithSkipDetachStoppedNodes(enabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.skipDetachStoppedNodes = enabled
+	}
+}
+
+func WithSnapshotScheduler(enabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.enableSnapshotScheduler = enabled
+	}
+}
+
+func WithSnapshotScheduleInterval(interval time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.snapshotScheduleInterval = interval
+	}
+}
+
+func WithSnapshotScheduleRetention(retention int) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.snapshotScheduleRetention = retention
+	}
+}
+
+// WithVolumeReaper enables the deletion of the volumes created by the driver but never bound to a PV.
+func WithVolumeReaper(enabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.enableVolumeReaper = enabled
+	}
+}
+
+func WithVolumeReaperGracePeriod(gracePeriod time.Duration) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.volumeReaperGracePeriod = gracePeriod
+	}
+}
+
+func WithVolumeReaperDryRun(dryRun bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.volumeReaperDryRun = dryRun
+	}
+}
+
+// WithAttachmentReconciler enables the detection at startup of the volumes attached to a node without VolumeAttachment.
+func WithAttachmentReconciler(enabled bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.enableAttachmentReconciler = enabled
+	}
+}
+
+func WithAttachmentReconcilerDryRun(dryRun bool) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.attachmentReconcilerDryRun = dryRun
+	}
+}
+
+// WithCascadeDeleteEndpoint serves the deletion of a volume with its snapshots on its own listener,
+// which must be bound to a loopback address.
+func WithCascadeDeleteEndpoint(endpoint string) func(*DriverOptions) {
+	return func(o *DriverOptions) {
+		o.cascadeDeleteEndpoint = endpoint
+	}
+}
@@ -0,0 +1,101 @@
+package driver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestDriverShutdown(t *testing.T) {
+	testCases := []struct {
+		name    string
+		timeout time.Duration
+		release bool
+		expCode codes.Code
+	}{
+		{
+			name:    "drain the call in progress",
+			timeout: time.Minute,
+			release: true,
+			expCode: codes.OK,
+		},
+		{
+			name:    "cancel the call still in progress after the timeout",
+			timeout: 100 * time.Millisecond,
+			release: false,
+			expCode: codes.Unavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			// Simulates an operation in progress, e.g. an attachment
+			block := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				close(started)
+				select {
+				case <-release:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				return handler(ctx, req)
+			}
+
+			d := &Driver{
+				srv:     grpc.NewServer(grpc.UnaryInterceptor(block)),
+				options: &DriverOptions{},
+			}
+			csi.RegisterIdentityServer(d.srv, d)
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Could not listen: %v", err)
+			}
+			go func() { _ = d.srv.Serve(listener) }()
+
+			conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatalf("Could not connect: %v", err)
+			}
+			defer conn.Close()
+
+			called := make(chan error, 1)
+			go func() {
+				_, err := csi.NewIdentityClient(conn).Probe(context.Background(), &csi.ProbeRequest{})
+				called <- err
+			}()
+			<-started
+
+			stopped := make(chan struct{})
+			go func() {
+				d.Shutdown(tc.timeout)
+				close(stopped)
+			}()
+
+			if tc.release {
+				select {
+				case <-stopped:
+					t.Fatal("Expected the shutdown to wait for the call in progress")
+				case <-time.After(100 * time.Millisecond):
+				}
+				close(release)
+			}
+
+			select {
+			case <-stopped:
+			case <-time.After(10 * time.Second):
+				t.Fatal("Expected the shutdown to complete")
+			}
+			if err := <-called; status.Code(err) != tc.expCode {
+				t.Fatalf("Expected the call to end with code %v, got %v", tc.expCode, err)
+			}
+		})
+	}
+}
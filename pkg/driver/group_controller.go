@@ -0,0 +1,214 @@
+package driver
+
+import (
+	"context"
+	"sync"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/klog/v2"
+)
+
+// BSU has no group snapshots: a group snapshot is the set of the snapshots tagged with
+// GroupSnapshotIDTagKey. Its ID is the name of the group snapshot, and each snapshot of the group
+// is named after the group snapshot and its source volume, so that retried requests find them.
+
+func (d *controllerService) GroupControllerGetCapabilities(ctx context.Context, req *csi.GroupControllerGetCapabilitiesRequest) (*csi.GroupControllerGetCapabilitiesResponse, error) {
+	klog.V(4).Infof("GroupControllerGetCapabilities: called with args %+v", req)
+	if d.driverOptions.disableSnapshots {
+		return &csi.GroupControllerGetCapabilitiesResponse{}, nil
+	}
+	return &csi.GroupControllerGetCapabilitiesResponse{
+		Capabilities: []*csi.GroupControllerServiceCapability{
+			{
+				Type: &csi.GroupControllerServiceCapability_Rpc{
+					Rpc: &csi.GroupControllerServiceCapability_RPC{
+						Type: csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// CreateVolumeGroupSnapshot snapshots all the source volumes at once, to take them as close in time as possible.
+// When a snapshot could not be created, all the snapshots of the group are deleted.
+func (d *controllerService) CreateVolumeGroupSnapshot(ctx context.Context, req *csi.CreateVolumeGroupSnapshotRequest) (*csi.CreateVolumeGroupSnapshotResponse, error) {
+	klog.V(4).Infof("CreateVolumeGroupSnapshot: called with args %+v", req)
+	if d.driverOptions.disableSnapshots {
+		return nil, status.Error(codes.Unimplemented, "Snapshots are disabled")
+	}
+	groupName := req.GetName()
+	if len(groupName) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Group snapshot name not provided")
+	}
+	volumeIDs := req.GetSourceVolumeIds()
+	if len(volumeIDs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Group snapshot source volume IDs not provided")
+	}
+
+	// The source volumes are all looked up before the first snapshot is taken
+	disks := make([]cloud.Disk, len(volumeIDs))
+	existing := make([]cloud.Snapshot, len(volumeIDs))
+	for i, volumeID := range volumeIDs {
+		snapshotName := groupSnapshotMemberName(groupName, volumeID)
+		snapshot, err := d.cloud.GetSnapshotByName(ctx, snapshotName)
+		if err != nil && err != cloud.ErrNotFound {
+			return nil, status.Errorf(codes.Internal, "Could not look for snapshot %s: %v", snapshotName, err)
+		}
+		if !cloud.IsNilSnapshot(snapshot) {
+			if snapshot.Tags[GroupSnapshotIDTagKey] != groupName {
+				return nil, status.Errorf(codes.AlreadyExists, "Snapshot %s already exists outside of group snapshot %s", snapshotName, groupName)
+			}
+			existing[i] = snapshot
+			continue
+		}
+		disks[i], err = d.cloud.GetDiskByID(ctx, volumeID)
+		if err != nil {
+			if err == cloud.ErrNotFound {
+				return nil, status.Errorf(codes.NotFound, "Source volume %s not found", volumeID)
+			}
+			return nil, status.Errorf(codes.Internal, "Could not get source volume %s: %v", volumeID, err)
+		}
+	}
+
+	snapshots := make([]cloud.Snapshot, len(volumeIDs))
+	errs := make([]error, len(volumeIDs))
+	var wg sync.WaitGroup
+	for i, volumeID := range volumeIDs {
+		if !cloud.IsNilSnapshot(existing[i]) {
+			klog.V(4).Infof("Snapshot %s of volume %s already exists; nothing to do", existing[i].SnapshotID, volumeID)
+			snapshots[i] = existing[i]
+			continue
+		}
+		snapshotName := groupSnapshotMemberName(groupName, volumeID)
+		opts := &cloud.SnapshotOptions{
+			Tags: d.snapshotTags(snapshotName, req.GetParameters(), disks[i].Tags),
+		}
+		for k, v := range luksTags(luksContext(disks[i].Tags)) {
+			opts.Tags[k] = v
+		}
+		opts.Tags[GroupSnapshotIDTagKey] = groupName
+		wg.Add(1)
+		go func(i int, volumeID string) {
+			defer wg.Done()
+			snapshots[i], errs[i] = d.cloud.CreateSnapshot(ctx, volumeID, opts)
+		}(i, volumeID)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		d.deleteGroupSnapshots(ctx, groupName, snapshots)
+		return nil, status.Errorf(codes.Internal, "Could not create the snapshot of volume %s in group snapshot %s: %v", volumeIDs[i], groupName, err)
+	}
+	return &csi.CreateVolumeGroupSnapshotResponse{GroupSnapshot: newVolumeGroupSnapshot(groupName, snapshots)}, nil
+}
+
+// deleteGroupSnapshots deletes the snapshots created for a group snapshot which could not be completed.
+// The snapshots left are deleted by the next attempt, which finds them by name.
+func (d *controllerService) deleteGroupSnapshots(ctx context.Context, groupName string, snapshots []cloud.Snapshot) {
+	for _, snapshot := range snapshots {
+		if cloud.IsNilSnapshot(snapshot) {
+			continue
+		}
+		klog.Infof("CreateVolumeGroupSnapshot: deleting snapshot %s of the failed group snapshot %s", snapshot.SnapshotID, groupName)
+		if _, err := d.cloud.DeleteSnapshot(ctx, snapshot.SnapshotID); err != nil && err != cloud.ErrNotFound {
+			klog.Errorf("Could not delete snapshot %s of the failed group snapshot %s: %v", snapshot.SnapshotID, groupName, err)
+		}
+	}
+}
+
+func (d *controllerService) DeleteVolumeGroupSnapshot(ctx context.Context, req *csi.DeleteVolumeGroupSnapshotRequest) (*csi.DeleteVolumeGroupSnapshotResponse, error) {
+	klog.V(4).Infof("DeleteVolumeGroupSnapshot: called with args %+v", req)
+	if d.driverOptions.disableSnapshots {
+		return nil, status.Error(codes.Unimplemented, "Snapshots are disabled")
+	}
+	groupID := req.GetGroupSnapshotId()
+	if len(groupID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Group snapshot ID not provided")
+	}
+	snapshots, err := d.getGroupSnapshots(ctx, groupID, req.GetSnapshotIds())
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if _, err := d.cloud.DeleteSnapshot(ctx, snapshot.SnapshotID); err != nil && err != cloud.ErrNotFound {
+			return nil, status.Errorf(codes.Internal, "Could not delete snapshot %s of group snapshot %s: %v", snapshot.SnapshotID, groupID, err)
+		}
+	}
+	return &csi.DeleteVolumeGroupSnapshotResponse{}, nil
+}
+
+func (d *controllerService) GetVolumeGroupSnapshot(ctx context.Context, req *csi.GetVolumeGroupSnapshotRequest) (*csi.GetVolumeGroupSnapshotResponse, error) {
+	klog.V(4).Infof("GetVolumeGroupSnapshot: called with args %+v", req)
+	if d.driverOptions.disableSnapshots {
+		return nil, status.Error(codes.Unimplemented, "Snapshots are disabled")
+	}
+	groupID := req.GetGroupSnapshotId()
+	if len(groupID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Group snapshot ID not provided")
+	}
+	snapshots, err := d.getGroupSnapshots(ctx, groupID, req.GetSnapshotIds())
+	if err != nil {
+		return nil, err
+	}
+	// The group snapshot is only known by its snapshots
+	if len(snapshots) == 0 || len(snapshots) != len(req.GetSnapshotIds()) {
+		return nil, status.Errorf(codes.NotFound, "Group snapshot %s not found", groupID)
+	}
+	return &csi.GetVolumeGroupSnapshotResponse{GroupSnapshot: newVolumeGroupSnapshot(groupID, snapshots)}, nil
+}
+
+// getGroupSnapshots returns the snapshots snapshotIDs of the group snapshot groupID, skipping the snapshots not found.
+// It fails with InvalidArgument when one of the snapshots belongs to another group.
+func (d *controllerService) getGroupSnapshots(ctx context.Context, groupID string, snapshotIDs []string) ([]cloud.Snapshot, error) {
+	var snapshots []cloud.Snapshot
+	for _, snapshotID := range snapshotIDs {
+		snapshot, err := d.cloud.GetSnapshotByID(ctx, snapshotID)
+		if err == cloud.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not get snapshot %s: %v", snapshotID, err)
+		}
+		if snapshot.Tags[GroupSnapshotIDTagKey] != groupID {
+			return nil, status.Errorf(codes.InvalidArgument, "Snapshot %s is not part of group snapshot %s", snapshotID, groupID)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// groupSnapshotMemberName returns the name of the snapshot of volumeID in the group snapshot groupName.
+func groupSnapshotMemberName(groupName, volumeID string) string {
+	return groupName + "-" + volumeID
+}
+
+// newVolumeGroupSnapshot returns the group snapshot groupID of snapshots, taken when its first snapshot was taken.
+func newVolumeGroupSnapshot(groupID string, snapshots []cloud.Snapshot) *csi.VolumeGroupSnapshot {
+	group := &csi.VolumeGroupSnapshot{
+		GroupSnapshotId: groupID,
+		ReadyToUse:      true,
+	}
+	for _, snapshot := range snapshots {
+		if group.CreationTime == nil || snapshot.CreationTime.Before(group.CreationTime.AsTime()) {
+			group.CreationTime = timestamppb.New(snapshot.CreationTime)
+		}
+		group.ReadyToUse = group.ReadyToUse && snapshot.ReadyToUse
+		group.Snapshots = append(group.Snapshots, &csi.Snapshot{
+			SnapshotId:      snapshot.SnapshotID,
+			SourceVolumeId:  snapshot.SourceVolumeID,
+			SizeBytes:       snapshot.Size,
+			CreationTime:    timestamppb.New(snapshot.CreationTime),
+			ReadyToUse:      snapshot.ReadyToUse,
+			GroupSnapshotId: groupID,
+		})
+	}
+	return group
+}
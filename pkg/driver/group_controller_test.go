@@ -0,0 +1,183 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/mock/gomock"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/mocks"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCreateVolumeGroupSnapshot(t *testing.T) {
+	ctx := context.Background()
+	req := &csi.CreateVolumeGroupSnapshotRequest{
+		Name:            "group-test",
+		SourceVolumeIds: []string{"vol-1", "vol-2"},
+	}
+	creationTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success with group tag",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				for i, volumeID := range req.SourceVolumeIds {
+					snapshotName := "group-test-" + volumeID
+					expTags := map[string]string{
+						cloud.SnapshotNameTagKey: snapshotName,
+						GroupSnapshotIDTagKey:    "group-test",
+					}
+					mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(snapshotName)).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+					mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(volumeID)).Return(cloud.Disk{VolumeID: volumeID}, nil)
+					mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(volumeID), gomock.Eq(&cloud.SnapshotOptions{Tags: expTags})).Return(cloud.Snapshot{
+						SnapshotID:     "snap-" + volumeID,
+						SourceVolumeID: volumeID,
+						CreationTime:   creationTime.Add(time.Duration(i) * time.Second),
+						ReadyToUse:     i == 0,
+						Tags:           expTags,
+					}, nil)
+				}
+
+				d := &controllerService{cloud: mockCloud, driverOptions: &DriverOptions{}}
+				resp, err := d.CreateVolumeGroupSnapshot(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				group := resp.GetGroupSnapshot()
+				if group.GetGroupSnapshotId() != "group-test" {
+					t.Fatalf("Expected group snapshot ID group-test, got %s", group.GetGroupSnapshotId())
+				}
+				if len(group.GetSnapshots()) != 2 {
+					t.Fatalf("Expected 2 snapshots, got %d", len(group.GetSnapshots()))
+				}
+				for i, snapshot := range group.GetSnapshots() {
+					if snapshot.GetSnapshotId() != "snap-"+req.SourceVolumeIds[i] || snapshot.GetGroupSnapshotId() != "group-test" {
+						t.Fatalf("Unexpected snapshot %+v", snapshot)
+					}
+				}
+				if !group.GetCreationTime().AsTime().Equal(creationTime) {
+					t.Fatalf("Expected creation time %v, got %v", creationTime, group.GetCreationTime().AsTime())
+				}
+				if group.GetReadyToUse() {
+					t.Fatal("Expected the group snapshot not to be ready while one of its snapshots is not")
+				}
+			},
+		},
+		{
+			name: "success with existing snapshot",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq("group-test-vol-1")).Return(cloud.Snapshot{
+					SnapshotID:     "snap-vol-1",
+					SourceVolumeID: "vol-1",
+					Tags:           map[string]string{GroupSnapshotIDTagKey: "group-test"},
+				}, nil)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq("group-test-vol-2")).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq("vol-2")).Return(cloud.Disk{VolumeID: "vol-2"}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq("vol-2"), gomock.Any()).Return(cloud.Snapshot{SnapshotID: "snap-vol-2", SourceVolumeID: "vol-2"}, nil)
+
+				d := &controllerService{cloud: mockCloud, driverOptions: &DriverOptions{}}
+				resp, err := d.CreateVolumeGroupSnapshot(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if n := len(resp.GetGroupSnapshot().GetSnapshots()); n != 2 {
+					t.Fatalf("Expected 2 snapshots, got %d", n)
+				}
+			},
+		},
+		{
+			name: "fail snapshot creation deletes the group",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Any()).Return(cloud.Snapshot{}, cloud.ErrNotFound).Times(2)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Any()).Return(cloud.Disk{}, nil).Times(2)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq("vol-1"), gomock.Any()).Return(cloud.Snapshot{SnapshotID: "snap-vol-1", SourceVolumeID: "vol-1"}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq("vol-2"), gomock.Any()).Return(cloud.Snapshot{}, errors.New("quota exceeded"))
+				mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("snap-vol-1")).Return(true, nil)
+
+				d := &controllerService{cloud: mockCloud, driverOptions: &DriverOptions{}}
+				_, err := d.CreateVolumeGroupSnapshot(ctx, req)
+				expectErr(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "fail source volume not found",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Any()).Return(cloud.Snapshot{}, cloud.ErrNotFound).Times(2)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq("vol-1")).Return(cloud.Disk{}, nil)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq("vol-2")).Return(cloud.Disk{}, cloud.ErrNotFound)
+
+				d := &controllerService{cloud: mockCloud, driverOptions: &DriverOptions{}}
+				_, err := d.CreateVolumeGroupSnapshot(ctx, req)
+				expectErr(t, err, codes.NotFound)
+			},
+		},
+		{
+			name: "fail snapshot of another group",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq("group-test-vol-1")).Return(cloud.Snapshot{SnapshotID: "snap-vol-1"}, nil)
+
+				d := &controllerService{cloud: mockCloud, driverOptions: &DriverOptions{}}
+				_, err := d.CreateVolumeGroupSnapshot(ctx, req)
+				expectErr(t, err, codes.AlreadyExists)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestDeleteVolumeGroupSnapshot(t *testing.T) {
+	ctx := context.Background()
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snap-1")).Return(cloud.Snapshot{SnapshotID: "snap-1", Tags: map[string]string{GroupSnapshotIDTagKey: "group-test"}}, nil)
+	mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snap-2")).Return(cloud.Snapshot{}, cloud.ErrNotFound)
+	mockCloud.EXPECT().DeleteSnapshot(gomock.Eq(ctx), gomock.Eq("snap-1")).Return(true, nil)
+
+	d := &controllerService{cloud: mockCloud, driverOptions: &DriverOptions{}}
+	if _, err := d.DeleteVolumeGroupSnapshot(ctx, &csi.DeleteVolumeGroupSnapshotRequest{
+		GroupSnapshotId: "group-test",
+		SnapshotIds:     []string{"snap-1", "snap-2"},
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A snapshot of another group is never deleted
+	mockCloud.EXPECT().GetSnapshotByID(gomock.Eq(ctx), gomock.Eq("snap-3")).Return(cloud.Snapshot{SnapshotID: "snap-3", Tags: map[string]string{GroupSnapshotIDTagKey: "group-other"}}, nil)
+	_, err := d.DeleteVolumeGroupSnapshot(ctx, &csi.DeleteVolumeGroupSnapshotRequest{
+		GroupSnapshotId: "group-test",
+		SnapshotIds:     []string{"snap-3"},
+	})
+	expectErr(t, err, codes.InvalidArgument)
+}
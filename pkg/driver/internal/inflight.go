@@ -17,7 +17,11 @@ limitations under the License.
 package internal
 
 import (
+	"context"
 	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
 )
 
 // Idempotent is the interface required to manage in flight requests.
@@ -31,15 +35,18 @@ type Idempotent interface {
 
 // InFlight is a struct used to manage in flight requests.
 type InFlight struct {
-	mux      *sync.Mutex
-	inFlight map[string]bool
+	mux *sync.Mutex
+	// inFlight maps the keys of the requests to the time they were inserted
+	inFlight map[string]time.Time
+	now      func() time.Time
 }
 
 // NewInFlight instanciates a InFlight structures.
 func NewInFlight() *InFlight {
 	return &InFlight{
 		mux:      &sync.Mutex{},
-		inFlight: make(map[string]bool),
+		inFlight: make(map[string]time.Time),
+		now:      time.Now,
 	}
 }
 
@@ -56,7 +63,7 @@ func (db *InFlight) Insert(entry Idempotent) bool {
 		return false
 	}
 
-	db.inFlight[hash] = true
+	db.inFlight[hash] = db.now()
 	return true
 }
 
@@ -68,3 +75,36 @@ func (db *InFlight) Delete(h Idempotent) {
 
 	delete(db.inFlight, h.String())
 }
+
+// Evict removes the entries inserted more than maxAge ago, which are left by a request whose Delete was missed,
+// and returns their keys.
+func (db *InFlight) Evict(maxAge time.Duration) []string {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	var evicted []string
+	now := db.now()
+	for hash, inserted := range db.inFlight {
+		if now.Sub(inserted) > maxAge {
+			delete(db.inFlight, hash)
+			evicted = append(evicted, hash)
+		}
+	}
+	return evicted
+}
+
+// RunJanitor evicts every interval the entries older than maxAge, until ctx is done.
+func (db *InFlight) RunJanitor(ctx context.Context, maxAge, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, hash := range db.Evict(maxAge) {
+				klog.Warningf("InFlight: evicting request in flight for more than %v: %s", maxAge, hash)
+			}
+		}
+	}
+}
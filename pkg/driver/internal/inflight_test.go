@@ -18,6 +18,7 @@ package internal
 
 import (
 	"testing"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/util"
@@ -212,3 +213,27 @@ func TestInFlight(t *testing.T) {
 
 	}
 }
+
+func TestInFlightEvict(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db := NewInFlight()
+	db.now = func() time.Time { return now }
+
+	stale := &csi.NodeStageVolumeRequest{VolumeId: "vol-stale"}
+	recent := &csi.NodeStageVolumeRequest{VolumeId: "vol-recent"}
+	db.Insert(stale)
+	now = now.Add(5 * time.Minute)
+	db.Insert(recent)
+	now = now.Add(6 * time.Minute)
+
+	evicted := db.Evict(10 * time.Minute)
+	if len(evicted) != 1 || evicted[0] != stale.String() {
+		t.Fatalf("expected only %q to be evicted, got %v", stale.String(), evicted)
+	}
+	if !db.Insert(stale) {
+		t.Fatal("expected the evicted request to be inserted again")
+	}
+	if db.Insert(recent) {
+		t.Fatal("expected the recent request to be still in flight")
+	}
+}
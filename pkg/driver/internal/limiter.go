@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyedLimiter limits the number of operations running concurrently for the same key.
+type KeyedLimiter struct {
+	limit int
+	mux   *sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewKeyedLimiter instanciates a KeyedLimiter allowing limit concurrent operations per key.
+func NewKeyedLimiter(limit int) *KeyedLimiter {
+	return &KeyedLimiter{
+		limit: limit,
+		mux:   &sync.Mutex{},
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+func (l *KeyedLimiter) keySlots(key string) chan struct{} {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	slots, ok := l.slots[key]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.slots[key] = slots
+	}
+	return slots
+}
+
+// Acquire waits until an operation can start for the key, or returns the error of the context
+// when it is done first. Each successful Acquire must be followed by a Release.
+func (l *KeyedLimiter) Acquire(ctx context.Context, key string) error {
+	select {
+	case l.keySlots(key) <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release ends an operation started by Acquire for the key.
+func (l *KeyedLimiter) Release(key string) {
+	<-l.keySlots(key)
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	l := NewKeyedLimiter(1)
+	ctx := context.Background()
+
+	if err := l.Acquire(ctx, "i-1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The other keys are not limited by i-1
+	if err := l.Acquire(ctx, "i-2"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(timeoutCtx, "i-1"); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	l.Release("i-1")
+	if err := l.Acquire(ctx, "i-1"); err != nil {
+		t.Fatalf("Expected no error after release, got %v", err)
+	}
+}
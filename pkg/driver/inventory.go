@@ -0,0 +1,94 @@
+package driver
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/util"
+)
+
+// InventoryReport lists the volumes and the snapshots created by the driver.
+type InventoryReport struct {
+	GeneratedAt time.Time           `json:"generatedAt"`
+	Volumes     []InventoryVolume   `json:"volumes"`
+	Snapshots   []InventorySnapshot `json:"snapshots"`
+}
+
+type InventoryVolume struct {
+	VolumeID         string    `json:"volumeID"`
+	Name             string    `json:"name"`
+	SizeGiB          int64     `json:"sizeGiB"`
+	VolumeType       string    `json:"volumeType"`
+	AvailabilityZone string    `json:"availabilityZone"`
+	State            string    `json:"state"`
+	CreationTime     time.Time `json:"creationTime"`
+}
+
+type InventorySnapshot struct {
+	SnapshotID     string    `json:"snapshotID"`
+	Name           string    `json:"name"`
+	SourceVolumeID string    `json:"sourceVolumeID"`
+	SizeGiB        int64     `json:"sizeGiB"`
+	State          string    `json:"state"`
+	CreationTime   time.Time `json:"creationTime"`
+}
+
+// inventory lists the volumes and the snapshots created by the driver, restricted to the cluster when a cluster ID is set.
+func (d *controllerService) inventory(ctx context.Context) (InventoryReport, error) {
+	var tags map[string]string
+	if d.driverOptions.clusterID != "" {
+		tags = map[string]string{cloud.ClusterIDTagKey: d.driverOptions.clusterID}
+	}
+	disks, err := d.cloud.ListDisks(ctx, tags)
+	if err != nil {
+		return InventoryReport{}, err
+	}
+
+	// Without volume ID, all the snapshots are read at once
+	resp, err := d.cloud.ListSnapshots(ctx, "", 0, "")
+	if err != nil && err != cloud.ErrNotFound {
+		return InventoryReport{}, err
+	}
+
+	return newInventoryReport(disks, resp.Snapshots, time.Now()), nil
+}
+
+// newInventoryReport builds the report of the disks and of the snapshots created by the driver, sorted by ID.
+func newInventoryReport(disks []cloud.Disk, snapshots []cloud.Snapshot, now time.Time) InventoryReport {
+	report := InventoryReport{
+		GeneratedAt: now.UTC(),
+		Volumes:     []InventoryVolume{},
+		Snapshots:   []InventorySnapshot{},
+	}
+	for _, disk := range disks {
+		report.Volumes = append(report.Volumes, InventoryVolume{
+			VolumeID:         disk.VolumeID,
+			Name:             disk.Tags[cloud.VolumeNameTagKey],
+			SizeGiB:          disk.CapacityGiB,
+			VolumeType:       disk.VolumeType,
+			AvailabilityZone: disk.AvailabilityZone,
+			State:            disk.State,
+			CreationTime:     disk.CreationTime.UTC(),
+		})
+	}
+	for _, snapshot := range snapshots {
+		name, ok := snapshot.Tags[cloud.SnapshotNameTagKey]
+		if !ok {
+			// Not created by the driver
+			continue
+		}
+		report.Snapshots = append(report.Snapshots, InventorySnapshot{
+			SnapshotID:     snapshot.SnapshotID,
+			Name:           name,
+			SourceVolumeID: snapshot.SourceVolumeID,
+			SizeGiB:        util.BytesToGiB(snapshot.Size),
+			State:          snapshot.State,
+			CreationTime:   snapshot.CreationTime.UTC(),
+		})
+	}
+	sort.Slice(report.Volumes, func(i, j int) bool { return report.Volumes[i].VolumeID < report.Volumes[j].VolumeID })
+	sort.Slice(report.Snapshots, func(i, j int) bool { return report.Snapshots[i].SnapshotID < report.Snapshots[j].SnapshotID })
+	return report
+}
@@ -0,0 +1,77 @@
+package driver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/mocks"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/util"
+)
+
+func TestNewInventoryReport(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 4, 1, 8, 30, 0, 0, time.UTC)
+	disks := []cloud.Disk{
+		{VolumeID: "vol-2", CapacityGiB: 20, VolumeType: "io1", AvailabilityZone: "eu-west-2b", State: "in-use", CreationTime: created, Tags: map[string]string{cloud.VolumeNameTagKey: "pvc-2"}},
+		{VolumeID: "vol-1", CapacityGiB: 10, VolumeType: "gp2", AvailabilityZone: "eu-west-2a", State: "available", CreationTime: created, Tags: map[string]string{cloud.VolumeNameTagKey: "pvc-1"}},
+	}
+	snapshots := []cloud.Snapshot{
+		{SnapshotID: "snap-1", SourceVolumeID: "vol-1", Size: util.GiBToBytes(10), State: "completed", CreationTime: created, Tags: map[string]string{cloud.SnapshotNameTagKey: "snapshot-1"}},
+		// Not created by the driver
+		{SnapshotID: "snap-2", SourceVolumeID: "vol-1", Size: util.GiBToBytes(10), State: "completed", CreationTime: created},
+	}
+
+	data, err := json.Marshal(newInventoryReport(disks, snapshots, now))
+	if err != nil {
+		t.Fatalf("Could not serialize the report: %v", err)
+	}
+	expected := `{"generatedAt":"2024-05-01T12:00:00Z",` +
+		`"volumes":[` +
+		`{"volumeID":"vol-1","name":"pvc-1","sizeGiB":10,"volumeType":"gp2","availabilityZone":"eu-west-2a","state":"available","creationTime":"2024-04-01T08:30:00Z"},` +
+		`{"volumeID":"vol-2","name":"pvc-2","sizeGiB":20,"volumeType":"io1","availabilityZone":"eu-west-2b","state":"in-use","creationTime":"2024-04-01T08:30:00Z"}],` +
+		`"snapshots":[` +
+		`{"snapshotID":"snap-1","name":"snapshot-1","sourceVolumeID":"vol-1","sizeGiB":10,"state":"completed","creationTime":"2024-04-01T08:30:00Z"}]}`
+	if string(data) != expected {
+		t.Fatalf("Expected report\n%s\ngot\n%s", expected, data)
+	}
+
+	data, err = json.Marshal(newInventoryReport(nil, nil, now))
+	if err != nil {
+		t.Fatalf("Could not serialize the report: %v", err)
+	}
+	if expected := `{"generatedAt":"2024-05-01T12:00:00Z","volumes":[],"snapshots":[]}`; string(data) != expected {
+		t.Fatalf("Expected report %s, got %s", expected, data)
+	}
+}
+
+func TestDebugInventory(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().ListDisks(gomock.Any(), gomock.Eq(map[string]string{cloud.ClusterIDTagKey: "cluster-test"})).Return([]cloud.Disk{{VolumeID: "vol-1"}}, nil)
+	mockCloud.EXPECT().ListSnapshots(gomock.Any(), gomock.Eq(""), gomock.Eq(int64(0)), gomock.Eq("")).Return(cloud.ListSnapshotsResponse{}, cloud.ErrNotFound)
+
+	d := &Driver{controllerService: controllerService{
+		cloud:         mockCloud,
+		driverOptions: &DriverOptions{clusterID: "cluster-test"},
+	}}
+	rec := httptest.NewRecorder()
+	d.newDebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/inventory", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var report InventoryReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Could not decode body %q: %v", rec.Body.String(), err)
+	}
+	if len(report.Volumes) != 1 || report.Volumes[0].VolumeID != "vol-1" || len(report.Snapshots) != 0 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+}
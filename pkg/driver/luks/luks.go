@@ -10,6 +10,7 @@ type LuksService interface {
 	IsLuks(devicePath string) bool
 	LuksFormat(devicePath string, passphrase string, context LuksContext) error
 	CheckLuksPassphrase(devicePath string, passphrase string) bool
+	LuksSlotForPassphrase(devicePath string, passphrase string) (int, error)
 	LuksOpen(devicePath string, encryptedDeviceName string, passphrase string) (bool, error)
 	IsLuksMapping(devicePath string) (bool, string, error)
 	LuksResize(deviceName string, passphrase string) error
@@ -3,6 +3,8 @@ package driver
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/luks"
@@ -52,6 +54,27 @@ func CheckLuksPassphrase(exec k8sExec.Interface, devicePath string, passphrase s
 	return true
 }
 
+// luksSlotRegexp matches the key slot reported by cryptsetup in verbose mode
+var luksSlotRegexp = regexp.MustCompile(`Key slot (\d+) unlocked`)
+
+// LuksSlotForPassphrase returns the key slot unlocked by the passphrase, or -1 when the passphrase is valid
+// but cryptsetup did not report the slot.
+func LuksSlotForPassphrase(exec k8sExec.Interface, devicePath string, passphrase string) (int, error) {
+	checkPassphraseCmd := exec.Command("cryptsetup", "-v", "--type=luks2", "--batch-mode", "--test-passphrase", "luksOpen", devicePath)
+	passwordReader := strings.NewReader(passphrase)
+	checkPassphraseCmd.SetStdin(passwordReader)
+	out, err := checkPassphraseCmd.CombinedOutput()
+	if err != nil {
+		return -1, fmt.Errorf("passphrase does not match any key slot of %s: %v, output: %s", devicePath, err, out)
+	}
+
+	match := luksSlotRegexp.FindSubmatch(out)
+	if match == nil {
+		return -1, nil
+	}
+	return strconv.Atoi(string(match[1]))
+}
+
 func LuksOpen(exec Mounter, devicePath string, encryptedDeviceName string, passphrase string) (bool, error) {
 	if ok, err := exec.ExistsPath("/dev/mapper/" + encryptedDeviceName); err == nil && ok {
 		klog.V(4).Info("luks volume is already open")
@@ -61,12 +84,18 @@ func LuksOpen(exec Mounter, devicePath string, encryptedDeviceName string, passp
 	passwordReader := strings.NewReader(passphrase)
 	openCmd.SetStdin(passwordReader)
 	if out, err := openCmd.CombinedOutput(); err != nil {
-		return false, fmt.Errorf("err: %v, output: %v", err, out)
+		return false, fmt.Errorf("err: %v, output: %s", err, out)
 	}
 
 	return true, nil
 }
 
+// isDeviceBusyError returns true when cryptsetup failed because the device is still busy,
+// which may happen right after the volume is attached.
+func isDeviceBusyError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "busy")
+}
+
 func IsLuksMapping(exec k8sExec.Interface, devicePath string) (bool, string, error) {
 	if strings.HasPrefix(devicePath, "/dev/mapper") {
 		mappingName := filepath.Base(devicePath)
@@ -169,6 +169,51 @@ func TestCheckLuksPassphrase(t *testing.T) {
 
 }
 
+func TestLuksSlotForPassphrase(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	devicePath := "/dev/fake"
+	passphrase := "ThisIsASecret"
+	expectTestPassphrase := func(mockCommand *mocks.MockInterface, mockRun *mocks.MockCmd) {
+		mockRun.EXPECT().SetStdin(gomock.Any()).Return()
+		mockCommand.EXPECT().Command(
+			gomock.Eq("cryptsetup"),
+			gomock.Eq("-v"),
+			gomock.Eq("--type=luks2"),
+			gomock.Eq("--batch-mode"),
+			gomock.Eq("--test-passphrase"),
+			gomock.Eq("luksOpen"),
+			gomock.Eq(devicePath),
+		).Return(mockRun)
+	}
+
+	// Check when the passphrase matches the second slot
+	mockCommand := mocks.NewMockInterface(mockCtl)
+	mockRun := mocks.NewMockCmd(mockCtl)
+	expectTestPassphrase(mockCommand, mockRun)
+	mockRun.EXPECT().CombinedOutput().Return([]byte("No usable token is available.\nKey slot 1 unlocked.\nCommand successful.\n"), nil)
+	slot, err := LuksSlotForPassphrase(mockCommand, devicePath, passphrase)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, slot)
+
+	// Check when the slot is not reported
+	mockCommand = mocks.NewMockInterface(mockCtl)
+	mockRun = mocks.NewMockCmd(mockCtl)
+	expectTestPassphrase(mockCommand, mockRun)
+	mockRun.EXPECT().CombinedOutput().Return([]byte("Command successful.\n"), nil)
+	slot, err = LuksSlotForPassphrase(mockCommand, devicePath, passphrase)
+	assert.Nil(t, err)
+	assert.Equal(t, -1, slot)
+
+	// Check when the passphrase does not match any slot
+	mockCommand = mocks.NewMockInterface(mockCtl)
+	mockRun = mocks.NewMockCmd(mockCtl)
+	expectTestPassphrase(mockCommand, mockRun)
+	mockRun.EXPECT().CombinedOutput().Return([]byte("No key available with this passphrase.\n"), fmt.Errorf("exit status 2"))
+	slot, err = LuksSlotForPassphrase(mockCommand, devicePath, passphrase)
+	assert.NotNil(t, err)
+	assert.Equal(t, -1, slot)
+}
+
 func TestLuksOpen(t *testing.T) {
 	mockCtl := gomock.NewController(t)
 	devicePath := "/dev/fake"
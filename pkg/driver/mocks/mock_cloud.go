@@ -80,32 +80,60 @@ func (mr *MockCloudMockRecorder) DeleteDisk(ctx, volumeID interface{}) *gomock.C
 }
 
 // AttachDisk mocks base method.
-func (m *MockCloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string, error) {
+func (m *MockCloud) AttachDisk(ctx context.Context, volumeID, nodeID, deviceName string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AttachDisk", ctx, volumeID, nodeID)
+	ret := m.ctrl.Call(m, "AttachDisk", ctx, volumeID, nodeID, deviceName)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AttachDisk indicates an expected call of AttachDisk.
-func (mr *MockCloudMockRecorder) AttachDisk(ctx, volumeID, nodeID interface{}) *gomock.Call {
+func (mr *MockCloudMockRecorder) AttachDisk(ctx, volumeID, nodeID, deviceName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachDisk", reflect.TypeOf((*MockCloud)(nil).AttachDisk), ctx, volumeID, nodeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachDisk", reflect.TypeOf((*MockCloud)(nil).AttachDisk), ctx, volumeID, nodeID, deviceName)
 }
 
 // DetachDisk mocks base method.
-func (m *MockCloud) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
+func (m *MockCloud) DetachDisk(ctx context.Context, volumeID, nodeID string, waitDetached bool) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DetachDisk", ctx, volumeID, nodeID)
+	ret := m.ctrl.Call(m, "DetachDisk", ctx, volumeID, nodeID, waitDetached)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DetachDisk indicates an expected call of DetachDisk.
-func (mr *MockCloudMockRecorder) DetachDisk(ctx, volumeID, nodeID interface{}) *gomock.Call {
+func (mr *MockCloudMockRecorder) DetachDisk(ctx, volumeID, nodeID, waitDetached interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachDisk", reflect.TypeOf((*MockCloud)(nil).DetachDisk), ctx, volumeID, nodeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachDisk", reflect.TypeOf((*MockCloud)(nil).DetachDisk), ctx, volumeID, nodeID, waitDetached)
+}
+
+// ModifyDisk mocks base method.
+func (m *MockCloud) ModifyDisk(ctx context.Context, volumeID, volumeType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModifyDisk", ctx, volumeID, volumeType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ModifyDisk indicates an expected call of ModifyDisk.
+func (mr *MockCloudMockRecorder) ModifyDisk(ctx, volumeID, volumeType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifyDisk", reflect.TypeOf((*MockCloud)(nil).ModifyDisk), ctx, volumeID, volumeType)
+}
+
+// TagDisk mocks base method.
+func (m *MockCloud) TagDisk(ctx context.Context, volumeID string, tags map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TagDisk", ctx, volumeID, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TagDisk indicates an expected call of TagDisk.
+func (mr *MockCloudMockRecorder) TagDisk(ctx, volumeID, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TagDisk", reflect.TypeOf((*MockCloud)(nil).TagDisk), ctx, volumeID, tags)
 }
 
 // ResizeDisk mocks base method.
@@ -167,6 +195,36 @@ func (mr *MockCloudMockRecorder) GetDiskByID(ctx, volumeID interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiskByID", reflect.TypeOf((*MockCloud)(nil).GetDiskByID), ctx, volumeID)
 }
 
+// ListDisks mocks base method.
+func (m *MockCloud) ListDisks(ctx context.Context, tags map[string]string) ([]cloud.Disk, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDisks", ctx, tags)
+	ret0, _ := ret[0].([]cloud.Disk)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDisks indicates an expected call of ListDisks.
+func (mr *MockCloudMockRecorder) ListDisks(ctx, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDisks", reflect.TypeOf((*MockCloud)(nil).ListDisks), ctx, tags)
+}
+
+// GetDisksByIDs mocks base method.
+func (m *MockCloud) GetDisksByIDs(ctx context.Context, volumeIDs []string) (map[string]cloud.Disk, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDisksByIDs", ctx, volumeIDs)
+	ret0, _ := ret[0].(map[string]cloud.Disk)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDisksByIDs indicates an expected call of GetDisksByIDs.
+func (mr *MockCloudMockRecorder) GetDisksByIDs(ctx, volumeIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDisksByIDs", reflect.TypeOf((*MockCloud)(nil).GetDisksByIDs), ctx, volumeIDs)
+}
+
 // IsExistInstance mocks base method.
 func (m *MockCloud) IsExistInstance(ctx context.Context, nodeID string) bool {
 	m.ctrl.T.Helper()
@@ -181,6 +239,36 @@ func (mr *MockCloudMockRecorder) IsExistInstance(ctx, nodeID interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsExistInstance", reflect.TypeOf((*MockCloud)(nil).IsExistInstance), ctx, nodeID)
 }
 
+// IsInstanceStopped mocks base method.
+func (m *MockCloud) IsInstanceStopped(ctx context.Context, nodeID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsInstanceStopped", ctx, nodeID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsInstanceStopped indicates an expected call of IsInstanceStopped.
+func (mr *MockCloudMockRecorder) IsInstanceStopped(ctx, nodeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsInstanceStopped", reflect.TypeOf((*MockCloud)(nil).IsInstanceStopped), ctx, nodeID)
+}
+
+// GetAttachedDisks mocks base method.
+func (m *MockCloud) GetAttachedDisks(ctx context.Context, nodeID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAttachedDisks", ctx, nodeID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAttachedDisks indicates an expected call of GetAttachedDisks.
+func (mr *MockCloudMockRecorder) GetAttachedDisks(ctx, nodeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAttachedDisks", reflect.TypeOf((*MockCloud)(nil).GetAttachedDisks), ctx, nodeID)
+}
+
 // CreateSnapshot mocks base method.
 func (m *MockCloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *cloud.SnapshotOptions) (cloud.Snapshot, error) {
 	m.ctrl.T.Helper()
@@ -255,3 +343,17 @@ func (mr *MockCloudMockRecorder) ListSnapshots(ctx, volumeID, maxResults, nextTo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSnapshots", reflect.TypeOf((*MockCloud)(nil).ListSnapshots), ctx, volumeID, maxResults, nextToken)
 }
+
+// GetDeviceAllocations mocks base method.
+func (m *MockCloud) GetDeviceAllocations() map[string]map[string]string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceAllocations")
+	ret0, _ := ret[0].(map[string]map[string]string)
+	return ret0
+}
+
+// GetDeviceAllocations indicates an expected call of GetDeviceAllocations.
+func (mr *MockCloudMockRecorder) GetDeviceAllocations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceAllocations", reflect.TypeOf((*MockCloud)(nil).GetDeviceAllocations))
+}
@@ -51,6 +51,20 @@ func (mr *MockMounterMockRecorder) CheckLuksPassphrase(devicePath, passphrase in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLuksPassphrase", reflect.TypeOf((*MockMounter)(nil).CheckLuksPassphrase), devicePath, passphrase)
 }
 
+// CheckFilesystem mocks base method.
+func (m *MockMounter) CheckFilesystem(device string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckFilesystem", device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckFilesystem indicates an expected call of CheckFilesystem.
+func (mr *MockMounterMockRecorder) CheckFilesystem(device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckFilesystem", reflect.TypeOf((*MockMounter)(nil).CheckFilesystem), device)
+}
+
 // Command mocks base method.
 func (m *MockMounter) Command(cmd string, args ...string) exec.Cmd {
 	m.ctrl.T.Helper()
@@ -118,6 +132,21 @@ func (mr *MockMounterMockRecorder) FormatAndMount(source, target, fstype, option
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FormatAndMount", reflect.TypeOf((*MockMounter)(nil).FormatAndMount), source, target, fstype, options)
 }
 
+// GetBlockSize mocks base method.
+func (m *MockMounter) GetBlockSize(devicePath string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockSize", devicePath)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockSize indicates an expected call of GetBlockSize.
+func (mr *MockMounterMockRecorder) GetBlockSize(devicePath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockSize", reflect.TypeOf((*MockMounter)(nil).GetBlockSize), devicePath)
+}
+
 // GetDeviceName mocks base method.
 func (m *MockMounter) GetDeviceName(mountPath string) (string, int, error) {
 	m.ctrl.T.Helper()
@@ -149,6 +178,66 @@ func (mr *MockMounterMockRecorder) GetDiskFormat(disk interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiskFormat", reflect.TypeOf((*MockMounter)(nil).GetDiskFormat), disk)
 }
 
+// GetFilesystemSize mocks base method.
+func (m *MockMounter) GetFilesystemSize(devicePath, mountPath string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystemSize", devicePath, mountPath)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilesystemSize indicates an expected call of GetFilesystemSize.
+func (mr *MockMounterMockRecorder) GetFilesystemSize(devicePath, mountPath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystemSize", reflect.TypeOf((*MockMounter)(nil).GetFilesystemSize), devicePath, mountPath)
+}
+
+// GetMountOptions mocks base method.
+func (m *MockMounter) GetMountOptions(mountPath string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMountOptions", mountPath)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMountOptions indicates an expected call of GetMountOptions.
+func (mr *MockMounterMockRecorder) GetMountOptions(mountPath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMountOptions", reflect.TypeOf((*MockMounter)(nil).GetMountOptions), mountPath)
+}
+
+// GetFilesystemUUID mocks base method.
+func (m *MockMounter) GetFilesystemUUID(devicePath string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystemUUID", devicePath)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilesystemUUID indicates an expected call of GetFilesystemUUID.
+func (mr *MockMounterMockRecorder) GetFilesystemUUID(devicePath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystemUUID", reflect.TypeOf((*MockMounter)(nil).GetFilesystemUUID), devicePath)
+}
+
+// GetDevicesByFilesystemUUID mocks base method.
+func (m *MockMounter) GetDevicesByFilesystemUUID(uuid string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDevicesByFilesystemUUID", uuid)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDevicesByFilesystemUUID indicates an expected call of GetDevicesByFilesystemUUID.
+func (mr *MockMounterMockRecorder) GetDevicesByFilesystemUUID(uuid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDevicesByFilesystemUUID", reflect.TypeOf((*MockMounter)(nil).GetDevicesByFilesystemUUID), uuid)
+}
+
 // GetMountRefs mocks base method.
 func (m *MockMounter) GetMountRefs(pathname string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -164,6 +253,21 @@ func (mr *MockMounterMockRecorder) GetMountRefs(pathname interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMountRefs", reflect.TypeOf((*MockMounter)(nil).GetMountRefs), pathname)
 }
 
+// IsBlockDevice mocks base method.
+func (m *MockMounter) IsBlockDevice(fullPath string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsBlockDevice", fullPath)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsBlockDevice indicates an expected call of IsBlockDevice.
+func (mr *MockMounterMockRecorder) IsBlockDevice(fullPath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsBlockDevice", reflect.TypeOf((*MockMounter)(nil).IsBlockDevice), fullPath)
+}
+
 // IsCorruptedMnt mocks base method.
 func (m *MockMounter) IsCorruptedMnt(arg0 error) bool {
 	m.ctrl.T.Helper()
@@ -310,6 +414,21 @@ func (mr *MockMounterMockRecorder) LuksResize(deviceName, passphrase interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LuksResize", reflect.TypeOf((*MockMounter)(nil).LuksResize), deviceName, passphrase)
 }
 
+// LuksSlotForPassphrase mocks base method.
+func (m *MockMounter) LuksSlotForPassphrase(devicePath, passphrase string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LuksSlotForPassphrase", devicePath, passphrase)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LuksSlotForPassphrase indicates an expected call of LuksSlotForPassphrase.
+func (mr *MockMounterMockRecorder) LuksSlotForPassphrase(devicePath, passphrase interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LuksSlotForPassphrase", reflect.TypeOf((*MockMounter)(nil).LuksSlotForPassphrase), devicePath, passphrase)
+}
+
 // MakeDir mocks base method.
 func (m *MockMounter) MakeDir(pathname string) error {
 	m.ctrl.T.Helper()
@@ -366,6 +485,63 @@ func (mr *MockMounterMockRecorder) MountSensitive(source, target, fstype, option
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MountSensitive", reflect.TypeOf((*MockMounter)(nil).MountSensitive), source, target, fstype, options, sensitiveOptions)
 }
 
+// ReadFile mocks base method.
+func (m *MockMounter) ReadFile(pathname string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadFile", pathname)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadFile indicates an expected call of ReadFile.
+func (mr *MockMounterMockRecorder) ReadFile(pathname interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadFile", reflect.TypeOf((*MockMounter)(nil).ReadFile), pathname)
+}
+
+// RemoveFile mocks base method.
+func (m *MockMounter) RemoveFile(pathname string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFile", pathname)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveFile indicates an expected call of RemoveFile.
+func (mr *MockMounterMockRecorder) RemoveFile(pathname interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFile", reflect.TypeOf((*MockMounter)(nil).RemoveFile), pathname)
+}
+
+// RepairFilesystem mocks base method.
+func (m *MockMounter) RepairFilesystem(device, fsType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RepairFilesystem", device, fsType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RepairFilesystem indicates an expected call of RepairFilesystem.
+func (mr *MockMounterMockRecorder) RepairFilesystem(device, fsType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RepairFilesystem", reflect.TypeOf((*MockMounter)(nil).RepairFilesystem), device, fsType)
+}
+
+// SetVolumeGroup mocks base method.
+func (m *MockMounter) SetVolumeGroup(mountPath string, gid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVolumeGroup", mountPath, gid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVolumeGroup indicates an expected call of SetVolumeGroup.
+func (mr *MockMounterMockRecorder) SetVolumeGroup(mountPath, gid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeGroup", reflect.TypeOf((*MockMounter)(nil).SetVolumeGroup), mountPath, gid)
+}
+
 // Unmount mocks base method.
 func (m *MockMounter) Unmount(target string) error {
 	m.ctrl.T.Helper()
@@ -379,3 +555,17 @@ func (mr *MockMounterMockRecorder) Unmount(target interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmount", reflect.TypeOf((*MockMounter)(nil).Unmount), target)
 }
+
+// WriteFile mocks base method.
+func (m *MockMounter) WriteFile(pathname string, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteFile", pathname, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteFile indicates an expected call of WriteFile.
+func (mr *MockMounterMockRecorder) WriteFile(pathname, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteFile", reflect.TypeOf((*MockMounter)(nil).WriteFile), pathname, data)
+}
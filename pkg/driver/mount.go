@@ -17,9 +17,16 @@ limitations under the License.
 package driver
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/luks"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
 	"k8s.io/utils/exec"
 	"k8s.io/utils/mount"
 )
@@ -33,9 +40,21 @@ type Mounter interface {
 	GetDiskFormat(disk string) (string, error)
 	GetDeviceName(mountPath string) (string, int, error)
 	MakeFile(pathname string) error
+	RemoveFile(pathname string) error
+	ReadFile(pathname string) ([]byte, error)
+	WriteFile(pathname string, data []byte) error
 	MakeDir(pathname string) error
 	ExistsPath(filename string) (bool, error)
 	IsCorruptedMnt(error) bool
+	RepairFilesystem(device string, fsType string) error
+	IsBlockDevice(fullPath string) (bool, error)
+	GetBlockSize(devicePath string) (int64, error)
+	GetFilesystemSize(devicePath string, mountPath string) (int64, error)
+	GetMountOptions(mountPath string) ([]string, error)
+	GetFilesystemUUID(devicePath string) (string, error)
+	GetDevicesByFilesystemUUID(uuid string) ([]string, error)
+	CheckFilesystem(device string) error
+	SetVolumeGroup(mountPath string, gid int64) error
 }
 
 type NodeMounter struct {
@@ -72,6 +91,22 @@ func (m *NodeMounter) MakeFile(pathname string) error {
 	return nil
 }
 
+// RemoveFile removes the file pathname, a missing file is not an error.
+func (m *NodeMounter) RemoveFile(pathname string) error {
+	if err := os.Remove(pathname); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (m *NodeMounter) ReadFile(pathname string) ([]byte, error) {
+	return os.ReadFile(pathname)
+}
+
+func (m *NodeMounter) WriteFile(pathname string, data []byte) error {
+	return os.WriteFile(pathname, data, os.FileMode(0644))
+}
+
 func (m *NodeMounter) MakeDir(pathname string) error {
 	err := os.MkdirAll(pathname, os.FileMode(0755))
 	if err != nil {
@@ -82,6 +117,31 @@ func (m *NodeMounter) MakeDir(pathname string) error {
 	return nil
 }
 
+// SetVolumeGroup gives the ownership of the files of the mount point to the group gid, the same way
+// the kubelet applies the fsGroup of a pod: the group gets read-write access and the setgid bit is set on the directories.
+func (m *NodeMounter) SetVolumeGroup(mountPath string, gid int64) error {
+	return filepath.WalkDir(mountPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(path, -1, int(gid)); err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode() | 0660
+		if d.IsDir() {
+			mode |= os.ModeSetgid | 0110
+		}
+		return os.Chmod(path, mode)
+	})
+}
+
 func (m *NodeMounter) ExistsPath(filename string) (bool, error) {
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		return false, nil
@@ -91,6 +151,180 @@ func (m *NodeMounter) ExistsPath(filename string) (bool, error) {
 	return true, nil
 }
 
+// IsBlockDevice returns true when the path is a block device.
+func (m *NodeMounter) IsBlockDevice(fullPath string) (bool, error) {
+	// Use stat to determine the kind of file this is.
+	var stat unix.Stat_t
+
+	err := unix.Stat(fullPath, &stat)
+	if err != nil {
+		return false, err
+	}
+
+	return (stat.Mode & unix.S_IFMT) == unix.S_IFBLK, nil
+}
+
+// GetBlockSize returns the size in bytes of the block device.
+func (m *NodeMounter) GetBlockSize(devicePath string) (int64, error) {
+	output, err := m.Command("blockdev", "--getsize64", devicePath).Output()
+	if err != nil {
+		return -1, fmt.Errorf("error when getting size of block volume at path %s: output: %s, err: %v", devicePath, string(output), err)
+	}
+	strOut := strings.TrimSpace(string(output))
+	gotSizeBytes, err := strconv.ParseInt(strOut, 10, 64)
+	if err != nil {
+		return -1, fmt.Errorf("failed to parse size %s as int", strOut)
+	}
+	return gotSizeBytes, nil
+}
+
+// GetFilesystemSize returns the size in bytes of the ext3, ext4 or xfs filesystem of the device mounted at mountPath.
+func (m *NodeMounter) GetFilesystemSize(devicePath string, mountPath string) (int64, error) {
+	format, err := m.GetDiskFormat(devicePath)
+	if err != nil {
+		return -1, err
+	}
+	var (
+		output                      []byte
+		sep, blockSizeKey, countKey string
+	)
+	switch format {
+	case FSTypeExt3, FSTypeExt4:
+		output, err = m.Command("dumpe2fs", "-h", devicePath).CombinedOutput()
+		sep, blockSizeKey, countKey = ":", "block size", "block count"
+	case FSTypeXfs:
+		output, err = m.Command("xfs_io", "-c", "statfs", mountPath).CombinedOutput()
+		sep, blockSizeKey, countKey = "=", "geom.bsize", "geom.datablocks"
+	default:
+		return -1, fmt.Errorf("the size of the %q filesystem of %s is not supported", format, devicePath)
+	}
+	if err != nil {
+		return -1, fmt.Errorf("could not read the size of the filesystem of %s: %v, output: %s", devicePath, err, string(output))
+	}
+
+	var blockSize, blockCount int64
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, found := strings.Cut(line, sep)
+		if !found {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case blockSizeKey:
+			blockSize, err = strconv.ParseInt(value, 10, 64)
+		case countKey:
+			blockCount, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return -1, fmt.Errorf("failed to parse %s %q of %s: %v", key, value, devicePath, err)
+		}
+	}
+	if blockSize == 0 || blockCount == 0 {
+		return -1, fmt.Errorf("could not find the block size and count of the filesystem of %s", devicePath)
+	}
+	return blockSize * blockCount, nil
+}
+
+// GetMountOptions returns the current options of the mount point.
+func (m *NodeMounter) GetMountOptions(mountPath string) ([]string, error) {
+	mountPoints, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, mp := range mountPoints {
+		if mp.Path == mountPath {
+			return mp.Opts, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not a mount point", mountPath)
+}
+
+// GetFilesystemUUID returns the UUID of the filesystem of the device.
+func (m *NodeMounter) GetFilesystemUUID(devicePath string) (string, error) {
+	output, err := m.Command("blkid", "-s", "UUID", "-o", "value", devicePath).Output()
+	if err != nil {
+		return "", fmt.Errorf("could not get the filesystem UUID of %s: output: %s, err: %v", devicePath, string(output), err)
+	}
+	uuid := strings.TrimSpace(string(output))
+	if uuid == "" {
+		return "", fmt.Errorf("no filesystem UUID found on %s", devicePath)
+	}
+	return uuid, nil
+}
+
+// GetDevicesByFilesystemUUID returns the devices holding a filesystem with the UUID, which are the candidates
+// of mount for UUID=<uuid>. The volumes restored from the same snapshot share the UUID of their filesystem.
+func (m *NodeMounter) GetDevicesByFilesystemUUID(uuid string) ([]string, error) {
+	// The cache of blkid is bypassed, it may miss the devices attached since it was written
+	output, err := m.Command("blkid", "-c", "/dev/null", "-t", "UUID="+uuid, "-o", "device").Output()
+	if err != nil {
+		// blkid exits with 2 when no device matches
+		if exitErr, ok := err.(exec.ExitError); ok && exitErr.ExitStatus() == 2 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not find the devices with the filesystem UUID %s: output: %s, err: %v", uuid, string(output), err)
+	}
+	var devices []string
+	for _, device := range strings.Fields(string(output)) {
+		devices = append(devices, resolveDevicePath(device))
+	}
+	return devices, nil
+}
+
+// CheckFilesystem fixes the errors of the filesystem of the device which can be fixed safely,
+// as FormatAndMount does before mounting a formatted device.
+func (m *NodeMounter) CheckFilesystem(device string) error {
+	klog.V(4).Infof("CheckFilesystem: running fsck -a %s", device)
+	out, err := m.Command("fsck", "-a", device).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	exitErr, isExitErr := err.(exec.ExitError)
+	switch {
+	case err == exec.ErrExecutableNotFound:
+		klog.Warningf("CheckFilesystem: fsck not found, mounting %s without checking it", device)
+	case isExitErr && exitErr.ExitStatus() == 1:
+		klog.Infof("CheckFilesystem: errors were corrected on %s: %s", device, string(out))
+	case isExitErr && exitErr.ExitStatus() == 4:
+		return fmt.Errorf("fsck found errors on %s but could not correct them: %s", device, string(out))
+	default:
+		klog.Warningf("CheckFilesystem: fsck on %s failed: %v, output: %s", device, err, string(out))
+	}
+	return nil
+}
+
+// resolveDevicePath returns the device the symbolic links of devicePath lead to, or devicePath when it cannot be resolved.
+func resolveDevicePath(devicePath string) string {
+	resolved, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return devicePath
+	}
+	return resolved
+}
+
+// RepairFilesystem checks the filesystem of the device and fixes the errors found.
+func (m *NodeMounter) RepairFilesystem(device string, fsType string) error {
+	cmd := "fsck"
+	args := []string{"-y", device}
+	if fsType == FSTypeXfs {
+		// fsck.xfs does nothing, xfs_repair must be used instead
+		cmd = "xfs_repair"
+		args = []string{device}
+	}
+
+	klog.V(4).Infof("RepairFilesystem: running %s %v", cmd, args)
+	out, err := m.Command(cmd, args...).CombinedOutput()
+	if err != nil {
+		// fsck exits with 1 when errors were found and corrected
+		if exitErr, ok := err.(exec.ExitError); ok && cmd == "fsck" && exitErr.ExitStatus() == 1 {
+			klog.Warningf("RepairFilesystem: errors were corrected on %s: %s", device, string(out))
+			return nil
+		}
+		return fmt.Errorf("could not repair filesystem on %s: %v, output: %s", device, err, string(out))
+	}
+	return nil
+}
+
 func (m *NodeMounter) IsLuks(devicePath string) bool {
 	return IsLuks(m, devicePath)
 }
@@ -103,6 +337,10 @@ func (m *NodeMounter) CheckLuksPassphrase(devicePath string, passphrase string)
 	return CheckLuksPassphrase(m, devicePath, passphrase)
 }
 
+func (m *NodeMounter) LuksSlotForPassphrase(devicePath string, passphrase string) (int, error) {
+	return LuksSlotForPassphrase(m, devicePath, passphrase)
+}
+
 func (m *NodeMounter) LuksOpen(devicePath string, encryptedDeviceName string, passphrase string) (bool, error) {
 	return LuksOpen(m, devicePath, encryptedDeviceName, passphrase)
 }
@@ -0,0 +1,48 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// mountProfiles maps a profile name to its mount flags.
+type mountProfiles map[string][]string
+
+// loadMountProfiles reads the mount profiles from a JSON file (usually a mounted ConfigMap) like:
+//
+//	{"database": ["noatime", "nodiratime"], "logs": ["noatime"]}
+//
+// An empty path returns no profiles.
+func loadMountProfiles(path string) (mountProfiles, error) {
+	profiles := mountProfiles{}
+	if path == "" {
+		return profiles, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read mount profiles file %q: %v", path, err)
+	}
+	if err := json.Unmarshal(content, &profiles); err != nil {
+		return nil, fmt.Errorf("could not parse mount profiles file %q: %v", path, err)
+	}
+	for name, flags := range profiles {
+		for _, f := range flags {
+			if f == "" || strings.Contains(f, ",") {
+				return nil, fmt.Errorf("invalid mount flag %q in mount profile %q", f, name)
+			}
+		}
+	}
+	return profiles, nil
+}
+
+// resolve returns the mount flags of the profile, joined to be passed in the publish context.
+func (p mountProfiles) resolve(name string) (string, error) {
+	flags, ok := p[name]
+	if !ok {
+		return "", fmt.Errorf("unknown mount profile %q", name)
+	}
+	return strings.Join(flags, ","), nil
+}
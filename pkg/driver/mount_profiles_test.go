@@ -0,0 +1,77 @@
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadMountProfiles(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		expProfiles mountProfiles
+		expErr      bool
+	}{
+		{
+			name:    "success",
+			content: `{"database": ["noatime", "nodiratime"], "logs": ["noatime"]}`,
+			expProfiles: mountProfiles{
+				"database": {"noatime", "nodiratime"},
+				"logs":     {"noatime"},
+			},
+		},
+		{
+			name:    "fail invalid json",
+			content: `database: noatime`,
+			expErr:  true,
+		},
+		{
+			name:    "fail invalid mount flag",
+			content: `{"database": ["noatime,nodiratime"]}`,
+			expErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "profiles.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("could not write profiles file: %v", err)
+			}
+
+			profiles, err := loadMountProfiles(path)
+			if tc.expErr {
+				if err == nil {
+					t.Fatalf("Expected error, got nothing")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(profiles, tc.expProfiles) {
+				t.Fatalf("Expected profiles %v, got %v", tc.expProfiles, profiles)
+			}
+		})
+	}
+}
+
+func TestResolveMountProfile(t *testing.T) {
+	profiles := mountProfiles{
+		"database": {"noatime", "nodiratime"},
+	}
+
+	options, err := profiles.resolve("database")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if options != "noatime,nodiratime" {
+		t.Fatalf("Expected options %q, got %q", "noatime,nodiratime", options)
+	}
+
+	if _, err := profiles.resolve("unknown"); err == nil {
+		t.Fatalf("Expected error for unknown profile, got nothing")
+	}
+}
@@ -17,10 +17,14 @@ limitations under the License.
 package driver
 
 import (
-	"io/ioutil"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/mocks"
+	"k8s.io/utils/mount"
 )
 
 func TestMakeDir(t *testing.T) {
@@ -78,6 +82,31 @@ func TestMakeFile(t *testing.T) {
 
 }
 
+func TestRemoveFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mount-bsu-csi")
+	if err != nil {
+		t.Fatalf("error creating directory %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	targetPath := filepath.Join(dir, "targetfile")
+	mountObj := newNodeMounter()
+
+	if err := mountObj.MakeFile(targetPath); err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+	if err := mountObj.RemoveFile(targetPath); err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+	if exists, err := mountObj.ExistsPath(targetPath); err != nil || exists {
+		t.Fatalf("Expect %s to be removed, exists: %v, err: %v", targetPath, exists, err)
+	}
+	// Removing a missing file is not an error
+	if err := mountObj.RemoveFile(targetPath); err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+}
+
 func TestExistsPath(t *testing.T) {
 	// Setup the full driver and its environment
 	dir, err :=os.MkdirTemp("", "mount-bsu-csi")
@@ -123,3 +152,70 @@ func TestGetDeviceName(t *testing.T) {
 	}
 
 }
+
+func TestGetBlockSize(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	devicePath := "/dev/fake"
+
+	mockCommand := mocks.NewMockInterface(mockCtl)
+	mockRun := mocks.NewMockCmd(mockCtl)
+	mockCommand.EXPECT().Command(gomock.Eq("blockdev"), gomock.Eq("--getsize64"), gomock.Eq(devicePath)).Return(mockRun)
+	mockRun.EXPECT().Output().Return([]byte("10737418240\n"), nil)
+	mountObj := &NodeMounter{Interface: mockCommand}
+	size, err := mountObj.GetBlockSize(devicePath)
+	if err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+	if size != 10737418240 {
+		t.Fatalf("Expected size 10737418240, got %d", size)
+	}
+
+	mockCommand = mocks.NewMockInterface(mockCtl)
+	mockRun = mocks.NewMockCmd(mockCtl)
+	mockCommand.EXPECT().Command(gomock.Eq("blockdev"), gomock.Eq("--getsize64"), gomock.Eq(devicePath)).Return(mockRun)
+	mockRun.EXPECT().Output().Return([]byte{}, fmt.Errorf("error"))
+	mountObj = &NodeMounter{Interface: mockCommand}
+	if _, err := mountObj.GetBlockSize(devicePath); err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestGetFilesystemSize(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	devicePath := "/dev/fake"
+	mountPath := "/test/path"
+
+	// ext4, read from the superblock of the device
+	mockCommand := mocks.NewMockInterface(mockCtl)
+	mockBlkid := mocks.NewMockCmd(mockCtl)
+	mockBlkid.EXPECT().CombinedOutput().Return([]byte("TYPE=ext4\n"), nil)
+	mockCommand.EXPECT().Command(gomock.Eq("blkid"), gomock.Any()).Return(mockBlkid)
+	mockRun := mocks.NewMockCmd(mockCtl)
+	mockRun.EXPECT().CombinedOutput().Return([]byte("Filesystem volume name:   <none>\nBlock count:              2621440\nBlock size:               4096\n"), nil)
+	mockCommand.EXPECT().Command(gomock.Eq("dumpe2fs"), gomock.Eq("-h"), gomock.Eq(devicePath)).Return(mockRun)
+	mountObj := &NodeMounter{SafeFormatAndMount: mount.SafeFormatAndMount{Exec: mockCommand}, Interface: mockCommand}
+	size, err := mountObj.GetFilesystemSize(devicePath, mountPath)
+	if err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+	if size != 10737418240 {
+		t.Fatalf("Expected size 10737418240, got %d", size)
+	}
+
+	// xfs, read from the mounted filesystem
+	mockCommand = mocks.NewMockInterface(mockCtl)
+	mockBlkid = mocks.NewMockCmd(mockCtl)
+	mockBlkid.EXPECT().CombinedOutput().Return([]byte("TYPE=xfs\n"), nil)
+	mockCommand.EXPECT().Command(gomock.Eq("blkid"), gomock.Any()).Return(mockBlkid)
+	mockRun = mocks.NewMockCmd(mockCtl)
+	mockRun.EXPECT().CombinedOutput().Return([]byte("fd.path = \"/test/path\"\ngeom.bsize = 4096\ngeom.agcount = 4\ngeom.datablocks = 2621440\n"), nil)
+	mockCommand.EXPECT().Command(gomock.Eq("xfs_io"), gomock.Eq("-c"), gomock.Eq("statfs"), gomock.Eq(mountPath)).Return(mockRun)
+	mountObj = &NodeMounter{SafeFormatAndMount: mount.SafeFormatAndMount{Exec: mockCommand}, Interface: mockCommand}
+	size, err = mountObj.GetFilesystemSize(devicePath, mountPath)
+	if err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+	if size != 10737418240 {
+		t.Fatalf("Expected size 10737418240, got %d", size)
+	}
+}
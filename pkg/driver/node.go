@@ -18,14 +18,16 @@ package driver
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
-
-	"golang.org/x/sys/unix"
+	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
@@ -33,6 +35,7 @@ import (
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/luks"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/volume"
 	mountutils "k8s.io/mount-utils"
@@ -66,28 +69,33 @@ var (
 		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
 		csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
 		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
 	}
 )
 
 // nodeService represents the node service of CSI driver
 type nodeService struct {
-	metadata cloud.MetadataService
-	mounter  Mounter
-	inFlight *internal.InFlight
+	metadata       cloud.MetadataService
+	mounter        Mounter
+	inFlight       *internal.InFlight
+	driverOptions  *DriverOptions
+	secretProvider SecretProvider
 }
 
 // newNodeService creates a new node service
 // it panics if failed to create the service
-func newNodeService() nodeService {
+func newNodeService(driverOptions *DriverOptions) nodeService {
 	metadata, err := cloud.NewMetadata()
 	if err != nil {
 		panic(err)
 	}
 
 	return nodeService{
-		metadata: metadata,
-		mounter:  newNodeMounter(),
-		inFlight: internal.NewInFlight(),
+		metadata:       metadata,
+		mounter:        newNodeMounter(),
+		inFlight:       internal.NewInFlight(),
+		driverOptions:  driverOptions,
+		secretProvider: newSecretProvider(driverOptions.secretProviderURL),
 	}
 }
 
@@ -109,10 +117,14 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, status.Error(codes.InvalidArgument, "Volume capability not provided")
 	}
 
-	if !isValidVolumeCapabilities([]*csi.VolumeCapability{volCap}) {
+	if !isValidNodeVolumeCapability(volCap) {
 		return nil, status.Error(codes.InvalidArgument, "Volume capability not supported")
 	}
 
+	if d.driverOptions.requireEncryption && req.PublishContext[EncryptedKey] != "true" {
+		return nil, status.Errorf(codes.FailedPrecondition, "Encryption is required, volume %s is not encrypted", volumeID)
+	}
+
 	// If the access type is block, do nothing for stage
 	switch volCap.GetAccessType().(type) {
 	case *csi.VolumeCapability_Block:
@@ -129,12 +141,34 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		fsType = defaultFsType
 	}
 
-	var mountOptions []string
-	for _, f := range mount.MountFlags {
-		if !hasMountOption(mountOptions, f) {
-			mountOptions = append(mountOptions, f)
+	mountFlags := append([]string{}, mount.MountFlags...)
+	mountFlags = append(mountFlags, req.PublishContext[MountProfileOptionsKey])
+	if mode := req.PublishContext[JournalModeKey]; mode != "" {
+		if err := validateJournalMode(mode, fsType); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "NodeStageVolume: %v", err)
+		}
+		mountFlags = append(mountFlags, "data="+mode)
+	}
+	preallocate := req.PublishContext[PreallocateKey] == "true"
+	if preallocate {
+		if err := validatePreallocate(fsType); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "NodeStageVolume: %v", err)
 		}
 	}
+	readOnly := isReadOnlyVolumeCapability(volCap)
+	if readOnly {
+		// The staging path is mounted read-only as well, not only the bind mounts of the pods
+		mountFlags = append(mountFlags, "ro")
+	}
+	mountOptions, err := normalizeMountOptions(mountFlags)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "NodeStageVolume: invalid mount flags: %v", err)
+	}
+
+	fsGroup, err := d.volumeMountGroup(volCap)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "NodeStageVolume: %v", err)
+	}
 
 	if ok := d.inFlight.Insert(req); !ok {
 		msg := fmt.Sprintf("request to stage volume=%q is already in progress", volumeID)
@@ -151,12 +185,16 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, status.Error(codes.InvalidArgument, "Device path not provided")
 	}
 
-	source, err := d.findDevicePath(devicePath, volumeID)
+	source, err := d.waitForDevicePath(ctx, devicePath, volumeID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to find device path %s. %v", devicePath, err)
 	}
 
-	klog.V(4).Infof("NodeStageVolume: find device path %s -> %s", devicePath, source)
+	klog.V(2).Infof("NodeStageVolume: volume %s resolved device path %s -> %s", volumeID, devicePath, source)
+
+	if err := d.checkDeviceSize(volumeID, source, req.PublishContext[VolumeSizeKey]); err != nil {
+		return nil, err
+	}
 
 	exists, err := d.mounter.ExistsPath(target)
 	if err != nil {
@@ -197,13 +235,19 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		encryptedDevicePath = fmt.Sprintf("/dev/mapper/%v", encryptedDeviceName)
 
 		if device == encryptedDevicePath {
+			if err := d.checkStagedCapability(volumeID, device, target, mount.GetFsType(), readOnly); err != nil {
+				return nil, err
+			}
 			klog.V(4).Infof("NodeStageVolume: volume=%q already staged (encryption)", volumeID)
 			return &csi.NodeStageVolumeResponse{}, nil
 		}
+		if err := d.checkNotStagedElsewhere(volumeID, encryptedDevicePath, target); err != nil {
+			return nil, err
+		}
 
-		passphrase, ok := req.Secrets[LuksPassphraseKey]
-		if !ok {
-			return nil, status.Error(codes.InvalidArgument, "no passphrase key has been provided")
+		passphrase, err := d.getPassphrase(ctx, volumeID, req.Secrets)
+		if err != nil {
+			return nil, err
 		}
 
 		// Check if the disk needs encryption
@@ -220,18 +264,21 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 			}
 		}
 
-		// Check passphrase
-		if ok := d.mounter.CheckLuksPassphrase(source, passphrase); !ok {
+		// Check passphrase, several key slots may be in use during a rotation
+		slot, err := d.mounter.LuksSlotForPassphrase(source, passphrase)
+		if err != nil {
 			msg := fmt.Sprintf("error while checking passphrase to %v, err: %v", volumeID, err)
 			return nil, status.Error(codes.Internal, msg)
 		}
+		klog.V(4).Infof("NodeStageVolume: passphrase of volume %s matches LUKS key slot %d", volumeID, slot)
 
 		// Open disk
-		if _, err := d.mounter.LuksOpen(source, encryptedDeviceName, passphrase); err != nil {
+		if err := d.luksOpen(ctx, source, encryptedDeviceName, passphrase); err != nil {
 			msg := fmt.Sprintf("error while opening luks device to %v, err: %v", volumeID, err)
 			return nil, status.Error(codes.Internal, msg)
 		}
 
+		klog.V(2).Infof("NodeStageVolume: volume %s opened LUKS device %s -> %s", volumeID, source, encryptedDevicePath)
 		source = encryptedDevicePath
 
 	} else {
@@ -239,9 +286,15 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		// If the volume corresponding to the volume_id is already staged to the staging_target_path,
 		// and is identical to the specified volume_capability the Plugin MUST reply 0 OK.
 		if device == source {
+			if err := d.checkStagedCapability(volumeID, device, target, mount.GetFsType(), readOnly); err != nil {
+				return nil, err
+			}
 			klog.V(4).Infof("NodeStageVolume: volume=%q already staged", volumeID)
 			return &csi.NodeStageVolumeResponse{}, nil
 		}
+		if err := d.checkNotStagedElsewhere(volumeID, source, target); err != nil {
+			return nil, err
+		}
 	}
 
 	existingFormat, err := d.mounter.GetDiskFormat(source)
@@ -255,25 +308,58 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, status.Error(codes.Internal, fmt.Sprintf("%vfailed to get disk format of disk %q: %v", msg, source, err))
 	}
 
+	if d.driverOptions.formatTimeout > 0 {
+		incomplete, err := d.mounter.ExistsPath(formatMarkerPath(target))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "could not check the format marker of %q: %v", target, err)
+		}
+		if incomplete {
+			klog.Warningf("NodeStageVolume: the previous format of volume %s did not complete, reformatting %s", volumeID, source)
+			existingFormat = ""
+		}
+	}
+
 	if existingFormat != "" && existingFormat != fsType {
-		if len(mount.GetFsType()) == 0 {
+		switch {
+		case len(mount.GetFsType()) == 0:
 			// The default FStype will break the disk, switching to existingFormat
 			klog.Warningf("NodeStageVolume: The default fstype %q does not match the fstype of the disk %q. Please update your StorageClass.", defaultFsType, existingFormat)
 			fsType = existingFormat
-		} else {
+		case d.driverOptions.allowFsTypeMismatch:
+			klog.Warningf("NodeStageVolume: The requested fstype %q does not match the fstype %q of volume %s, mounting it as %q.", fsType, existingFormat, volumeID, existingFormat)
+			fsType = existingFormat
+		default:
 			msg := ""
 			if isEncrypted {
 				if closeError := d.mounter.LuksClose(encryptedDeviceName); closeError != nil {
 					msg = fmt.Sprintf("error when closing the disk but ignoring (%v) and ", closeError)
 				}
 			}
-			return nil, status.Error(codes.Internal, fmt.Sprintf("NodeStageVolume: %vThe requested fstype %q does not match the fstype of the disk %q", msg, fsType, existingFormat))
+			return nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("NodeStageVolume: %vThe requested fstype %q does not match the fstype %q of volume %s, the volume is not reformatted. Update the fstype of the StorageClass or allow the mismatch with --allow-fs-type-mismatch", msg, fsType, existingFormat, volumeID))
 		}
 	}
 
+	formatted := false
+	if d.driverOptions.formatTimeout > 0 && existingFormat == "" {
+		if err := d.formatWithTimeout(ctx, source, target, fsType, preallocate); err != nil {
+			msg := ""
+			if isEncrypted {
+				if closeError := d.mounter.LuksClose(encryptedDeviceName); closeError != nil {
+					msg = fmt.Sprintf("error when closing the disk but ignoring (%v) and ", closeError)
+				}
+			}
+			code := codes.Internal
+			if errors.Is(err, context.DeadlineExceeded) {
+				code = codes.DeadlineExceeded
+			}
+			return nil, status.Error(code, fmt.Sprintf("%vcould not format %q: %v", msg, source, err))
+		}
+		formatted = true
+	}
+
 	klog.V(5).Infof("NodeStageVolume: formatting %s and mounting at %s with fstype %s", source, target, fsType)
 	if FSTypeXfs == fsType {
-		if existingFormat == "" {
+		if existingFormat == "" && !formatted && !readOnly {
 			argsXfs := []string{source}
 			klog.V(5).Infof("NodeStageVolume: xfs case mkfs %v ", argsXfs)
 			cmdOut, cmdErr := d.mounter.Command("mkfs.xfs", argsXfs...).CombinedOutput()
@@ -284,8 +370,61 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		}
 	}
 
-	// FormatAndMount will format only if needed
-	err = d.mounter.FormatAndMount(source, target, fsType, mountOptions)
+	if preallocate && existingFormat == "" && !formatted {
+		if err := d.formatPreallocated(source, fsType); err != nil {
+			msg := ""
+			if isEncrypted {
+				if closeError := d.mounter.LuksClose(encryptedDeviceName); closeError != nil {
+					msg = fmt.Sprintf("error when closing the disk but ignoring (%v) and ", closeError)
+				}
+			}
+			return nil, status.Error(codes.Internal, fmt.Sprintf("%vcould not format %q: %v", msg, source, err))
+		}
+	}
+
+	repairOnMount := req.PublishContext[RepairOnMountKey] == "true"
+	if repairOnMount && existingFormat != "" && !readOnly {
+		klog.V(4).Infof("NodeStageVolume: repairing filesystem of %s before mount", source)
+		if err := d.mounter.RepairFilesystem(source, fsType); err != nil {
+			msg := ""
+			if isEncrypted {
+				if closeError := d.mounter.LuksClose(encryptedDeviceName); closeError != nil {
+					msg = fmt.Sprintf("error when closing the disk but ignoring (%v) and ", closeError)
+				}
+			}
+			return nil, status.Error(codes.Internal, fmt.Sprintf("%vcould not repair filesystem of %q: %v", msg, source, err))
+		}
+	}
+
+	filesystemUUID := ""
+	if d.driverOptions.mountByUUID && !isEncrypted {
+		// The filesystem recorded at a previous stage is mounted even when the device path changed
+		if existingFormat != "" {
+			metadata, err := d.readStagingMetadata(target)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "could not read the staging metadata of %q: %v", target, err)
+			}
+			filesystemUUID = metadata.FilesystemUUID
+		}
+		// The xfs and preallocated volumes are already formatted. As FormatAndMount, the read-only volumes
+		// are never formatted, and the others are checked before being mounted
+		format := existingFormat == "" && !preallocate && FSTypeXfs != fsType && !formatted
+		if existingFormat == "" && readOnly {
+			return nil, status.Errorf(codes.FailedPrecondition, "cannot format the volume %s staged as read-only", volumeID)
+		}
+		if existingFormat != "" && !readOnly {
+			if err := d.mounter.CheckFilesystem(source); err != nil {
+				return nil, status.Errorf(codes.Internal, "could not check the filesystem of %q: %v", source, err)
+			}
+		}
+		filesystemUUID, err = d.mountByUUID(volumeID, source, target, fsType, filesystemUUID, format, mountOptions)
+		if status.Code(err) == codes.FailedPrecondition {
+			return nil, err
+		}
+	} else {
+		// FormatAndMount will format only if needed
+		err = d.mounter.FormatAndMount(source, target, fsType, mountOptions)
+	}
 	if err != nil {
 		msg := ""
 		if isEncrypted {
@@ -297,9 +436,256 @@ func (d *nodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, status.Error(codes.Internal, msg)
 	}
 
+	if fsGroup != nil {
+		klog.V(5).Infof("NodeStageVolume: setting the group of %s to %d", target, *fsGroup)
+		if err := d.mounter.SetVolumeGroup(target, *fsGroup); err != nil {
+			return nil, status.Errorf(codes.Internal, "could not set the group of %q to %d: %v", target, *fsGroup, err)
+		}
+	}
+
+	if metadata := (stagingMetadata{ReadOnly: readOnly, FilesystemUUID: filesystemUUID}); metadata != (stagingMetadata{}) {
+		if err := d.writeStagingMetadata(target, metadata); err != nil {
+			return nil, status.Errorf(codes.Internal, "could not record the staging metadata of %q: %v", target, err)
+		}
+	}
+
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
+// isReadOnlyVolumeCapability returns true when the volume is accessed read-only.
+func isReadOnlyVolumeCapability(volCap *csi.VolumeCapability) bool {
+	return volCap.GetAccessMode().GetMode() == csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY
+}
+
+// isValidNodeVolumeCapability returns true when the node supports volCap, the access modes of the controller
+// and SINGLE_NODE_READER_ONLY for the volumes it does not provision.
+func isValidNodeVolumeCapability(volCap *csi.VolumeCapability) bool {
+	return isValidVolumeCapabilities([]*csi.VolumeCapability{volCap}) || isReadOnlyVolumeCapability(volCap)
+}
+
+// checkDeviceSize compares the size of the device with the size of the volume published by the controller.
+// A smaller device reveals a stale device: it is logged, or fails the staging with --strict-device-size-check.
+// A larger device is expected once the volume has been expanded, the published size is the one at attach time.
+func (d *nodeService) checkDeviceSize(volumeID string, devicePath string, volumeSize string) error {
+	if volumeSize == "" {
+		return nil
+	}
+	expectedBytes, err := strconv.ParseInt(volumeSize, 10, 64)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid size %q of volume %s: %v", volumeSize, volumeID, err)
+	}
+
+	var msg string
+	deviceBytes, err := d.mounter.GetBlockSize(devicePath)
+	switch {
+	case err != nil:
+		msg = fmt.Sprintf("could not get the size of device %s of volume %s: %v", devicePath, volumeID, err)
+	case deviceBytes < expectedBytes:
+		msg = fmt.Sprintf("device %s of volume %s has a size of %d bytes instead of %d bytes, the device may be stale", devicePath, volumeID, deviceBytes, expectedBytes)
+	default:
+		return nil
+	}
+	if d.driverOptions.strictDeviceSizeCheck {
+		return status.Error(codes.FailedPrecondition, msg)
+	}
+	klog.Warningf("NodeStageVolume: %s", msg)
+	return nil
+}
+
+// checkNotStagedElsewhere returns FailedPrecondition when device is mounted at another path than the staging path target,
+// which means the volume is already staged for another request.
+func (d *nodeService) checkNotStagedElsewhere(volumeID, device, target string) error {
+	mountPoints, err := d.mounter.List()
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list the mount points: %v", err)
+	}
+	for _, mp := range mountPoints {
+		if mp.Device == device && mp.Path != target {
+			return status.Errorf(codes.FailedPrecondition, "NodeStageVolume: volume %s is already staged at %q, not staging it at %q", volumeID, mp.Path, target)
+		}
+	}
+	return nil
+}
+
+// checkStagedCapability returns AlreadyExists when the volume staged from device at target does not have
+// the fstype fsType, unless it is empty or the mismatch is allowed, or is not mounted with the access mode readOnly.
+func (d *nodeService) checkStagedCapability(volumeID, device, target, fsType string, readOnly bool) error {
+	if fsType != "" && !d.driverOptions.allowFsTypeMismatch {
+		existingFormat, err := d.mounter.GetDiskFormat(device)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to get disk format of disk %q: %v", device, err)
+		}
+		if existingFormat != "" && existingFormat != fsType {
+			return status.Errorf(codes.AlreadyExists, "NodeStageVolume: volume %s is already staged at %q with fstype %q, not %q", volumeID, target, existingFormat, fsType)
+		}
+	}
+	mountPoints, err := d.mounter.List()
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list the mount points: %v", err)
+	}
+	for _, mp := range mountPoints {
+		if mp.Path == target && slices.Contains(mp.Opts, "ro") != readOnly {
+			return status.Errorf(codes.AlreadyExists, "NodeStageVolume: volume %s is already staged at %q with read-only %v, not %v", volumeID, target, !readOnly, readOnly)
+		}
+	}
+	return nil
+}
+
+// mountByUUID formats source if needed, then mounts it at target by the UUID of its filesystem,
+// which stays the same when the device path of the volume changes. The UUID is read from source
+// unless uuid is not empty. It returns the UUID of the mounted filesystem.
+// A UUID which is not held by source fails with FailedPrecondition. A UUID also held by another device,
+// such as a volume restored from the same snapshot, is ambiguous: source is then mounted by its device path.
+func (d *nodeService) mountByUUID(volumeID, source, target, fsType, uuid string, format bool, mountOptions []string) (string, error) {
+	if format {
+		args := mkfsArgs(source, fsType, false)
+		klog.V(5).Infof("Formatting %s: mkfs.%s %v", source, fsType, args)
+		if out, err := d.mounter.Command("mkfs."+fsType, args...).CombinedOutput(); err != nil {
+			return "", fmt.Errorf("mkfs.%s failed: %v, output: %s", fsType, err, string(out))
+		}
+	}
+	if uuid == "" {
+		var err error
+		if uuid, err = d.mounter.GetFilesystemUUID(source); err != nil {
+			return "", err
+		}
+	}
+
+	devices, err := d.mounter.GetDevicesByFilesystemUUID(uuid)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(devices, resolveDevicePath(source)) {
+		return "", status.Errorf(codes.FailedPrecondition, "the filesystem UUID %s of volume %s is held by %v, not by %s", uuid, volumeID, devices, source)
+	}
+	if len(devices) > 1 {
+		klog.Warningf("NodeStageVolume: the filesystem UUID %s of volume %s is also held by %v, mounting %s by its device path", uuid, volumeID, devices, source)
+		return uuid, d.mounter.Mount(source, target, fsType, mountOptions)
+	}
+	klog.V(4).Infof("NodeStageVolume: mounting volume %s by filesystem UUID %s", volumeID, uuid)
+	return uuid, d.mounter.Mount("UUID="+uuid, target, fsType, mountOptions)
+}
+
+// formatPreallocated formats source as an ext filesystem without discarding its blocks nor initializing
+// the inode tables and the journal lazily, so that FormatAndMount finds it formatted and only mounts it.
+func (d *nodeService) formatPreallocated(source, fsType string) error {
+	args := mkfsArgs(source, fsType, true)
+	klog.V(5).Infof("Formatting %s with preallocation: mkfs.%s %v", source, fsType, args)
+	if out, err := d.mounter.Command("mkfs."+fsType, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("mkfs.%s failed: %v, output: %s", fsType, err, string(out))
+	}
+	return nil
+}
+
+// formatWithTimeout formats source, failing with context.DeadlineExceeded when mkfs does not complete within
+// the format timeout. The format marker of the staging target path is kept until the format completes, so that
+// the retry after a timeout or a restart reformats the device instead of mounting a half-formatted filesystem.
+func (d *nodeService) formatWithTimeout(ctx context.Context, source, target, fsType string, preallocate bool) error {
+	marker := formatMarkerPath(target)
+	if err := d.mounter.MakeFile(marker); err != nil {
+		return fmt.Errorf("could not create the format marker %q: %v", marker, err)
+	}
+
+	timeout := d.driverOptions.formatTimeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	args := mkfsArgs(source, fsType, preallocate)
+	klog.V(5).Infof("Formatting %s within %v: mkfs.%s %v", source, timeout, fsType, args)
+	out, err := d.mounter.CommandContext(ctx, "mkfs."+fsType, args...).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("mkfs.%s did not complete within %v, the device will be reformatted on retry: %w", fsType, timeout, ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("mkfs.%s failed: %v, output: %s", fsType, err, string(out))
+	}
+
+	if err := d.mounter.RemoveFile(marker); err != nil {
+		return fmt.Errorf("could not remove the format marker %q: %v", marker, err)
+	}
+	return nil
+}
+
+// formatMarkerPath returns the path of the file marking the format of the volume staged at target as in progress.
+func formatMarkerPath(target string) string {
+	return target + ".format-incomplete"
+}
+
+// stagingMetadata is recorded by NodeStageVolume next to the staging target path,
+// for the calls which do not get the capability of the volume.
+type stagingMetadata struct {
+	// ReadOnly is true when the volume is staged read-only
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// FilesystemUUID is the UUID of the filesystem mounted by --mount-by-uuid
+	FilesystemUUID string `json:"filesystemUUID,omitempty"`
+}
+
+// stagingMetadataPath returns the path of the staging metadata of the volume staged at target.
+func stagingMetadataPath(target string) string {
+	return target + ".staging.json"
+}
+
+func (d *nodeService) writeStagingMetadata(target string, metadata stagingMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return d.mounter.WriteFile(stagingMetadataPath(target), data)
+}
+
+// readStagingMetadata returns the staging metadata of the volume staged at target, empty when none was recorded.
+func (d *nodeService) readStagingMetadata(target string) (stagingMetadata, error) {
+	var metadata stagingMetadata
+	data, err := d.mounter.ReadFile(stagingMetadataPath(target))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return metadata, nil
+		}
+		return metadata, err
+	}
+	err = json.Unmarshal(data, &metadata)
+	return metadata, err
+}
+
+// mkfsArgs returns the arguments of mkfs to force the format of source, as FormatAndMount does.
+// With preallocate, the blocks of an ext filesystem are not discarded and its inode tables and journal
+// are initialized at once.
+func mkfsArgs(source, fsType string, preallocate bool) []string {
+	switch {
+	case fsType == FSTypeXfs:
+		return []string{"-f", source}
+	case preallocate:
+		return []string{"-F", "-m0", "-E", "nodiscard,lazy_itable_init=0,lazy_journal_init=0", source}
+	default:
+		return []string{"-F", "-m0", source}
+	}
+}
+
+// volumeMountGroup returns the group to apply to a volume mounted with volCap, or nil if the fsGroup is left to the kubelet.
+func (d *nodeService) volumeMountGroup(volCap *csi.VolumeCapability) (*int64, error) {
+	group := volCap.GetMount().GetVolumeMountGroup()
+	if len(group) == 0 || !applyFSGroup(d.driverOptions.fsGroupPolicy, volCap) {
+		return nil, nil
+	}
+	gid, err := strconv.ParseInt(group, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid volume mount group %q: %v", group, err)
+	}
+	return &gid, nil
+}
+
+// applyFSGroup returns true when the fsGroup of the pod must be applied to a volume staged with volCap.
+func applyFSGroup(policy string, volCap *csi.VolumeCapability) bool {
+	switch policy {
+	case FSGroupPolicyFile:
+		return true
+	case FSGroupPolicyReadWriteOnceWithFSType:
+		return len(volCap.GetMount().GetFsType()) > 0 &&
+			volCap.GetAccessMode().GetMode() == csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER
+	default:
+		return false
+	}
+}
+
 func (d *nodeService) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
 	klog.V(4).Infof("NodeUnstageVolume: called with args %+v", *req)
 	volumeID := req.GetVolumeId()
@@ -326,6 +712,9 @@ func (d *nodeService) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstag
 	// reply 0 OK.
 	if refCount == 0 {
 		klog.V(5).Infof("NodeUnstageVolume: %s target not mounted", target)
+		if err := d.mounter.RemoveFile(stagingMetadataPath(target)); err != nil {
+			return nil, status.Errorf(codes.Internal, "Could not remove the staging metadata of %q: %v", target, err)
+		}
 		return &csi.NodeUnstageVolumeResponse{}, nil
 	}
 
@@ -338,6 +727,9 @@ func (d *nodeService) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstag
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Could not unmount target %q: %v", target, err)
 	}
+	if err := d.mounter.RemoveFile(stagingMetadataPath(target)); err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not remove the staging metadata of %q: %v", target, err)
+	}
 
 	// Check Encryption
 	isLuksMapping, mappingName, err := d.mounter.IsLuksMapping(dev)
@@ -387,16 +779,20 @@ func (d *nodeService) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandV
 	}
 
 	if isLuksMapping {
-		passphrase, ok := req.Secrets[LuksPassphraseKey]
-		if !ok {
-			klog.Errorf("NodeStageVolume: no passphrase key has been provided in req.Secrets: %+v", req.Secrets)
-			return nil, status.Error(codes.InvalidArgument, "no passphrase key has been provided")
+		passphrase, err := d.getPassphrase(ctx, volumeID, req.Secrets)
+		if err != nil {
+			return nil, err
 		}
 		if err := d.mounter.LuksResize(mappingName, passphrase); err != nil {
 			return nil, status.Errorf(codes.Internal, "Could not resize Luks volume %q: %v", volumeID, err)
 		}
 	}
 
+	if d.filesystemExpanded(devicePath, volumePath) {
+		klog.V(4).Infof("NodeExpandVolume: filesystem of volume %q already fills device %q, skipping the resize", volumeID, devicePath)
+		return &csi.NodeExpandVolumeResponse{}, nil
+	}
+
 	// TODO: refactor Mounter to expose a mount.SafeFormatAndMount object
 	r := mountutils.NewResizeFs(d.mounter)
 
@@ -408,6 +804,26 @@ func (d *nodeService) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandV
 	return &csi.NodeExpandVolumeResponse{}, nil
 }
 
+// filesystemExpanded returns true when the filesystem mounted at volumePath is within the resize tolerance
+// of the size of its device, as the rounding of the sizes may leave a few bytes unused after a resize.
+func (d *nodeService) filesystemExpanded(devicePath string, volumePath string) bool {
+	tolerance := d.driverOptions.fsResizeTolerance
+	if tolerance <= 0 {
+		return false
+	}
+	deviceSize, err := d.mounter.GetBlockSize(devicePath)
+	if err != nil {
+		klog.Warningf("NodeExpandVolume: could not get the size of device %s, resizing: %v", devicePath, err)
+		return false
+	}
+	fsSize, err := d.mounter.GetFilesystemSize(devicePath, volumePath)
+	if err != nil {
+		klog.Warningf("NodeExpandVolume: could not get the size of the filesystem of %s, resizing: %v", devicePath, err)
+		return false
+	}
+	return deviceSize-fsSize <= tolerance
+}
+
 func (d *nodeService) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
 	klog.V(4).Infof("NodePublishVolume: called with args %+v", *req)
 	volumeID := req.GetVolumeId()
@@ -416,7 +832,7 @@ func (d *nodeService) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 
 	source := req.GetStagingTargetPath()
-	if len(source) == 0 {
+	if len(source) == 0 && !d.driverOptions.disableStaging {
 		return nil, status.Error(codes.InvalidArgument, "Staging target not provided")
 	}
 
@@ -430,22 +846,33 @@ func (d *nodeService) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		return nil, status.Error(codes.InvalidArgument, "Volume capability not provided")
 	}
 
-	if !isValidVolumeCapabilities([]*csi.VolumeCapability{volCap}) {
+	if !isValidNodeVolumeCapability(volCap) {
 		return nil, status.Error(codes.InvalidArgument, "Volume capability not supported")
 	}
 
-	mountOptions := []string{"bind"}
-	if req.GetReadonly() {
+	// Without staging, the volumes are published without going through NodeStageVolume
+	if d.driverOptions.disableStaging && d.driverOptions.requireEncryption && req.PublishContext[EncryptedKey] != "true" {
+		return nil, status.Errorf(codes.FailedPrecondition, "Encryption is required, volume %s is not encrypted", volumeID)
+	}
+
+	var mountOptions []string
+	if req.GetReadonly() || isReadOnlyVolumeCapability(volCap) {
 		mountOptions = append(mountOptions, "ro")
 	}
 
 	switch mode := volCap.GetAccessType().(type) {
 	case *csi.VolumeCapability_Block:
-		if err := d.nodePublishVolumeForBlock(req, mountOptions); err != nil {
+		if err := d.nodePublishVolumeForBlock(req, append([]string{"bind"}, mountOptions...)); err != nil {
 			return nil, err
 		}
 	case *csi.VolumeCapability_Mount:
-		if err := d.nodePublishVolumeForFileSystem(req, mountOptions, mode); err != nil {
+		if d.driverOptions.disableStaging {
+			if err := d.nodePublishVolumeWithoutStaging(ctx, req, mountOptions, mode); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if err := d.nodePublishVolumeForFileSystem(req, append([]string{"bind"}, mountOptions...), mode); err != nil {
 			return nil, err
 		}
 	}
@@ -490,32 +917,6 @@ func (d *nodeService) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpu
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
 
-func (d *nodeService) isBlockDevice(filename string) (bool, error) {
-	// Use stat to determine the kind of file this is.
-	var stat unix.Stat_t
-
-	err := unix.Stat(filename, &stat)
-	if err != nil {
-		return false, err
-	}
-
-	return (stat.Mode & unix.S_IFMT) == unix.S_IFBLK, nil
-}
-
-func (d *nodeService) getBlockSizeBytes(devicePath string) (int64, error) {
-	cmd := d.mounter.Command("blockdev", "--getsize64", devicePath)
-	output, err := cmd.Output()
-	if err != nil {
-		return -1, fmt.Errorf("error when getting size of block volume at path %s: output: %s, err: %v", devicePath, string(output), err)
-	}
-	strOut := strings.TrimSpace(string(output))
-	gotSizeBytes, err := strconv.ParseInt(strOut, 10, 64)
-	if err != nil {
-		return -1, fmt.Errorf("failed to parse size %s as int", strOut)
-	}
-	return gotSizeBytes, nil
-}
-
 func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
 
 	klog.V(4).Infof("NodeGetVolumeStats: called with args %+v", *req)
@@ -539,31 +940,27 @@ func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVo
 		return nil, status.Errorf(codes.NotFound, "path %s does not exist", req.VolumePath)
 	}
 
-	isBlock, err := d.isBlockDevice(req.VolumePath)
+	isBlock, err := d.mounter.IsBlockDevice(req.VolumePath)
 	klog.V(4).Infof("isBlockDevice %v, %v", isBlock, err)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to determine whether %s is block device: %v", req.VolumePath, err)
 	}
 
 	if isBlock {
-		bcap, err := d.getBlockSizeBytes(req.VolumePath)
+		bcap, err := d.mounter.GetBlockSize(req.VolumePath)
 		if err != nil {
 			klog.V(4).Infof("failed to get block capacity on path")
 			return nil, status.Errorf(codes.Internal, "failed to get block capacity on path %s: %v", req.VolumePath, err)
 		}
-		klog.V(4).Infof("bcap %+v", csi.NodeGetVolumeStatsResponse{
-			Usage: []*csi.VolumeUsage{
-				{
-					Unit:  csi.VolumeUsage_BYTES,
-					Total: bcap,
-				},
-			},
-		})
+		// The usage of a raw block volume is unknown to the node: the whole device is reported as available
+		klog.V(4).Infof("NodeGetVolumeStats: block device %s has a capacity of %d bytes", req.VolumePath, bcap)
 		return &csi.NodeGetVolumeStatsResponse{
 			Usage: []*csi.VolumeUsage{
 				{
-					Unit:  csi.VolumeUsage_BYTES,
-					Total: bcap,
+					Unit:      csi.VolumeUsage_BYTES,
+					Available: bcap,
+					Total:     bcap,
+					Used:      0,
 				},
 			},
 		}, nil
@@ -576,14 +973,15 @@ func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVo
 		return nil, status.Errorf(codes.Internal, "failed to get fs info on path %s: %v", req.VolumePath, err)
 	}
 
+	bytesUsage := newBytesUsage(
+		metrics.Capacity.AsDec().UnscaledBig().Int64(),
+		metrics.Used.AsDec().UnscaledBig().Int64(),
+		metrics.Available.AsDec().UnscaledBig().Int64(),
+		d.driverOptions.excludeReservedBlocks)
+
 	klog.V(4).Infof("NodeGetVolumeStatsResponse: %+v", csi.NodeGetVolumeStatsResponse{
 		Usage: []*csi.VolumeUsage{
-			{
-				Unit:      csi.VolumeUsage_BYTES,
-				Available: metrics.Available.AsDec().UnscaledBig().Int64(),
-				Total:     metrics.Capacity.AsDec().UnscaledBig().Int64(),
-				Used:      metrics.Used.AsDec().UnscaledBig().Int64(),
-			},
+			bytesUsage,
 			{
 				Unit:      csi.VolumeUsage_INODES,
 				Available: metrics.InodesFree.AsDec().UnscaledBig().Int64(),
@@ -595,12 +993,7 @@ func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVo
 
 	return &csi.NodeGetVolumeStatsResponse{
 		Usage: []*csi.VolumeUsage{
-			{
-				Unit:      csi.VolumeUsage_BYTES,
-				Available: metrics.Available.AsDec().UnscaledBig().Int64(),
-				Total:     metrics.Capacity.AsDec().UnscaledBig().Int64(),
-				Used:      metrics.Used.AsDec().UnscaledBig().Int64(),
-			},
+			bytesUsage,
 			{
 				Unit:      csi.VolumeUsage_INODES,
 				Available: metrics.InodesFree.AsDec().UnscaledBig().Int64(),
@@ -608,13 +1001,83 @@ func (d *nodeService) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVo
 				Used:      metrics.InodesUsed.AsDec().UnscaledBig().Int64(),
 			},
 		},
+		VolumeCondition: d.getVolumeCondition(req.StagingTargetPath),
 	}, nil
 }
 
+// getPassphrase resolves the LUKS passphrase of the volume with the secret provider of the node.
+func (d *nodeService) getPassphrase(ctx context.Context, volumeID string, secrets map[string]string) (string, error) {
+	provider := d.secretProvider
+	if provider == nil {
+		provider = requestSecretProvider{}
+	}
+	passphrase, err := provider.GetPassphrase(ctx, volumeID, secrets)
+	switch {
+	case errors.Is(err, ErrPassphraseNotFound):
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return "", status.Errorf(codes.Unavailable, "Could not get the passphrase of volume %s: %v", volumeID, err)
+	}
+	return passphrase, nil
+}
+
+// newBytesUsage returns the usage in bytes of a filesystem. statfs leaves the blocks reserved to root
+// (ext filesystems) out of both the used and the available bytes, so they only show up in the total.
+// When excludeReserved is set, they are removed from the total as well, which then matches the space
+// usable by the pods.
+func newBytesUsage(capacity, used, available int64, excludeReserved bool) *csi.VolumeUsage {
+	if reserved := capacity - used - available; excludeReserved && reserved > 0 {
+		capacity -= reserved
+	}
+	return &csi.VolumeUsage{
+		Unit:      csi.VolumeUsage_BYTES,
+		Available: available,
+		Total:     capacity,
+		Used:      used,
+	}
+}
+
+// getVolumeCondition reports the volume as abnormal when its staging mount has been remounted read-only,
+// which happens when the filesystem detects errors. It returns nil when the condition is unknown.
+func (d *nodeService) getVolumeCondition(stagingPath string) *csi.VolumeCondition {
+	if stagingPath == "" {
+		return nil
+	}
+	options, err := d.mounter.GetMountOptions(stagingPath)
+	if err != nil {
+		klog.V(4).Infof("NodeGetVolumeStats: could not get the mount options of %s: %v", stagingPath, err)
+		return nil
+	}
+	if hasMountOption(options, "ro") {
+		// the volumes of the read-only access mode are staged read-only
+		metadata, err := d.readStagingMetadata(stagingPath)
+		if err != nil {
+			klog.V(4).Infof("NodeGetVolumeStats: could not read the staging metadata of %s: %v", stagingPath, err)
+		}
+		if metadata.ReadOnly {
+			return &csi.VolumeCondition{
+				Abnormal: false,
+				Message:  "volume is healthy",
+			}
+		}
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("the filesystem mounted at %s is read-only, it may have been remounted after errors", stagingPath),
+		}
+	}
+	return &csi.VolumeCondition{
+		Abnormal: false,
+		Message:  "volume is healthy",
+	}
+}
+
 func (d *nodeService) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
 	klog.V(4).Infof("NodeGetCapabilities: called with args %+v", *req)
 	var caps []*csi.NodeServiceCapability
 	for _, cap := range nodeCaps {
+		if cap == csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME && d.driverOptions.disableStaging {
+			continue
+		}
 		c := &csi.NodeServiceCapability{
 			Type: &csi.NodeServiceCapability_Rpc{
 				Rpc: &csi.NodeServiceCapability_RPC{
@@ -624,6 +1087,16 @@ func (d *nodeService) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetC
 		}
 		caps = append(caps, c)
 	}
+	// The kubelet delegates the fsGroup to the driver only when it supports VOLUME_MOUNT_GROUP
+	if policy := d.driverOptions.fsGroupPolicy; policy == FSGroupPolicyFile || policy == FSGroupPolicyReadWriteOnceWithFSType {
+		caps = append(caps, &csi.NodeServiceCapability{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_VOLUME_MOUNT_GROUP,
+				},
+			},
+		})
+	}
 	return &csi.NodeGetCapabilitiesResponse{Capabilities: caps}, nil
 }
 
@@ -631,7 +1104,15 @@ func (d *nodeService) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoReque
 	klog.V(4).Infof("NodeGetInfo: called with args %+v", *req)
 
 	topology := &csi.Topology{
-		Segments: map[string]string{TopologyKey: d.metadata.GetAvailabilityZone()},
+		Segments: map[string]string{
+			TopologyKey: d.metadata.GetAvailabilityZone(),
+		},
+	}
+	if d.driverOptions.reportRegionTopology {
+		topology.Segments[TopologyRegionKey] = d.metadata.GetRegion()
+	}
+	if d.driverOptions.reportNodeTopology {
+		topology.Segments[TopologyNodeKey] = d.metadata.GetInstanceID()
 	}
 
 	return &csi.NodeGetInfoResponse{
@@ -742,6 +1223,119 @@ func (d *nodeService) nodePublishVolumeForFileSystem(req *csi.NodePublishVolumeR
 	return nil
 }
 
+// nodePublishVolumeWithoutStaging formats the device if needed and mounts it directly at the target path,
+// when the node does not advertise the STAGE_UNSTAGE_VOLUME capability.
+func (d *nodeService) nodePublishVolumeWithoutStaging(ctx context.Context, req *csi.NodePublishVolumeRequest, mountOptions []string, mode *csi.VolumeCapability_Mount) error {
+	target := req.GetTargetPath()
+	volumeID := req.GetVolumeId()
+
+	if req.PublishContext[EncryptedKey] == "true" {
+		return status.Errorf(codes.FailedPrecondition, "Volume %s is encrypted, encrypted volumes require staging", volumeID)
+	}
+
+	fsType := mode.Mount.GetFsType()
+	if len(fsType) == 0 {
+		fsType = defaultFsType
+	}
+
+	mountFlags := append(append([]string{}, mountOptions...), mode.Mount.GetMountFlags()...)
+	mountFlags = append(mountFlags, req.PublishContext[MountProfileOptionsKey])
+	if journalMode := req.PublishContext[JournalModeKey]; journalMode != "" {
+		if err := validateJournalMode(journalMode, fsType); err != nil {
+			return status.Errorf(codes.InvalidArgument, "NodePublishVolume: %v", err)
+		}
+		mountFlags = append(mountFlags, "data="+journalMode)
+	}
+	preallocate := req.PublishContext[PreallocateKey] == "true"
+	if preallocate {
+		if err := validatePreallocate(fsType); err != nil {
+			return status.Errorf(codes.InvalidArgument, "NodePublishVolume: %v", err)
+		}
+	}
+	mountOptions, err := normalizeMountOptions(mountFlags)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "NodePublishVolume: invalid mount flags: %v", err)
+	}
+
+	fsGroup, err := d.volumeMountGroup(req.GetVolumeCapability())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "NodePublishVolume: %v", err)
+	}
+
+	devicePath, ok := req.PublishContext[DevicePathKey]
+	if !ok {
+		return status.Error(codes.InvalidArgument, "Device path not provided")
+	}
+	source, err := d.waitForDevicePath(ctx, devicePath, volumeID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to find device path %s. %v", devicePath, err)
+	}
+
+	klog.V(2).Infof("NodePublishVolume: volume %s resolved device path %s -> %s", volumeID, devicePath, source)
+
+	klog.V(5).Infof("NodePublishVolume: creating dir %s", target)
+	if err := d.mounter.MakeDir(target); err != nil {
+		return status.Errorf(codes.Internal, "Could not create dir %q: %v", target, err)
+	}
+
+	isMounted, err := d.isMounted(target)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Could not check if %q is mounted: %v", target, err)
+	}
+
+	if isMounted {
+		return nil
+	}
+
+	// The fstype and the repair of the filesystem are handled as in NodeStageVolume
+	existingFormat, err := d.mounter.GetDiskFormat(source)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to get disk format of disk %q: %v", source, err)
+	}
+	if existingFormat != "" && existingFormat != fsType {
+		switch {
+		case len(mode.Mount.GetFsType()) == 0:
+			klog.Warningf("NodePublishVolume: The default fstype %q does not match the fstype of the disk %q. Please update your StorageClass.", defaultFsType, existingFormat)
+			fsType = existingFormat
+		case d.driverOptions.allowFsTypeMismatch:
+			klog.Warningf("NodePublishVolume: The requested fstype %q does not match the fstype %q of volume %s, mounting it as %q.", fsType, existingFormat, volumeID, existingFormat)
+			fsType = existingFormat
+		default:
+			return status.Errorf(codes.FailedPrecondition, "NodePublishVolume: The requested fstype %q does not match the fstype %q of volume %s, the volume is not reformatted. Update the fstype of the StorageClass or allow the mismatch with --allow-fs-type-mismatch", fsType, existingFormat, volumeID)
+		}
+	}
+
+	if req.PublishContext[RepairOnMountKey] == "true" && existingFormat != "" && !slices.Contains(mountOptions, "ro") {
+		klog.V(4).Infof("NodePublishVolume: repairing filesystem of %s before mount", source)
+		if err := d.mounter.RepairFilesystem(source, fsType); err != nil {
+			return status.Errorf(codes.Internal, "Could not repair filesystem of %q: %v", source, err)
+		}
+	}
+
+	if preallocate && existingFormat == "" {
+		if err := d.formatPreallocated(source, fsType); err != nil {
+			return status.Errorf(codes.Internal, "Could not format %q: %v", source, err)
+		}
+	}
+
+	klog.V(5).Infof("NodePublishVolume: formatting %s and mounting at %s with option %s as fstype %s", source, target, mountOptions, fsType)
+	if err := d.mounter.FormatAndMount(source, target, fsType, mountOptions); err != nil {
+		if removeErr := os.Remove(target); removeErr != nil {
+			return status.Errorf(codes.Internal, "Could not remove mount target %q: %v", target, removeErr)
+		}
+		return status.Errorf(codes.Internal, "Could not format %q and mount it at %q: %v", source, target, err)
+	}
+
+	if fsGroup != nil {
+		klog.V(5).Infof("NodePublishVolume: setting the group of %s to %d", target, *fsGroup)
+		if err := d.mounter.SetVolumeGroup(target, *fsGroup); err != nil {
+			return status.Errorf(codes.Internal, "could not set the group of %q to %d: %v", target, *fsGroup, err)
+		}
+	}
+
+	return nil
+}
+
 // findDevicePath finds path of device and verifies its existence
 // if the device is not nvme, return the path directly
 // if the device is nvme, finds and returns the nvme device path eg. /dev/nvme1n1
@@ -766,6 +1360,58 @@ func (d *nodeService) findDevicePath(devicePath, volumeID string) (string, error
 	return findScsiVolume(scsiName)
 }
 
+// waitForDevicePath polls findDevicePath until the device shows up, the configured
+// timeout expires or ctx is done. A zero interval or timeout disables polling.
+func (d *nodeService) waitForDevicePath(ctx context.Context, devicePath, volumeID string) (string, error) {
+	interval := d.driverOptions.devicePathPollInterval
+	timeout := d.driverOptions.devicePathTimeout
+	if interval <= 0 || timeout <= 0 {
+		return d.findDevicePath(devicePath, volumeID)
+	}
+
+	var (
+		source  string
+		findErr error
+	)
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(context.Context) (bool, error) {
+		source, findErr = d.findDevicePath(devicePath, volumeID)
+		if findErr != nil {
+			klog.V(5).Infof("waitForDevicePath: device %s not found yet: %v", devicePath, findErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("device not found before the request ended: %w, last error: %v", ctx.Err(), findErr)
+		}
+		return "", fmt.Errorf("device not found after %v: %v", timeout, findErr)
+	}
+	return source, nil
+}
+
+// luksOpen opens the LUKS device, retrying up to the configured number of times
+// while the device is busy, unless ctx is done.
+func (d *nodeService) luksOpen(ctx context.Context, devicePath, encryptedDeviceName, passphrase string) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if _, err = d.mounter.LuksOpen(devicePath, encryptedDeviceName, passphrase); err == nil {
+			return nil
+		}
+		if !isDeviceBusyError(err) || attempt >= d.driverOptions.luksOpenRetries {
+			return err
+		}
+		klog.V(4).Infof("luksOpen: device %s is busy, retrying in %v: %v", devicePath, d.driverOptions.luksOpenRetryDelay, err)
+		timer := time.NewTimer(d.driverOptions.luksOpenRetryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("device %s is still busy: %w", devicePath, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
 func findScsiName(devicePath string) (string, error) {
 	myreg := regexp.MustCompile(`^/dev/xvd(?P<suffix>[a-z]{1,2})$`)
 	match := myreg.FindStringSubmatch(devicePath)
@@ -813,6 +1459,12 @@ func findScsiVolume(findName string) (device string, err error) {
 
 // getVolumesLimit returns the limit of volumes that the node supports
 func (d *nodeService) getVolumesLimit() int64 {
+	return getVolumesLimit()
+}
+
+// getVolumesLimit returns the maximum number of volumes attached to a node,
+// overridden by the MAX_BSU_VOLUMES environment variable.
+func getVolumesLimit() int64 {
 	value := os.Getenv("MAX_BSU_VOLUMES")
 	if value == "" {
 		return defaultMaxBSUVolumes
@@ -836,6 +1488,65 @@ func hasMountOption(options []string, opt string) bool {
 	return false
 }
 
+// exclusiveMountOptions lists the groups of mount options that cannot be used together.
+var exclusiveMountOptions = [][]string{
+	{"atime", "noatime"},
+	{"noatime", "relatime", "strictatime"},
+	{"diratime", "nodiratime"},
+	{"relatime", "norelatime"},
+	{"ro", "rw"},
+	{"exec", "noexec"},
+	{"suid", "nosuid"},
+	{"dev", "nodev"},
+	{"sync", "async"},
+}
+
+// normalizeMountOptions splits the comma separated mount options, drops the
+// empty and duplicated ones, and returns an error if mutually exclusive options are set.
+func normalizeMountOptions(flags []string) ([]string, error) {
+	var options []string
+	for _, flag := range flags {
+		for _, o := range splitMountOptions(flag) {
+			o = strings.TrimSpace(o)
+			if o != "" && !hasMountOption(options, o) {
+				options = append(options, o)
+			}
+		}
+	}
+
+	for _, group := range exclusiveMountOptions {
+		var found []string
+		for _, o := range group {
+			if hasMountOption(options, o) {
+				found = append(found, o)
+			}
+		}
+		if len(found) > 1 {
+			return nil, fmt.Errorf("mount options %s are mutually exclusive", strings.Join(found, " and "))
+		}
+	}
+	return options, nil
+}
+
+// splitMountOptions splits the comma separated mount options of flag, keeping the commas
+// between double quotes, as in context="system_u:object_r:container_file_t:s0:c1,c2".
+func splitMountOptions(flag string) []string {
+	var options []string
+	start, quoted := 0, false
+	for i, c := range flag {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				options = append(options, flag[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(options, flag[start:])
+}
+
 // isMounted checks if target is mounted. It does NOT return an error if target
 // doesn't exist.
 func (d *nodeService) isMounted(target string) (bool, error) {
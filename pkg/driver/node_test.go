@@ -17,12 +17,17 @@ limitations under the License.
 package driver
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
@@ -32,9 +37,28 @@ import (
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
 	exec "k8s.io/utils/exec"
+	"k8s.io/utils/mount"
 )
 
+// captureKlog redirects the klog output at the given verbosity to the returned buffer until the end of the test.
+func captureKlog(t *testing.T, verbosity int) *bytes.Buffer {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	_ = fs.Set("logtostderr", "false")
+	_ = fs.Set("v", strconv.Itoa(verbosity))
+	logs := &bytes.Buffer{}
+	klog.SetOutput(logs)
+	t.Cleanup(func() {
+		klog.Flush()
+		klog.SetOutput(os.Stderr)
+		_ = fs.Set("logtostderr", "true")
+		_ = fs.Set("v", "0")
+	})
+	return logs
+}
+
 func TestNodeStageVolume(t *testing.T) {
 
 	var (
@@ -52,14 +76,1265 @@ func TestNodeStageVolume(t *testing.T) {
 			AccessMode: &csi.VolumeCapability_AccessMode{
 				Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
 			},
-		}
-	)
-	testCases := []struct {
-		name     string
-		testFunc func(t *testing.T)
-	}{
+		}
+	)
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "success normal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success logs the resolved device path",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+
+				logs := captureKlog(t, 2)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+				klog.Flush()
+				expLog := fmt.Sprintf("volume vol-test resolved device path %s -> %s", devicePath, devicePath)
+				if !strings.Contains(logs.String(), expLog) {
+					t.Fatalf("Expected the logs to contain %q, got:\n%s", expLog, logs.String())
+				}
+			},
+		},
+		{
+			name: "success device size matches the volume size",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{strictDeviceSizeCheck: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath, VolumeSizeKey: "10737418240"},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+				mockMounter.EXPECT().GetBlockSize(devicePath).Return(int64(10737418240), nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success device larger than the published size with strict check",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{strictDeviceSizeCheck: true},
+				}
+
+				// The volume was expanded after the attachment, the publish context keeps the size at attach time
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath, VolumeSizeKey: "10737418240"},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+				mockMounter.EXPECT().GetBlockSize(devicePath).Return(int64(21474836480), nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success device size mismatch is logged",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath, VolumeSizeKey: "21474836480"},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+				mockMounter.EXPECT().GetBlockSize(devicePath).Return(int64(10737418240), nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+
+				logs := captureKlog(t, 0)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+				klog.Flush()
+				expLog := fmt.Sprintf("device %s of volume vol-test has a size of 10737418240 bytes instead of 21474836480 bytes", devicePath)
+				if !strings.Contains(logs.String(), expLog) {
+					t.Fatalf("Expected the logs to contain %q, got:\n%s", expLog, logs.String())
+				}
+			},
+		},
+		{
+			name: "fail device size mismatch with strict check",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{strictDeviceSizeCheck: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath, VolumeSizeKey: "21474836480"},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil)
+				mockMounter.EXPECT().GetBlockSize(devicePath).Return(int64(10737418240), nil)
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "success plaintext volume without LUKS probe",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath, EncryptedKey: "false"},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().IsLuks(gomock.Any()).Times(0)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success with duplicated mount flags",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath, MountProfileOptionsKey: "noatime,nodiratime"},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{
+								FsType:     FSTypeExt4,
+								MountFlags: []string{"noatime", " nodiratime"},
+							},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Eq([]string{"noatime", "nodiratime"}))
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail with conflicting mount flags",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath, MountProfileOptionsKey: "noatime"},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{
+								FsType:     FSTypeExt4,
+								MountFlags: []string{"atime"},
+							},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "fail volume already staged at another path",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 0, nil)
+				mockMounter.EXPECT().List().Return([]mount.MountPoint{
+					{Device: "/dev/other", Path: "/other/path"},
+					{Device: devicePath, Path: "/other/staging/path"},
+				}, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "success device path appears on second poll",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata: mockMetadata,
+					mounter:  mockMounter,
+					inFlight: internal.NewInFlight(),
+					driverOptions: &DriverOptions{
+						devicePathPollInterval: time.Millisecond,
+						devicePathTimeout:      time.Second,
+					},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(false, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail device path never appears",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata: mockMetadata,
+					mounter:  mockMounter,
+					inFlight: internal.NewInFlight(),
+					driverOptions: &DriverOptions{
+						devicePathPollInterval: time.Millisecond,
+						devicePathTimeout:      10 * time.Millisecond,
+					},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(false, nil).AnyTimes()
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "fail device path wait when the context is canceled",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata: mockMetadata,
+					mounter:  mockMounter,
+					inFlight: internal.NewInFlight(),
+					driverOptions: &DriverOptions{
+						devicePathPollInterval: time.Millisecond,
+						devicePathTimeout:      time.Hour,
+					},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				// the polling stops with the request instead of lasting until the timeout
+				mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(false, nil).AnyTimes()
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, err := oscDriver.NodeStageVolume(ctx, req)
+				expectErr(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "success normal [raw block]",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: "/dev/fake"},
+					StagingTargetPath: "/test/path",
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Block{
+							Block: &csi.VolumeCapability_BlockVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success repair on mount",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext: map[string]string{
+						DevicePathKey:    devicePath,
+						RepairOnMountKey: "true",
+					},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				gomock.InOrder(
+					mockMounter.EXPECT().RepairFilesystem(gomock.Eq(devicePath), gomock.Eq(FSTypeExt4)).Return(nil),
+					mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any()),
+				)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success journal mode",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext: map[string]string{
+						DevicePathKey:  devicePath,
+						JournalModeKey: JournalModeJournal,
+					},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Eq([]string{"data=journal"}))
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail journal mode with xfs",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext: map[string]string{
+						DevicePathKey:  devicePath,
+						JournalModeKey: JournalModeWriteback,
+					},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeXfs},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "success preallocate",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				mockCmd := mocks.NewMockCmd(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext: map[string]string{
+						DevicePathKey:  devicePath,
+						PreallocateKey: "true",
+					},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
+				mockMounter.EXPECT().Command(gomock.Eq("mkfs.ext4"), gomock.Eq("-F"), gomock.Eq("-m0"), gomock.Eq("-E"), gomock.Eq("nodiscard,lazy_itable_init=0,lazy_journal_init=0"), gomock.Eq(devicePath)).Return(mockCmd)
+				mockCmd.EXPECT().CombinedOutput().Return(nil, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success mount by uuid",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				mockCmd := mocks.NewMockCmd(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{mountByUUID: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
+				gomock.InOrder(
+					mockMounter.EXPECT().Command(gomock.Eq("mkfs.ext4"), gomock.Eq("-F"), gomock.Eq("-m0"), gomock.Eq(devicePath)).Return(mockCmd),
+					mockCmd.EXPECT().CombinedOutput().Return(nil, nil),
+					mockMounter.EXPECT().GetFilesystemUUID(gomock.Eq(devicePath)).Return("0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e", nil),
+					mockMounter.EXPECT().GetDevicesByFilesystemUUID(gomock.Eq("0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e")).Return([]string{devicePath}, nil),
+					mockMounter.EXPECT().Mount(gomock.Eq("UUID=0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e"), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any()).Return(nil),
+					// The UUID is recorded in the staging metadata
+					mockMounter.EXPECT().WriteFile(gomock.Eq(stagingMetadataPath(targetPath)), gomock.Eq([]byte(`{"filesystemUUID":"0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e"}`))).Return(nil),
+				)
+				mockMounter.EXPECT().FormatAndMount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success mount by recorded uuid",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{mountByUUID: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				// The volume is restaged after a reboot, its filesystem is mounted by the UUID recorded at the first stage
+				metadata := []byte(`{"filesystemUUID":"0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e"}`)
+				mockMounter.EXPECT().ReadFile(gomock.Eq(stagingMetadataPath(targetPath))).Return(metadata, nil)
+				mockMounter.EXPECT().GetFilesystemUUID(gomock.Any()).Times(0)
+				mockMounter.EXPECT().CheckFilesystem(gomock.Eq(devicePath)).Return(nil)
+				mockMounter.EXPECT().GetDevicesByFilesystemUUID(gomock.Eq("0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e")).Return([]string{devicePath}, nil)
+				mockMounter.EXPECT().Mount(gomock.Eq("UUID=0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e"), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().WriteFile(gomock.Eq(stagingMetadataPath(targetPath)), gomock.Eq(metadata)).Return(nil)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success mount by device path with a uuid shared by two devices",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{mountByUUID: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().ReadFile(gomock.Eq(stagingMetadataPath(targetPath))).Return(nil, os.ErrNotExist)
+				mockMounter.EXPECT().CheckFilesystem(gomock.Eq(devicePath)).Return(nil)
+				// Another volume restored from the same snapshot holds the same filesystem UUID
+				mockMounter.EXPECT().GetFilesystemUUID(gomock.Eq(devicePath)).Return("0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e", nil)
+				mockMounter.EXPECT().GetDevicesByFilesystemUUID(gomock.Eq("0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e")).Return([]string{"/dev/other", devicePath}, nil)
+				mockMounter.EXPECT().Mount(gomock.Eq("UUID=0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e"), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockMounter.EXPECT().Mount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().WriteFile(gomock.Eq(stagingMetadataPath(targetPath)), gomock.Eq([]byte(`{"filesystemUUID":"0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e"}`))).Return(nil)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail mount by recorded uuid held by another device",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{mountByUUID: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().ReadFile(gomock.Eq(stagingMetadataPath(targetPath))).Return([]byte(`{"filesystemUUID":"0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e"}`), nil)
+				mockMounter.EXPECT().CheckFilesystem(gomock.Eq(devicePath)).Return(nil)
+				mockMounter.EXPECT().GetDevicesByFilesystemUUID(gomock.Eq("0b9d7c4e-2f1a-4c3b-9e8d-5a6f7b8c9d0e")).Return([]string{"/dev/other"}, nil)
+				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "fail mount by uuid of an unformatted read-only volume",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{mountByUUID: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
+				mockMounter.EXPECT().Command(gomock.Any(), gomock.Any()).Times(0)
+				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "fail format timeout",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				mockCmd := mocks.NewMockCmd(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{formatTimeout: 10 * time.Millisecond},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
+				mockMounter.EXPECT().ExistsPath(gomock.Eq(formatMarkerPath(targetPath))).Return(false, nil)
+				mockMounter.EXPECT().MakeFile(gomock.Eq(formatMarkerPath(targetPath))).Return(nil)
+				// The format outlasts the timeout
+				mockMounter.EXPECT().CommandContext(gomock.Any(), gomock.Eq("mkfs.ext4"), gomock.Eq("-F"), gomock.Eq("-m0"), gomock.Eq(devicePath)).DoAndReturn(
+					func(ctx context.Context, cmd string, args ...string) exec.Cmd {
+						mockCmd.EXPECT().CombinedOutput().DoAndReturn(func() ([]byte, error) {
+							<-ctx.Done()
+							return nil, errors.New("signal: killed")
+						})
+						return mockCmd
+					})
+				mockMounter.EXPECT().FormatAndMount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.DeadlineExceeded)
+			},
+		},
+		{
+			name: "success reformat after incomplete format",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				mockCmd := mocks.NewMockCmd(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{formatTimeout: time.Minute},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				// The filesystem left by the interrupted format is detected
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().ExistsPath(gomock.Eq(formatMarkerPath(targetPath))).Return(true, nil)
+				gomock.InOrder(
+					mockMounter.EXPECT().MakeFile(gomock.Eq(formatMarkerPath(targetPath))).Return(nil),
+					mockMounter.EXPECT().CommandContext(gomock.Any(), gomock.Eq("mkfs.ext4"), gomock.Eq("-F"), gomock.Eq("-m0"), gomock.Eq(devicePath)).Return(mockCmd),
+					mockCmd.EXPECT().CombinedOutput().Return(nil, nil),
+					// The marker is cleared once the format completes
+					mockMounter.EXPECT().RemoveFile(gomock.Eq(formatMarkerPath(targetPath))).Return(nil),
+					mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any()).Return(nil),
+				)
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail preallocate with xfs",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext: map[string]string{
+						DevicePathKey:  devicePath,
+						PreallocateKey: "true",
+					},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeXfs},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "fail fstype mismatch",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeXfs},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "success fstype mismatch allowed",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{allowFsTypeMismatch: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeXfs},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success _netdev mount flag",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeExt4, MountFlags: []string{"_netdev", "_netdev"}},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Eq([]string{"_netdev"}))
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success repair on mount skipped [raw block]",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext: map[string]string{
+						DevicePathKey:    devicePath,
+						RepairOnMountKey: "true",
+					},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Block{
+							Block: &csi.VolumeCapability_BlockVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				mockMounter.EXPECT().RepairFilesystem(gomock.Any(), gomock.Any()).Times(0)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
 		{
-			name: "success normal",
+			name: "fail repair on mount",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -68,13 +1343,17 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
-					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					PublishContext: map[string]string{
+						DevicePathKey:    devicePath,
+						RepairOnMountKey: "true",
+					},
 					StagingTargetPath: targetPath,
 					VolumeCapability:  stdVolCap,
 					VolumeId:          "vol-test",
@@ -87,16 +1366,15 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
-				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
-				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().RepairFilesystem(gomock.Eq(devicePath), gomock.Eq(FSTypeExt4)).Return(errors.New("fsck failed"))
 				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
-				if err != nil {
-					t.Fatalf("Expect no error but got: %v", err)
-				}
+				expectErr(t, err, codes.Internal)
 			},
 		},
 		{
-			name: "success normal [raw block]",
+			name: "success with mount options",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -105,17 +1383,20 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
-					PublishContext:    map[string]string{DevicePathKey: "/dev/fake"},
-					StagingTargetPath: "/test/path",
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
 					VolumeCapability: &csi.VolumeCapability{
-						AccessType: &csi.VolumeCapability_Block{
-							Block: &csi.VolumeCapability_BlockVolume{},
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{
+								MountFlags: []string{"dirsync", "noexec"},
+							},
 						},
 						AccessMode: &csi.VolumeCapability_AccessMode{
 							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
@@ -124,14 +1405,26 @@ func TestNodeStageVolume(t *testing.T) {
 					VolumeId: "vol-test",
 				}
 
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return("", nil)
+				mockMounter.EXPECT().Command(gomock.Eq("mkfs.xfs"), gomock.Eq(devicePath)).Return(exec.New().Command("mkfs"))
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeXfs), gomock.Eq([]string{"dirsync", "noexec"}))
 				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
 				if err != nil {
 					t.Fatalf("Expect no error but got: %v", err)
 				}
+
 			},
 		},
 		{
-			name: "success with mount options",
+			name: "success read-only access mode",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -140,22 +1433,24 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
-					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					PublishContext:    map[string]string{DevicePathKey: devicePath, RepairOnMountKey: "true"},
 					StagingTargetPath: targetPath,
 					VolumeCapability: &csi.VolumeCapability{
 						AccessType: &csi.VolumeCapability_Mount{
 							Mount: &csi.VolumeCapability_MountVolume{
-								MountFlags: []string{"dirsync", "noexec"},
+								FsType:     FSTypeExt4,
+								MountFlags: []string{"noexec"},
 							},
 						},
 						AccessMode: &csi.VolumeCapability_AccessMode{
-							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
 						},
 					},
 					VolumeId: "vol-test",
@@ -163,19 +1458,20 @@ func TestNodeStageVolume(t *testing.T) {
 
 				gomock.InOrder(
 					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
-					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
 				)
 
-				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
-				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return("", nil)
-				mockMounter.EXPECT().Command(gomock.Eq("mkfs.xfs"), gomock.Eq(devicePath)).Return(exec.New().Command("mkfs"))
-				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeXfs), gomock.Eq([]string{"dirsync", "noexec"}))
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return(FSTypeExt4, nil)
+				// The filesystem of a read-only volume is never repaired
+				mockMounter.EXPECT().RepairFilesystem(gomock.Any(), gomock.Any()).Times(0)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Eq([]string{"noexec", "ro"}))
+				mockMounter.EXPECT().WriteFile(gomock.Eq(stagingMetadataPath(targetPath)), gomock.Eq([]byte(`{"readOnly":true}`))).Return(nil)
 				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
 				if err != nil {
 					t.Fatalf("Expect no error but got: %v", err)
 				}
-
 			},
 		},
 		{
@@ -188,9 +1484,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -216,6 +1513,7 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
 				mockMounter.EXPECT().GetDiskFormat(devicePath).Return("", nil)
 				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt3), gomock.Any())
 				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
@@ -234,9 +1532,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -262,6 +1561,7 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
 				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return("", nil)
 				mockMounter.EXPECT().Command(gomock.Eq("mkfs.xfs"), gomock.Eq(devicePath)).Return(exec.New().Command("mkfs"))
 				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeXfs), gomock.Any())
@@ -281,9 +1581,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -310,9 +1611,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -344,9 +1646,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				devicePath := "/dev/fake"
@@ -374,9 +1677,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -402,9 +1706,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -425,6 +1730,33 @@ func TestNodeStageVolume(t *testing.T) {
 				expectErr(t, err, codes.InvalidArgument)
 			},
 		},
+		{
+			name: "fail encryption required on plaintext volume",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{requireEncryption: true},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
 		{
 			name: "fail no devicePath",
 			testFunc: func(t *testing.T) {
@@ -435,9 +1767,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -464,9 +1797,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -483,12 +1817,84 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return(devicePath, 1, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().List().Return([]mount.MountPoint{{Device: devicePath, Path: targetPath, Opts: []string{"rw"}}}, nil)
 				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
 				if err != nil {
 					t.Fatalf("Expect no error but got: %v", err)
 				}
 			},
 		},
+		{
+			name: "fail device already mounted at target with another fstype",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return(devicePath, 1, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeXfs, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.AlreadyExists)
+			},
+		},
+		{
+			name: "fail device already mounted read-only at target",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil),
+				)
+
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return(devicePath, 1, nil)
+				mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().List().Return([]mount.MountPoint{{Device: devicePath, Path: targetPath, Opts: []string{"ro"}}}, nil)
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.AlreadyExists)
+			},
+		},
 		{
 			name: "success mount of a disk from an old CSI plugin version (<= 0.0.14beta) with default FSType",
 			testFunc: func(t *testing.T) {
@@ -499,9 +1905,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -527,6 +1934,7 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
 				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return("ext4", nil)
 				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
 				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
@@ -545,9 +1953,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -566,7 +1975,111 @@ func TestNodeStageVolume(t *testing.T) {
 							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
 						},
 					},
-					VolumeId: "vol-test",
+					VolumeId: "vol-test",
+					Secrets: map[string]string{
+						LuksPassphraseKey: passphrase,
+					},
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				// Check Luks
+				mockMounter.EXPECT().IsLuks(gomock.Eq(devicePath)).Return(false)
+				mockMounter.EXPECT().LuksFormat(gomock.Eq(devicePath), gomock.Eq(passphrase), gomock.Eq(luks.LuksContext{Cipher: "", Hash: "", KeySize: ""})).Return(nil)
+				mockMounter.EXPECT().LuksSlotForPassphrase(gomock.Eq(devicePath), gomock.Eq(passphrase)).Return(0, nil)
+				mockMounter.EXPECT().LuksOpen(gomock.Eq(devicePath), gomock.Eq(encryptedDeviceName), gomock.Eq(passphrase))
+
+				// Format opened luks device
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(encryptedDevicePath)).Return(defaultFsType, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(encryptedDevicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any())
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success encryption with busy device on open",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{luksOpenRetries: 1, luksOpenRetryDelay: time.Millisecond},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext: map[string]string{
+						DevicePathKey: devicePath,
+						EncryptedKey:  "true",
+					},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
+					Secrets: map[string]string{
+						LuksPassphraseKey: passphrase,
+					},
+				}
+
+				gomock.InOrder(
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil),
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(false, nil),
+				)
+
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().IsLuks(gomock.Eq(devicePath)).Return(true)
+				mockMounter.EXPECT().LuksSlotForPassphrase(gomock.Eq(devicePath), gomock.Eq(passphrase)).Return(0, nil)
+				gomock.InOrder(
+					mockMounter.EXPECT().LuksOpen(gomock.Eq(devicePath), gomock.Eq(encryptedDeviceName), gomock.Eq(passphrase)).Return(false, errors.New("err: exit status 5, output: Device /dev/fake is busy.")),
+					mockMounter.EXPECT().LuksOpen(gomock.Eq(devicePath), gomock.Eq(encryptedDeviceName), gomock.Eq(passphrase)).Return(true, nil),
+				)
+
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(encryptedDevicePath)).Return(FSTypeExt4, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(encryptedDevicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Any())
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail encryption with busy device on open when the context is canceled",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{luksOpenRetries: 1, luksOpenRetryDelay: time.Hour},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext: map[string]string{
+						DevicePathKey: devicePath,
+						EncryptedKey:  "true",
+					},
+					StagingTargetPath: targetPath,
+					VolumeCapability:  stdVolCap,
+					VolumeId:          "vol-test",
 					Secrets: map[string]string{
 						LuksPassphraseKey: passphrase,
 					},
@@ -579,19 +2092,16 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
-				// Check Luks
-				mockMounter.EXPECT().IsLuks(gomock.Eq(devicePath)).Return(false)
-				mockMounter.EXPECT().LuksFormat(gomock.Eq(devicePath), gomock.Eq(passphrase), gomock.Eq(luks.LuksContext{Cipher: "", Hash: "", KeySize: ""})).Return(nil)
-				mockMounter.EXPECT().CheckLuksPassphrase(gomock.Eq(devicePath), gomock.Eq(passphrase)).Return(true)
-				mockMounter.EXPECT().LuksOpen(gomock.Eq(devicePath), gomock.Eq(encryptedDeviceName), gomock.Eq(passphrase))
+				mockMounter.EXPECT().List().Return(nil, nil)
+				mockMounter.EXPECT().IsLuks(gomock.Eq(devicePath)).Return(true)
+				mockMounter.EXPECT().LuksSlotForPassphrase(gomock.Eq(devicePath), gomock.Eq(passphrase)).Return(0, nil)
+				// the retry is not waited for once the context is canceled
+				mockMounter.EXPECT().LuksOpen(gomock.Eq(devicePath), gomock.Eq(encryptedDeviceName), gomock.Eq(passphrase)).Return(false, errors.New("err: exit status 5, output: Device /dev/fake is busy.")).Times(1)
 
-				// Format opened luks device
-				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(encryptedDevicePath)).Return(defaultFsType, nil)
-				mockMounter.EXPECT().FormatAndMount(gomock.Eq(encryptedDevicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Any())
-				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
-				if err != nil {
-					t.Fatalf("Expect no error but got: %v", err)
-				}
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, err := oscDriver.NodeStageVolume(ctx, req)
+				expectErr(t, err, codes.Internal)
 			},
 		},
 		{
@@ -604,9 +2114,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -641,10 +2152,11 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
 				// Check Luks
 				mockMounter.EXPECT().IsLuks(gomock.Eq(devicePath)).Return(false)
 				mockMounter.EXPECT().LuksFormat(gomock.Eq(devicePath), gomock.Eq(passphrase), gomock.Eq(luks.LuksContext{Cipher: req.PublishContext[LuksCipherKey], Hash: req.PublishContext[LuksHashKey], KeySize: req.PublishContext[LuksKeySizeKey]})).Return(nil)
-				mockMounter.EXPECT().CheckLuksPassphrase(gomock.Eq(devicePath), gomock.Eq(passphrase)).Return(true)
+				mockMounter.EXPECT().LuksSlotForPassphrase(gomock.Eq(devicePath), gomock.Eq(passphrase)).Return(0, nil)
 				mockMounter.EXPECT().LuksOpen(gomock.Eq(devicePath), gomock.Eq(encryptedDeviceName), gomock.Eq(passphrase))
 
 				// Format opened luks device
@@ -666,9 +2178,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -698,6 +2211,7 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
 				// Check Luks
 				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
 				if err == nil {
@@ -715,9 +2229,10 @@ func TestNodeStageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeStageVolumeRequest{
@@ -749,9 +2264,10 @@ func TestNodeStageVolume(t *testing.T) {
 
 				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
 				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+				mockMounter.EXPECT().List().Return(nil, nil)
 				// Check Luks (it is already format)
 				mockMounter.EXPECT().IsLuks(gomock.Eq(devicePath)).Return(true)
-				mockMounter.EXPECT().CheckLuksPassphrase(gomock.Eq(devicePath), gomock.Eq(passphrase)).Return(true)
+				mockMounter.EXPECT().LuksSlotForPassphrase(gomock.Eq(devicePath), gomock.Eq(passphrase)).Return(0, nil)
 				mockMounter.EXPECT().LuksOpen(gomock.Eq(devicePath), gomock.Eq(encryptedDeviceName), gomock.Eq(passphrase))
 
 				// Format opened luks device
@@ -789,13 +2305,15 @@ func TestNodeUnstageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().GetDeviceName(gomock.Eq(targetPath)).Return(devicePath, 1, nil)
 				mockMounter.EXPECT().Unmount(gomock.Eq(targetPath)).Return(nil)
+				mockMounter.EXPECT().RemoveFile(gomock.Eq(stagingMetadataPath(targetPath))).Return(nil)
 				mockMounter.EXPECT().IsLuksMapping(gomock.Eq(devicePath)).Return(false, "", nil)
 
 				req := &csi.NodeUnstageVolumeRequest{
@@ -819,12 +2337,14 @@ func TestNodeUnstageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().GetDeviceName(gomock.Eq(targetPath)).Return(devicePath, 0, nil)
+				mockMounter.EXPECT().RemoveFile(gomock.Eq(stagingMetadataPath(targetPath))).Return(nil)
 
 				req := &csi.NodeUnstageVolumeRequest{
 					StagingTargetPath: targetPath,
@@ -846,13 +2366,15 @@ func TestNodeUnstageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().GetDeviceName(gomock.Eq(targetPath)).Return(devicePath, 2, nil)
 				mockMounter.EXPECT().Unmount(gomock.Eq(targetPath)).Return(nil)
+				mockMounter.EXPECT().RemoveFile(gomock.Eq(stagingMetadataPath(targetPath))).Return(nil)
 				mockMounter.EXPECT().IsLuksMapping(gomock.Eq(devicePath)).Return(false, "", nil)
 
 				req := &csi.NodeUnstageVolumeRequest{
@@ -866,6 +2388,107 @@ func TestNodeUnstageVolume(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success fsGroup applied according to the policy",
+			testFunc: func(t *testing.T) {
+				policies := []struct {
+					policy  string
+					fsType  string
+					applied bool
+				}{
+					{policy: FSGroupPolicyFile, fsType: FSTypeExt4, applied: true},
+					{policy: FSGroupPolicyFile, fsType: "", applied: true},
+					{policy: FSGroupPolicyReadWriteOnceWithFSType, fsType: FSTypeExt4, applied: true},
+					{policy: FSGroupPolicyReadWriteOnceWithFSType, fsType: "", applied: false},
+					{policy: FSGroupPolicyNone, fsType: FSTypeExt4, applied: false},
+					{policy: "", fsType: FSTypeExt4, applied: false},
+				}
+				for _, p := range policies {
+					mockCtl := gomock.NewController(t)
+
+					mockMetadata := mocks.NewMockMetadataService(mockCtl)
+					mockMounter := mocks.NewMockMounter(mockCtl)
+
+					oscDriver := &nodeService{
+						metadata:      mockMetadata,
+						mounter:       mockMounter,
+						inFlight:      internal.NewInFlight(),
+						driverOptions: &DriverOptions{fsGroupPolicy: p.policy},
+					}
+
+					req := &csi.NodeStageVolumeRequest{
+						PublishContext:    map[string]string{DevicePathKey: devicePath},
+						StagingTargetPath: targetPath,
+						VolumeCapability: &csi.VolumeCapability{
+							AccessType: &csi.VolumeCapability_Mount{
+								Mount: &csi.VolumeCapability_MountVolume{
+									FsType:           p.fsType,
+									VolumeMountGroup: "1000",
+								},
+							},
+							AccessMode: &csi.VolumeCapability_AccessMode{
+								Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+							},
+						},
+						VolumeId: "vol-test",
+					}
+
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil)
+					mockMounter.EXPECT().ExistsPath(gomock.Eq(targetPath)).Return(true, nil)
+					mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 1, nil)
+					mockMounter.EXPECT().List().Return(nil, nil)
+					mockMounter.EXPECT().GetDiskFormat(devicePath).Return(FSTypeExt4, nil)
+					mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Any(), gomock.Any())
+					if p.applied {
+						mockMounter.EXPECT().SetVolumeGroup(gomock.Eq(targetPath), gomock.Eq(int64(1000))).Return(nil)
+					} else {
+						mockMounter.EXPECT().SetVolumeGroup(gomock.Any(), gomock.Any()).Times(0)
+					}
+					_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+					if err != nil {
+						t.Fatalf("Expect no error with policy %q and fsType %q but got: %v", p.policy, p.fsType, err)
+					}
+					mockCtl.Finish()
+				}
+			},
+		},
+		{
+			name: "fail invalid volume mount group",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{fsGroupPolicy: FSGroupPolicyFile},
+				}
+
+				req := &csi.NodeStageVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: devicePath},
+					StagingTargetPath: targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{
+								FsType:           FSTypeExt4,
+								VolumeMountGroup: "staff",
+							},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				_, err := oscDriver.NodeStageVolume(context.TODO(), req)
+				expectErr(t, err, codes.InvalidArgument)
+			},
+		},
 		{
 			name: "fail no VolumeId",
 			testFunc: func(t *testing.T) {
@@ -876,9 +2499,10 @@ func TestNodeUnstageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeUnstageVolumeRequest{
@@ -899,9 +2523,10 @@ func TestNodeUnstageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeUnstageVolumeRequest{
@@ -921,9 +2546,10 @@ func TestNodeUnstageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().GetDeviceName(gomock.Eq(targetPath)).Return("", 0, errors.New("GetDeviceName faield"))
@@ -947,13 +2573,15 @@ func TestNodeUnstageVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().GetDeviceName(gomock.Eq(targetPath)).Return(devicePath, 1, nil)
 				mockMounter.EXPECT().Unmount(gomock.Eq(targetPath)).Return(nil)
+				mockMounter.EXPECT().RemoveFile(gomock.Eq(stagingMetadataPath(targetPath))).Return(nil)
 				mockMounter.EXPECT().IsLuksMapping(gomock.Eq(devicePath)).Return(true, encryptedDeviceName, nil)
 				mockMounter.EXPECT().LuksClose(gomock.Eq(encryptedDeviceName)).Return(nil)
 				req := &csi.NodeUnstageVolumeRequest{
@@ -1000,9 +2628,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
@@ -1023,6 +2652,176 @@ func TestNodePublishVolume(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success without staging",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{disableStaging: true},
+				}
+
+				mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil)
+				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return("", nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Nil()).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				req := &csi.NodePublishVolumeRequest{
+					PublishContext:   map[string]string{DevicePathKey: devicePath},
+					TargetPath:       targetPath,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+				}
+
+				_, err := oscDriver.NodePublishVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success without staging with repair on mount",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{disableStaging: true},
+				}
+
+				mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil)
+				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return(FSTypeExt4, nil)
+				gomock.InOrder(
+					mockMounter.EXPECT().RepairFilesystem(gomock.Eq(devicePath), gomock.Eq(FSTypeExt4)).Return(nil),
+					mockMounter.EXPECT().FormatAndMount(gomock.Eq(devicePath), gomock.Eq(targetPath), gomock.Eq(FSTypeExt4), gomock.Nil()).Return(nil),
+				)
+
+				req := &csi.NodePublishVolumeRequest{
+					PublishContext:   map[string]string{DevicePathKey: devicePath, RepairOnMountKey: "true"},
+					TargetPath:       targetPath,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+				}
+
+				_, err := oscDriver.NodePublishVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail without staging with a fstype mismatch",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{disableStaging: true},
+				}
+
+				mockMounter.EXPECT().ExistsPath(gomock.Eq(devicePath)).Return(true, nil)
+				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().GetDiskFormat(gomock.Eq(devicePath)).Return(FSTypeXfs, nil)
+				mockMounter.EXPECT().FormatAndMount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				req := &csi.NodePublishVolumeRequest{
+					PublishContext: map[string]string{DevicePathKey: devicePath},
+					TargetPath:     targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{FsType: FSTypeExt4},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				_, err := oscDriver.NodePublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "fail without staging for a plain volume when encryption is required",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{disableStaging: true, requireEncryption: true},
+				}
+
+				mockMounter.EXPECT().FormatAndMount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				req := &csi.NodePublishVolumeRequest{
+					PublishContext:   map[string]string{DevicePathKey: devicePath},
+					TargetPath:       targetPath,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+				}
+
+				_, err := oscDriver.NodePublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "fail without staging for an encrypted volume",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{disableStaging: true},
+				}
+
+				req := &csi.NodePublishVolumeRequest{
+					PublishContext:   map[string]string{DevicePathKey: devicePath, EncryptedKey: "true"},
+					TargetPath:       targetPath,
+					VolumeCapability: stdVolCap,
+					VolumeId:         "vol-test",
+				}
+
+				_, err := oscDriver.NodePublishVolume(context.TODO(), req)
+				expectErr(t, err, codes.FailedPrecondition)
+			},
+		},
 		{
 			name: "success normal idempotency",
 			testFunc: func(t *testing.T) {
@@ -1033,9 +2832,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
@@ -1065,9 +2865,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
@@ -1107,9 +2908,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
@@ -1132,7 +2934,54 @@ func TestNodePublishVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "success mount options",
+			name: "success mount options",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				oscDriver := &nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
+				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(targetPath)).Return(true, nil)
+				mockMounter.EXPECT().Mount(gomock.Eq(stagingTargetPath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Eq([]string{"bind", "test-flag"})).Return(nil)
+
+				req := &csi.NodePublishVolumeRequest{
+					PublishContext:    map[string]string{DevicePathKey: "/dev/fake"},
+					StagingTargetPath: stagingTargetPath,
+					TargetPath:        targetPath,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{
+								// this request will call mount with the bind option,
+								// adding "bind" here we test that we don't add the
+								// same option twice. "test-flag" is a canary to check
+								// that the driver calls mount with that flag
+								MountFlags: []string{"bind", "test-flag"},
+							},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+					VolumeId: "vol-test",
+				}
+
+				_, err := oscDriver.NodePublishVolume(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+			},
+		},
+		{
+			name: "success normal with _netdev mount flag",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				defer mockCtl.Finish()
@@ -1141,14 +2990,15 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				mockMounter.EXPECT().MakeDir(gomock.Eq(targetPath)).Return(nil)
 				mockMounter.EXPECT().IsLikelyNotMountPoint(gomock.Eq(targetPath)).Return(true, nil)
-				mockMounter.EXPECT().Mount(gomock.Eq(stagingTargetPath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Eq([]string{"bind", "test-flag"})).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Eq(stagingTargetPath), gomock.Eq(targetPath), gomock.Eq(defaultFsType), gomock.Eq([]string{"bind", "_netdev"})).Return(nil)
 
 				req := &csi.NodePublishVolumeRequest{
 					PublishContext:    map[string]string{DevicePathKey: "/dev/fake"},
@@ -1157,11 +3007,7 @@ func TestNodePublishVolume(t *testing.T) {
 					VolumeCapability: &csi.VolumeCapability{
 						AccessType: &csi.VolumeCapability_Mount{
 							Mount: &csi.VolumeCapability_MountVolume{
-								// this request will call mount with the bind option,
-								// adding "bind" here we test that we don't add the
-								// same option twice. "test-flag" is a canary to check
-								// that the driver calls mount with that flag
-								MountFlags: []string{"bind", "test-flag"},
+								MountFlags: []string{"_netdev"},
 							},
 						},
 						AccessMode: &csi.VolumeCapability_AccessMode{
@@ -1187,9 +3033,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				gomock.InOrder(
@@ -1232,9 +3079,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1266,9 +3114,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1303,9 +3152,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1330,9 +3180,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1357,9 +3208,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1384,9 +3236,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1410,9 +3263,10 @@ func TestNodePublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodePublishVolumeRequest{
@@ -1456,9 +3310,10 @@ func TestNodeUnpublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeUnpublishVolumeRequest{
@@ -1484,9 +3339,10 @@ func TestNodeUnpublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeUnpublishVolumeRequest{
@@ -1511,9 +3367,10 @@ func TestNodeUnpublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeUnpublishVolumeRequest{
@@ -1534,9 +3391,10 @@ func TestNodeUnpublishVolume(t *testing.T) {
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				oscDriver := &nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeUnpublishVolumeRequest{
@@ -1575,11 +3433,13 @@ func TestNodeGetVolumeStats(t *testing.T) {
 				defer os.RemoveAll(VolumePath)
 
 				mockMounter.EXPECT().ExistsPath(VolumePath).Return(true, nil)
+				mockMounter.EXPECT().IsBlockDevice(VolumePath).Return(false, nil)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeGetVolumeStatsRequest{
@@ -1592,6 +3452,117 @@ func TestNodeGetVolumeStats(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success abnormal read-only filesystem",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				VolumePath := t.TempDir()
+				StagingPath := "/staging/path"
+
+				mockMounter.EXPECT().ExistsPath(VolumePath).Return(true, nil)
+				mockMounter.EXPECT().IsBlockDevice(VolumePath).Return(false, nil)
+				mockMounter.EXPECT().GetMountOptions(StagingPath).Return([]string{"ro", "relatime"}, nil)
+				mockMounter.EXPECT().ReadFile(stagingMetadataPath(StagingPath)).Return(nil, os.ErrNotExist)
+
+				oscDriver := nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeGetVolumeStatsRequest{
+					VolumeId:          "vol-test",
+					VolumePath:        VolumePath,
+					StagingTargetPath: StagingPath,
+				}
+				resp, err := oscDriver.NodeGetVolumeStats(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+				if condition := resp.GetVolumeCondition(); condition == nil || !condition.GetAbnormal() {
+					t.Fatalf("Expected an abnormal volume condition, got %v", condition)
+				}
+			},
+		},
+		{
+			name: "success healthy read-only staged filesystem",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				VolumePath := t.TempDir()
+				StagingPath := "/staging/path"
+
+				mockMounter.EXPECT().ExistsPath(VolumePath).Return(true, nil)
+				mockMounter.EXPECT().IsBlockDevice(VolumePath).Return(false, nil)
+				mockMounter.EXPECT().GetMountOptions(StagingPath).Return([]string{"ro", "relatime"}, nil)
+				// The volume was staged read-only for a SINGLE_NODE_READER_ONLY capability
+				mockMounter.EXPECT().ReadFile(stagingMetadataPath(StagingPath)).Return([]byte(`{"readOnly":true}`), nil)
+
+				oscDriver := nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeGetVolumeStatsRequest{
+					VolumeId:          "vol-test",
+					VolumePath:        VolumePath,
+					StagingTargetPath: StagingPath,
+				}
+				resp, err := oscDriver.NodeGetVolumeStats(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+				if condition := resp.GetVolumeCondition(); condition == nil || condition.GetAbnormal() {
+					t.Fatalf("Expected a normal volume condition, got %v", condition)
+				}
+			},
+		},
+		{
+			name: "success healthy filesystem",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				VolumePath := t.TempDir()
+				StagingPath := "/staging/path"
+
+				mockMounter.EXPECT().ExistsPath(VolumePath).Return(true, nil)
+				mockMounter.EXPECT().IsBlockDevice(VolumePath).Return(false, nil)
+				mockMounter.EXPECT().GetMountOptions(StagingPath).Return([]string{"rw", "relatime"}, nil)
+
+				oscDriver := nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeGetVolumeStatsRequest{
+					VolumeId:          "vol-test",
+					VolumePath:        VolumePath,
+					StagingTargetPath: StagingPath,
+				}
+				resp, err := oscDriver.NodeGetVolumeStats(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+				if condition := resp.GetVolumeCondition(); condition == nil || condition.GetAbnormal() {
+					t.Fatalf("Expected a normal volume condition, got %v", condition)
+				}
+			},
+		},
 		{
 			name: "fail path not exist",
 			testFunc: func(t *testing.T) {
@@ -1605,9 +3576,10 @@ func TestNodeGetVolumeStats(t *testing.T) {
 				mockMounter.EXPECT().ExistsPath(VolumePath).Return(false, nil)
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeGetVolumeStatsRequest{
@@ -1618,6 +3590,49 @@ func TestNodeGetVolumeStats(t *testing.T) {
 				expectErr(t, err, codes.NotFound)
 			},
 		},
+		{
+			name: "success block device",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockMetadata := mocks.NewMockMetadataService(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				VolumePath := "/dev/fake"
+				var deviceSize int64 = 10 * 1024 * 1024 * 1024
+
+				mockMounter.EXPECT().ExistsPath(VolumePath).Return(true, nil)
+				mockMounter.EXPECT().IsBlockDevice(VolumePath).Return(true, nil)
+				mockMounter.EXPECT().GetBlockSize(VolumePath).Return(deviceSize, nil)
+
+				oscDriver := nodeService{
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
+				}
+
+				req := &csi.NodeGetVolumeStatsRequest{
+					VolumeId:   "vol-test",
+					VolumePath: VolumePath,
+				}
+				resp, err := oscDriver.NodeGetVolumeStats(context.TODO(), req)
+				if err != nil {
+					t.Fatalf("Expect no error but got: %v", err)
+				}
+				expUsage := []*csi.VolumeUsage{
+					{
+						Unit:      csi.VolumeUsage_BYTES,
+						Available: deviceSize,
+						Total:     deviceSize,
+						Used:      0,
+					},
+				}
+				if !reflect.DeepEqual(resp.GetUsage(), expUsage) {
+					t.Fatalf("Expected usage %v, got %v", expUsage, resp.GetUsage())
+				}
+			},
+		},
 		{
 			name: "fail can't determine block device",
 			testFunc: func(t *testing.T) {
@@ -1629,11 +3644,13 @@ func TestNodeGetVolumeStats(t *testing.T) {
 				VolumePath := "/test"
 
 				mockMounter.EXPECT().ExistsPath(VolumePath).Return(true, nil)
+				mockMounter.EXPECT().IsBlockDevice(VolumePath).Return(false, errors.New("stat failed"))
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeGetVolumeStatsRequest{
@@ -1657,9 +3674,10 @@ func TestNodeGetVolumeStats(t *testing.T) {
 				mockMounter.EXPECT().ExistsPath(VolumePath).Return(false, errors.New("get existsPath call fail"))
 
 				oscDriver := nodeService{
-					metadata: mockMetadata,
-					mounter:  mockMounter,
-					inFlight: internal.NewInFlight(),
+					metadata:      mockMetadata,
+					mounter:       mockMounter,
+					inFlight:      internal.NewInFlight(),
+					driverOptions: &DriverOptions{},
 				}
 
 				req := &csi.NodeGetVolumeStatsRequest{
@@ -1686,9 +3704,10 @@ func TestNodeGetCapabilities(t *testing.T) {
 	mockMounter := mocks.NewMockMounter(mockCtl)
 
 	oscDriver := nodeService{
-		metadata: mockMetadata,
-		mounter:  mockMounter,
-		inFlight: internal.NewInFlight(),
+		metadata:      mockMetadata,
+		mounter:       mockMounter,
+		inFlight:      internal.NewInFlight(),
+		driverOptions: &DriverOptions{},
 	}
 
 	caps := []*csi.NodeServiceCapability{
@@ -1713,6 +3732,13 @@ func TestNodeGetCapabilities(t *testing.T) {
 				},
 			},
 		},
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+				},
+			},
+		},
 	}
 	expResp := &csi.NodeGetCapabilitiesResponse{Capabilities: caps}
 
@@ -1730,12 +3756,99 @@ func TestNodeGetCapabilities(t *testing.T) {
 	}
 }
 
+func TestNodeGetCapabilitiesWithoutStaging(t *testing.T) {
+	oscDriver := nodeService{
+		inFlight:      internal.NewInFlight(),
+		driverOptions: &DriverOptions{disableStaging: true},
+	}
+
+	resp, err := oscDriver.NodeGetCapabilities(context.TODO(), &csi.NodeGetCapabilitiesRequest{})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	for _, c := range resp.GetCapabilities() {
+		if c.GetRpc().GetType() == csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME {
+			t.Fatalf("Expected no STAGE_UNSTAGE_VOLUME capability, got %+v", resp.GetCapabilities())
+		}
+	}
+	if len(resp.GetCapabilities()) != len(nodeCaps)-1 {
+		t.Fatalf("Expected %d capabilities, got %+v", len(nodeCaps)-1, resp.GetCapabilities())
+	}
+}
+
+func TestNodeExpandVolume(t *testing.T) {
+	var (
+		volumePath = "/test/path"
+		devicePath = "/dev/xvdba"
+		deviceSize = int64(10 * 1024 * 1024 * 1024)
+	)
+	testCases := []struct {
+		name      string
+		tolerance int64
+		fsSize    int64
+		expResize bool
+	}{
+		{
+			name:      "success filesystem within tolerance is not resized",
+			tolerance: 1024 * 1024,
+			fsSize:    deviceSize - 4096,
+		},
+		{
+			name:      "success filesystem beyond tolerance is resized",
+			tolerance: 1024 * 1024,
+			fsSize:    deviceSize - 2*1024*1024,
+			expResize: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockMetadata := mocks.NewMockMetadataService(mockCtl)
+			mockMounter := mocks.NewMockMounter(mockCtl)
+
+			mockMounter.EXPECT().GetDeviceName(volumePath).Return(devicePath, 1, nil)
+			mockMounter.EXPECT().ExistsPath(devicePath).Return(true, nil)
+			mockMounter.EXPECT().IsLuksMapping(devicePath).Return(false, "", nil)
+			mockMounter.EXPECT().GetBlockSize(devicePath).Return(deviceSize, nil)
+			mockMounter.EXPECT().GetFilesystemSize(devicePath, volumePath).Return(tc.fsSize, nil)
+			if tc.expResize {
+				mockBlkid := mocks.NewMockCmd(mockCtl)
+				mockBlkid.EXPECT().CombinedOutput().Return([]byte("DEVNAME="+devicePath+"\nTYPE=ext4\n"), nil)
+				mockMounter.EXPECT().Command(gomock.Eq("blkid"), gomock.Any()).Return(mockBlkid)
+				mockResize := mocks.NewMockCmd(mockCtl)
+				mockResize.EXPECT().CombinedOutput().Return([]byte{}, nil)
+				mockMounter.EXPECT().Command(gomock.Eq("resize2fs"), gomock.Eq(devicePath)).Return(mockResize)
+			}
+
+			oscDriver := &nodeService{
+				metadata:      mockMetadata,
+				mounter:       mockMounter,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{fsResizeTolerance: tc.tolerance},
+			}
+
+			_, err := oscDriver.NodeExpandVolume(context.TODO(), &csi.NodeExpandVolumeRequest{
+				VolumeId:   "vol-test",
+				VolumePath: volumePath,
+			})
+			if err != nil {
+				t.Fatalf("Expect no error but got: %v", err)
+			}
+		})
+	}
+}
+
 func TestNodeGetInfo(t *testing.T) {
 	testCases := []struct {
 		name             string
 		instanceID       string
 		instanceType     string
 		availabilityZone string
+		region           string
+		reportTopology   bool
+		reportRegion     bool
 		expMaxVolumes    int64
 	}{
 		{
@@ -1743,6 +3856,7 @@ func TestNodeGetInfo(t *testing.T) {
 			instanceID:       "i-123456789abcdef01",
 			instanceType:     "t2.medium",
 			availabilityZone: "us-west-2b",
+			region:           "us-west-2",
 			expMaxVolumes:    defaultMaxBSUVolumes,
 		},
 		{
@@ -1750,6 +3864,25 @@ func TestNodeGetInfo(t *testing.T) {
 			instanceID:       "i-123456789abcdef01",
 			instanceType:     "m5d.large",
 			availabilityZone: "us-west-2b",
+			region:           "us-west-2",
+			expMaxVolumes:    defaultMaxBSUVolumes,
+		},
+		{
+			name:             "success with node topology",
+			instanceID:       "i-123456789abcdef01",
+			instanceType:     "t2.medium",
+			availabilityZone: "us-west-2b",
+			region:           "us-west-2",
+			reportTopology:   true,
+			expMaxVolumes:    defaultMaxBSUVolumes,
+		},
+		{
+			name:             "success with region topology",
+			instanceID:       "i-123456789abcdef01",
+			instanceType:     "t2.medium",
+			availabilityZone: "us-west-2b",
+			region:           "us-west-2",
+			reportRegion:     true,
 			expMaxVolumes:    defaultMaxBSUVolumes,
 		},
 	}
@@ -1759,15 +3892,19 @@ func TestNodeGetInfo(t *testing.T) {
 			defer mockCtl.Finish()
 
 			mockMetadata := mocks.NewMockMetadataService(mockCtl)
-			mockMetadata.EXPECT().GetInstanceID().Return(tc.instanceID)
+			mockMetadata.EXPECT().GetInstanceID().Return(tc.instanceID).MinTimes(1)
 			mockMetadata.EXPECT().GetAvailabilityZone().Return(tc.availabilityZone)
+			if tc.reportRegion {
+				mockMetadata.EXPECT().GetRegion().Return(tc.region)
+			}
 
 			mockMounter := mocks.NewMockMounter(mockCtl)
 
 			oscDriver := &nodeService{
-				metadata: mockMetadata,
-				mounter:  mockMounter,
-				inFlight: internal.NewInFlight(),
+				metadata:      mockMetadata,
+				mounter:       mockMounter,
+				inFlight:      internal.NewInFlight(),
+				driverOptions: &DriverOptions{reportNodeTopology: tc.reportTopology, reportRegionTopology: tc.reportRegion},
 			}
 
 			resp, err := oscDriver.NodeGetInfo(context.TODO(), &csi.NodeGetInfoRequest{})
@@ -1787,6 +3924,12 @@ func TestNodeGetInfo(t *testing.T) {
 			if at.Segments[TopologyKey] != tc.availabilityZone {
 				t.Fatalf("Expected topology %q, got %q", tc.availabilityZone, at.Segments[TopologyKey])
 			}
+			if region, ok := at.Segments[TopologyRegionKey]; ok != tc.reportRegion || (ok && region != tc.region) {
+				t.Fatalf("Expected region topology %q to be reported: %v, got %v", tc.region, tc.reportRegion, at.Segments)
+			}
+			if node, ok := at.Segments[TopologyNodeKey]; ok != tc.reportTopology || (ok && node != tc.instanceID) {
+				t.Fatalf("Expected node topology %q to be reported: %v, got %v", tc.instanceID, tc.reportTopology, at.Segments)
+			}
 
 			if resp.GetMaxVolumesPerNode() != tc.expMaxVolumes {
 				t.Fatalf("Expected %d max volumes per node, got %d", tc.expMaxVolumes, resp.GetMaxVolumesPerNode())
@@ -1795,6 +3938,87 @@ func TestNodeGetInfo(t *testing.T) {
 	}
 }
 
+func TestNormalizeMountOptions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		flags      []string
+		expOptions []string
+		expErr     bool
+	}{
+		{
+			name:       "no flags",
+			flags:      nil,
+			expOptions: nil,
+		},
+		{
+			name:       "duplicated flags",
+			flags:      []string{"noatime", "nodiratime,noatime", " nodiratime ", ""},
+			expOptions: []string{"noatime", "nodiratime"},
+		},
+		{
+			name:       "_netdev kept once",
+			flags:      []string{"_netdev", "noatime,_netdev"},
+			expOptions: []string{"_netdev", "noatime"},
+		},
+		{
+			name:       "comma in a quoted SELinux context",
+			flags:      []string{`noatime,context="system_u:object_r:container_file_t:s0:c1,c2"`, `context="system_u:object_r:container_file_t:s0:c1,c2"`},
+			expOptions: []string{"noatime", `context="system_u:object_r:container_file_t:s0:c1,c2"`},
+		},
+		{
+			name:   "conflicting atime flags",
+			flags:  []string{"atime", "noatime"},
+			expErr: true,
+		},
+		{
+			name:   "conflicting flags in the same option",
+			flags:  []string{"ro,rw"},
+			expErr: true,
+		},
+		{
+			name:   "conflicting access time update modes",
+			flags:  []string{"noatime", "relatime"},
+			expErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options, err := normalizeMountOptions(tc.flags)
+			if tc.expErr {
+				if err == nil {
+					t.Fatalf("Expected error, got nothing")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(options, tc.expOptions) {
+				t.Fatalf("Expected options %v, got %v", tc.expOptions, options)
+			}
+		})
+	}
+}
+
+func TestNewBytesUsage(t *testing.T) {
+	// 100 bytes filesystem with 5 bytes reserved to root
+	var capacity, used, available int64 = 100, 40, 55
+
+	usage := newBytesUsage(capacity, used, available, false)
+	if usage.Total != 100 || usage.Available != 55 || usage.Used != 40 {
+		t.Fatalf("Expected usage without adjustment %d/%d/%d, got %d/%d/%d", 100, 55, 40, usage.Total, usage.Available, usage.Used)
+	}
+
+	usage = newBytesUsage(capacity, used, available, true)
+	if usage.Total != 95 || usage.Available != 55 || usage.Used != 40 {
+		t.Fatalf("Expected usage with adjustment %d/%d/%d, got %d/%d/%d", 95, 55, 40, usage.Total, usage.Available, usage.Used)
+	}
+	if usage.Total != usage.Used+usage.Available {
+		t.Fatalf("Expected total to be used plus available, got %d", usage.Total)
+	}
+}
+
 func TestFindScsiName(t *testing.T) {
 	findScsiNameCase := []struct {
 		name                string
@@ -0,0 +1,57 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+)
+
+// performanceClass is the volume type and the IOPS per GiB of the volumes of a performance class.
+type performanceClass struct {
+	VolumeType string `json:"type"`
+	IopsPerGB  int    `json:"iopsPerGB,omitempty"`
+}
+
+// performanceClasses maps a performance class name to its settings.
+type performanceClasses map[string]performanceClass
+
+// loadPerformanceClasses reads the performance classes from a JSON file (usually a mounted ConfigMap) like:
+//
+//	{"fast": {"type": "io1", "iopsPerGB": 50}, "balanced": {"type": "gp2"}, "cold": {"type": "standard"}}
+//
+// An empty path returns no classes.
+func loadPerformanceClasses(path string) (performanceClasses, error) {
+	classes := performanceClasses{}
+	if path == "" {
+		return classes, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read performance classes file %q: %v", path, err)
+	}
+	if err := json.Unmarshal(content, &classes); err != nil {
+		return nil, fmt.Errorf("could not parse performance classes file %q: %v", path, err)
+	}
+	for name, class := range classes {
+		if !slices.Contains(cloud.ValidVolumeTypes, class.VolumeType) {
+			return nil, fmt.Errorf("invalid volume type %q in performance class %q", class.VolumeType, name)
+		}
+		if class.IopsPerGB < 0 {
+			return nil, fmt.Errorf("invalid iopsPerGB %d in performance class %q", class.IopsPerGB, name)
+		}
+	}
+	return classes, nil
+}
+
+// resolve returns the settings of the performance class.
+func (c performanceClasses) resolve(name string) (performanceClass, error) {
+	class, ok := c[name]
+	if !ok {
+		return performanceClass{}, fmt.Errorf("unknown performance class %q", name)
+	}
+	return class, nil
+}
@@ -0,0 +1,65 @@
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPerformanceClasses(t *testing.T) {
+	testCases := []struct {
+		name       string
+		content    string
+		expClasses performanceClasses
+		expErr     bool
+	}{
+		{
+			name:    "success",
+			content: `{"fast": {"type": "io1", "iopsPerGB": 50}, "balanced": {"type": "gp2"}, "cold": {"type": "standard"}}`,
+			expClasses: performanceClasses{
+				"fast":     {VolumeType: "io1", IopsPerGB: 50},
+				"balanced": {VolumeType: "gp2"},
+				"cold":     {VolumeType: "standard"},
+			},
+		},
+		{
+			name:    "fail invalid json",
+			content: `fast: io1`,
+			expErr:  true,
+		},
+		{
+			name:    "fail invalid volume type",
+			content: `{"fast": {"type": "ssd"}}`,
+			expErr:  true,
+		},
+		{
+			name:    "fail negative iops",
+			content: `{"fast": {"type": "io1", "iopsPerGB": -1}}`,
+			expErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "classes.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("could not write classes file: %v", err)
+			}
+
+			classes, err := loadPerformanceClasses(path)
+			if tc.expErr {
+				if err == nil {
+					t.Fatalf("Expected error, got nothing")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(classes, tc.expClasses) {
+				t.Fatalf("Expected classes %v, got %v", tc.expClasses, classes)
+			}
+		})
+	}
+}
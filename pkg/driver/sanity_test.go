@@ -3,10 +3,10 @@ package driver
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -22,7 +22,7 @@ import (
 
 func TestSanity(t *testing.T) {
 	// Setup the full driver and its environment
-	dir, err :=os.MkdirTemp("", "sanity-bsu-csi")
+	dir, err := os.MkdirTemp("", "sanity-bsu-csi")
 	if err != nil {
 		t.Fatalf("error creating directory %v", err)
 	}
@@ -41,8 +41,9 @@ func TestSanity(t *testing.T) {
 	config.CheckPath = checkPath
 
 	driverOptions := &DriverOptions{
-		endpoint: endpoint,
-		mode:     AllMode,
+		endpoint:    endpoint,
+		mode:        AllMode,
+		auditLogger: klogAuditLogger{},
 	}
 
 	drv := &Driver{
@@ -57,8 +58,9 @@ func TestSanity(t *testing.T) {
 				Region:           "region",
 				AvailabilityZone: "az",
 			},
-			mounter:  newFakeMounter(),
-			inFlight: internal.NewInFlight(),
+			mounter:       newFakeMounter(),
+			inFlight:      internal.NewInFlight(),
+			driverOptions: driverOptions,
 		},
 	}
 	defer func() {
@@ -164,12 +166,12 @@ func (c *fakeCloudProvider) DeleteDisk(ctx context.Context, volumeID string) (bo
 	return true, nil
 }
 
-func (c *fakeCloudProvider) AttachDisk(ctx context.Context, volumeID, nodeID string) (string, error) {
+func (c *fakeCloudProvider) AttachDisk(ctx context.Context, volumeID, nodeID, deviceName string) (string, error) {
 	c.pub[volumeID] = nodeID
 	return "/tmp", nil
 }
 
-func (c *fakeCloudProvider) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
+func (c *fakeCloudProvider) DetachDisk(ctx context.Context, volumeID, nodeID string, waitDetached bool) error {
 	return nil
 }
 
@@ -206,10 +208,39 @@ func (c *fakeCloudProvider) GetDiskByID(ctx context.Context, volumeID string) (c
 	return cloud.Disk{}, cloud.ErrNotFound
 }
 
+func (c *fakeCloudProvider) ListDisks(ctx context.Context, tags map[string]string) ([]cloud.Disk, error) {
+	var disks []cloud.Disk
+	for _, f := range c.disks {
+		disks = append(disks, f.Disk)
+	}
+	return disks, nil
+}
+
+func (c *fakeCloudProvider) GetDisksByIDs(ctx context.Context, volumeIDs []string) (map[string]cloud.Disk, error) {
+	disks := map[string]cloud.Disk{}
+	for _, f := range c.disks {
+		if slices.Contains(volumeIDs, f.Disk.VolumeID) {
+			disks[f.Disk.VolumeID] = f.Disk
+		}
+	}
+	return disks, nil
+}
+
 func (c *fakeCloudProvider) IsExistInstance(ctx context.Context, nodeID string) bool {
 	return nodeID == "instanceID"
 }
 
+func (c *fakeCloudProvider) GetAttachedDisks(ctx context.Context, nodeID string) ([]string, error) {
+	if !c.IsExistInstance(ctx, nodeID) {
+		return nil, cloud.ErrNotFound
+	}
+	return nil, nil
+}
+
+func (c *fakeCloudProvider) IsInstanceStopped(ctx context.Context, nodeID string) (bool, error) {
+	return false, nil
+}
+
 func (c *fakeCloudProvider) CreateSnapshot(ctx context.Context, volumeID string, snapshotOptions *cloud.SnapshotOptions) (snapshot cloud.Snapshot, err error) {
 	r1 := rand.New(rand.NewSource(time.Now().UnixNano()))
 	snapshotID := fmt.Sprintf("snapshot-%d", r1.Uint64())
@@ -292,6 +323,34 @@ func (c *fakeCloudProvider) ListSnapshots(ctx context.Context, volumeID string,
 
 }
 
+func (c *fakeCloudProvider) GetDeviceAllocations() map[string]map[string]string {
+	return map[string]map[string]string{}
+}
+
+func (c *fakeCloudProvider) ModifyDisk(ctx context.Context, volumeID string, volumeType string) error {
+	for _, f := range c.disks {
+		if f.Disk.VolumeID == volumeID {
+			return nil
+		}
+	}
+	return cloud.ErrNotFound
+}
+
+func (c *fakeCloudProvider) TagDisk(ctx context.Context, volumeID string, tags map[string]string) error {
+	for _, f := range c.disks {
+		if f.Disk.VolumeID == volumeID {
+			if f.tags == nil {
+				f.tags = map[string]string{}
+			}
+			for key, value := range tags {
+				f.tags[key] = value
+			}
+			return nil
+		}
+	}
+	return cloud.ErrNotFound
+}
+
 func (c *fakeCloudProvider) ResizeDisk(ctx context.Context, volumeID string, newSize int64) (int64, error) {
 	for volName, f := range c.disks {
 		if f.Disk.VolumeID == volumeID {
@@ -342,6 +401,21 @@ func (f *fakeMounter) MakeFile(pathname string) error {
 	return nil
 }
 
+func (f *fakeMounter) RemoveFile(pathname string) error {
+	if err := os.Remove(pathname); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (f *fakeMounter) ReadFile(pathname string) ([]byte, error) {
+	return os.ReadFile(pathname)
+}
+
+func (f *fakeMounter) WriteFile(pathname string, data []byte) error {
+	return os.WriteFile(pathname, data, os.FileMode(0644))
+}
+
 func (f *fakeMounter) MakeDir(pathname string) error {
 	err := os.MkdirAll(pathname, os.FileMode(0755))
 	if err != nil {
@@ -373,6 +447,42 @@ func (f *fakeMounter) IsCorruptedMnt(err error) bool {
 	return false
 }
 
+func (f *fakeMounter) GetBlockSize(devicePath string) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeMounter) GetFilesystemSize(devicePath string, mountPath string) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeMounter) IsBlockDevice(fullPath string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeMounter) GetMountOptions(mountPath string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeMounter) GetFilesystemUUID(devicePath string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeMounter) GetDevicesByFilesystemUUID(uuid string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeMounter) CheckFilesystem(device string) error {
+	return nil
+}
+
+func (f *fakeMounter) RepairFilesystem(device string, fsType string) error {
+	return nil
+}
+
+func (f *fakeMounter) SetVolumeGroup(mountPath string, gid int64) error {
+	return nil
+}
+
 func (m *fakeMounter) IsLuks(devicePath string) bool {
 	return false
 }
@@ -385,6 +495,10 @@ func (m *fakeMounter) CheckLuksPassphrase(devicePath string, passphrase string)
 	return true
 }
 
+func (m *fakeMounter) LuksSlotForPassphrase(devicePath string, passphrase string) (int, error) {
+	return 0, nil
+}
+
 func (m *fakeMounter) LuksOpen(devicePath string, encryptedDeviceName string, passphrase string) (bool, error) {
 	return true, nil
 }
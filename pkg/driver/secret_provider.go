@@ -0,0 +1,83 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrPassphraseNotFound is returned by a SecretProvider when it has no passphrase for the volume.
+var ErrPassphraseNotFound = errors.New("no passphrase key has been provided")
+
+// SecretProvider resolves the LUKS passphrase of a volume.
+type SecretProvider interface {
+	// GetPassphrase returns the passphrase of the volume. secrets are the secrets of the CSI request.
+	GetPassphrase(ctx context.Context, volumeID string, secrets map[string]string) (string, error)
+}
+
+// requestSecretProvider reads the passphrase from the secrets of the CSI request.
+type requestSecretProvider struct{}
+
+func (requestSecretProvider) GetPassphrase(ctx context.Context, volumeID string, secrets map[string]string) (string, error) {
+	passphrase, ok := secrets[LuksPassphraseKey]
+	if !ok {
+		return "", ErrPassphraseNotFound
+	}
+	return passphrase, nil
+}
+
+// externalSecretProvider fetches the passphrase from an external secret store:
+// GET <url>/<volumeID> returns the passphrase as the body of the response.
+type externalSecretProvider struct {
+	url    string
+	client *http.Client
+}
+
+func newExternalSecretProvider(url string, timeout time.Duration) *externalSecretProvider {
+	return &externalSecretProvider{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *externalSecretProvider) GetPassphrase(ctx context.Context, volumeID string, secrets map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"/"+url.PathEscape(volumeID), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch the passphrase of volume %s: %v", volumeID, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrPassphraseNotFound
+	default:
+		return "", fmt.Errorf("could not fetch the passphrase of volume %s: unexpected status %s", volumeID, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("could not read the passphrase of volume %s: %v", volumeID, err)
+	}
+	passphrase := strings.TrimRight(string(body), "\r\n")
+	if passphrase == "" {
+		return "", ErrPassphraseNotFound
+	}
+	return passphrase, nil
+}
+
+// newSecretProvider returns the external secret provider when its URL is set, or the provider reading the request secrets.
+func newSecretProvider(url string) SecretProvider {
+	if url == "" {
+		return requestSecretProvider{}
+	}
+	return newExternalSecretProvider(url, DefaultSecretProviderTimeout)
+}
@@ -0,0 +1,93 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestRequestSecretProvider(t *testing.T) {
+	provider := requestSecretProvider{}
+
+	passphrase, err := provider.GetPassphrase(context.Background(), "vol-test", map[string]string{LuksPassphraseKey: "thePassphrase"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if passphrase != "thePassphrase" {
+		t.Fatalf("Expected passphrase %q, got %q", "thePassphrase", passphrase)
+	}
+
+	if _, err := provider.GetPassphrase(context.Background(), "vol-test", nil); !errors.Is(err, ErrPassphraseNotFound) {
+		t.Fatalf("Expected error %v, got %v", ErrPassphraseNotFound, err)
+	}
+}
+
+func TestExternalSecretProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/passphrases/vol-test":
+			_, _ = w.Write([]byte("thePassphrase\n"))
+		case "/passphrases/vol-error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := newExternalSecretProvider(server.URL+"/passphrases/", time.Second)
+	ctx := context.Background()
+
+	// The request secrets are ignored
+	passphrase, err := provider.GetPassphrase(ctx, "vol-test", map[string]string{LuksPassphraseKey: "ignored"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if passphrase != "thePassphrase" {
+		t.Fatalf("Expected passphrase %q, got %q", "thePassphrase", passphrase)
+	}
+
+	if _, err := provider.GetPassphrase(ctx, "vol-unknown", nil); !errors.Is(err, ErrPassphraseNotFound) {
+		t.Fatalf("Expected error %v, got %v", ErrPassphraseNotFound, err)
+	}
+	if _, err := provider.GetPassphrase(ctx, "vol-error", nil); err == nil || errors.Is(err, ErrPassphraseNotFound) {
+		t.Fatalf("Expected server error, got %v", err)
+	}
+}
+
+type stubSecretProvider struct {
+	passphrases map[string]string
+}
+
+func (p stubSecretProvider) GetPassphrase(ctx context.Context, volumeID string, secrets map[string]string) (string, error) {
+	passphrase, ok := p.passphrases[volumeID]
+	if !ok {
+		return "", ErrPassphraseNotFound
+	}
+	return passphrase, nil
+}
+
+func TestGetPassphrase(t *testing.T) {
+	ctx := context.Background()
+
+	// Without provider, the passphrase is read from the request secrets
+	d := &nodeService{driverOptions: &DriverOptions{}}
+	passphrase, err := d.getPassphrase(ctx, "vol-test", map[string]string{LuksPassphraseKey: "thePassphrase"})
+	if err != nil || passphrase != "thePassphrase" {
+		t.Fatalf("Expected passphrase %q, got %q (%v)", "thePassphrase", passphrase, err)
+	}
+
+	d.secretProvider = stubSecretProvider{passphrases: map[string]string{"vol-test": "storedPassphrase"}}
+	passphrase, err = d.getPassphrase(ctx, "vol-test", map[string]string{LuksPassphraseKey: "thePassphrase"})
+	if err != nil || passphrase != "storedPassphrase" {
+		t.Fatalf("Expected passphrase %q, got %q (%v)", "storedPassphrase", passphrase, err)
+	}
+
+	_, err = d.getPassphrase(ctx, "vol-unknown", nil)
+	expectErr(t, err, codes.InvalidArgument)
+}
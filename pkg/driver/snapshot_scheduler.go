@@ -0,0 +1,167 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// SnapshotScheduleAnnotation is the PV annotation holding the interval between two
+	// scheduled snapshots (e.g. "24h").
+	SnapshotScheduleAnnotation = DriverName + "/snapshot-schedule"
+
+	// ScheduledSnapshotTagKey is the tag set on snapshots created by the scheduler.
+	// Only snapshots carrying this tag are considered for retention pruning.
+	ScheduledSnapshotTagKey = "CSIScheduledSnapshot"
+)
+
+// snapshotScheduler periodically snapshots the PVs annotated with SnapshotScheduleAnnotation.
+// It does not keep any state: the last scheduled snapshot of a volume is looked up in the cloud
+// on every pass, so that restarts of the controller do not lose or duplicate schedules.
+type snapshotScheduler struct {
+	cloud     cloud.Cloud
+	client    kubernetes.Interface
+	retention int
+	interval  time.Duration
+	clusterID string
+	now       func() time.Time
+}
+
+// newSnapshotScheduler creates a scheduler using the in-cluster Kubernetes configuration.
+func newSnapshotScheduler(c cloud.Cloud, driverOptions *DriverOptions) (*snapshotScheduler, error) {
+	client, err := newInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshotScheduler{
+		cloud:     c,
+		client:    client,
+		retention: driverOptions.snapshotScheduleRetention,
+		clusterID: driverOptions.clusterID,
+		interval:  driverOptions.snapshotScheduleInterval,
+		now:       time.Now,
+	}, nil
+}
+
+// newInClusterClient creates a Kubernetes client using the in-cluster configuration.
+func newInClusterClient() (kubernetes.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not get in-cluster config: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Kubernetes client: %v", err)
+	}
+	return client, nil
+}
+
+// Run reconciles the scheduled snapshots until the context is cancelled.
+func (s *snapshotScheduler) Run(ctx context.Context) {
+	klog.Infof("Starting snapshot scheduler with interval %v and retention %d", s.interval, s.retention)
+	wait.UntilWithContext(ctx, s.reconcile, s.interval)
+}
+
+func (s *snapshotScheduler) reconcile(ctx context.Context) {
+	pvs, err := s.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf("snapshotScheduler: could not list persistent volumes: %v", err)
+		return
+	}
+
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != DriverName {
+			continue
+		}
+		value, ok := pv.Annotations[SnapshotScheduleAnnotation]
+		if !ok {
+			continue
+		}
+		schedule, err := time.ParseDuration(value)
+		if err != nil || schedule <= 0 {
+			klog.Warningf("snapshotScheduler: invalid schedule %q on PV %s: %v", value, pv.Name, err)
+			continue
+		}
+		if err := s.reconcileVolume(ctx, pv.Spec.CSI.VolumeHandle, schedule); err != nil {
+			klog.Errorf("snapshotScheduler: could not reconcile PV %s: %v", pv.Name, err)
+		}
+	}
+}
+
+func (s *snapshotScheduler) reconcileVolume(ctx context.Context, volumeID string, schedule time.Duration) error {
+	resp, err := s.cloud.ListSnapshots(ctx, volumeID, 0, "")
+	if err != nil && err != cloud.ErrNotFound {
+		return err
+	}
+
+	var scheduled []cloud.Snapshot
+	for _, snapshot := range resp.Snapshots {
+		if _, ok := snapshot.Tags[ScheduledSnapshotTagKey]; ok {
+			scheduled = append(scheduled, snapshot)
+		}
+	}
+
+	now := s.now()
+	if shouldTakeScheduledSnapshot(scheduled, schedule, now) {
+		opts := &cloud.SnapshotOptions{
+			Tags: map[string]string{
+				cloud.SnapshotNameTagKey: fmt.Sprintf("scheduled-%s-%d", volumeID, now.Unix()),
+				ScheduledSnapshotTagKey:  "true",
+			},
+		}
+		if s.clusterID != "" {
+			opts.Tags[cloud.ClusterIDTagKey] = s.clusterID
+		}
+		snapshot, err := s.cloud.CreateSnapshot(ctx, volumeID, opts)
+		if err != nil {
+			return fmt.Errorf("could not create scheduled snapshot: %v", err)
+		}
+		klog.V(4).Infof("snapshotScheduler: created snapshot %s of volume %s", snapshot.SnapshotID, volumeID)
+		snapshot.CreationTime = now
+		scheduled = append(scheduled, snapshot)
+	}
+
+	for _, snapshot := range scheduledSnapshotsToPrune(scheduled, s.retention) {
+		klog.V(4).Infof("snapshotScheduler: pruning snapshot %s of volume %s", snapshot.SnapshotID, volumeID)
+		if _, err := s.cloud.DeleteSnapshot(ctx, snapshot.SnapshotID); err != nil && err != cloud.ErrNotFound {
+			return fmt.Errorf("could not prune snapshot %s: %v", snapshot.SnapshotID, err)
+		}
+	}
+	return nil
+}
+
+// shouldTakeScheduledSnapshot returns true when no scheduled snapshot was taken during the last schedule interval.
+func shouldTakeScheduledSnapshot(scheduled []cloud.Snapshot, schedule time.Duration, now time.Time) bool {
+	var last time.Time
+	for _, snapshot := range scheduled {
+		if snapshot.CreationTime.After(last) {
+			last = snapshot.CreationTime
+		}
+	}
+	return !now.Before(last.Add(schedule))
+}
+
+// scheduledSnapshotsToPrune returns the scheduled snapshots beyond the retention count, oldest first.
+// A retention lower or equal to 0 disables pruning.
+func scheduledSnapshotsToPrune(scheduled []cloud.Snapshot, retention int) []cloud.Snapshot {
+	if retention <= 0 || len(scheduled) <= retention {
+		return nil
+	}
+
+	sorted := make([]cloud.Snapshot, len(scheduled))
+	copy(sorted, scheduled)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreationTime.Before(sorted[j].CreationTime)
+	})
+	return sorted[:len(sorted)-retention]
+}
@@ -0,0 +1,124 @@
+package driver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/mocks"
+)
+
+func TestShouldTakeScheduledSnapshot(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		scheduled []cloud.Snapshot
+		schedule  time.Duration
+		expected  bool
+	}{
+		{
+			name:     "no previous snapshot",
+			schedule: 24 * time.Hour,
+			expected: true,
+		},
+		{
+			name: "last snapshot is recent",
+			scheduled: []cloud.Snapshot{
+				{SnapshotID: "snap-old", CreationTime: now.Add(-48 * time.Hour)},
+				{SnapshotID: "snap-recent", CreationTime: now.Add(-time.Hour)},
+			},
+			schedule: 24 * time.Hour,
+			expected: false,
+		},
+		{
+			name: "last snapshot is due",
+			scheduled: []cloud.Snapshot{
+				{SnapshotID: "snap-old", CreationTime: now.Add(-24 * time.Hour)},
+			},
+			schedule: 24 * time.Hour,
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := shouldTakeScheduledSnapshot(tc.scheduled, tc.schedule, now); got != tc.expected {
+				t.Fatalf("expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestScheduledSnapshotsToPrune(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	snapshots := []cloud.Snapshot{
+		{SnapshotID: "snap-2", CreationTime: now.Add(-2 * time.Hour)},
+		{SnapshotID: "snap-4", CreationTime: now.Add(-4 * time.Hour)},
+		{SnapshotID: "snap-1", CreationTime: now.Add(-1 * time.Hour)},
+		{SnapshotID: "snap-3", CreationTime: now.Add(-3 * time.Hour)},
+	}
+	testCases := []struct {
+		name      string
+		retention int
+		expected  []string
+	}{
+		{
+			name:      "retention disabled",
+			retention: 0,
+		},
+		{
+			name:      "under retention",
+			retention: 4,
+		},
+		{
+			name:      "over retention",
+			retention: 2,
+			expected:  []string{"snap-4", "snap-3"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, snapshot := range scheduledSnapshotsToPrune(snapshots, tc.retention) {
+				got = append(got, snapshot.SnapshotID)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSnapshotSchedulerReconcileVolume(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	volumeID := "vol-test"
+	scheduledTags := map[string]string{ScheduledSnapshotTagKey: "true"}
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().ListSnapshots(gomock.Any(), gomock.Eq(volumeID), gomock.Any(), gomock.Any()).Return(cloud.ListSnapshotsResponse{
+		Snapshots: []cloud.Snapshot{
+			{SnapshotID: "snap-manual", CreationTime: now.Add(-72 * time.Hour)},
+			{SnapshotID: "snap-old", CreationTime: now.Add(-48 * time.Hour), Tags: scheduledTags},
+			{SnapshotID: "snap-due", CreationTime: now.Add(-24 * time.Hour), Tags: scheduledTags},
+		},
+	}, nil)
+	mockCloud.EXPECT().CreateSnapshot(gomock.Any(), gomock.Eq(volumeID), gomock.Any()).Return(cloud.Snapshot{SnapshotID: "snap-new"}, nil)
+	mockCloud.EXPECT().DeleteSnapshot(gomock.Any(), gomock.Eq("snap-old")).Return(true, nil)
+
+	scheduler := &snapshotScheduler{
+		cloud:     mockCloud,
+		retention: 2,
+		now:       func() time.Time { return now },
+	}
+
+	if err := scheduler.reconcileVolume(context.TODO(), volumeID, 24*time.Hour); err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+}
@@ -18,6 +18,8 @@ package driver
 
 import (
 	"fmt"
+	"net"
+	"slices"
 	"strings"
 
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
@@ -28,10 +30,75 @@ func ValidateDriverOptions(options *DriverOptions) error {
 		return fmt.Errorf("Invalid extra volume tags: %v", err)
 	}
 
+	if err := validateExtraSnapshotTags(options.extraSnapshotTags); err != nil {
+		return fmt.Errorf("Invalid extra snapshot tags: %v", err)
+	}
+
+	if len(options.clusterID) > cloud.MaxTagValueLength {
+		return fmt.Errorf("Cluster ID too long (actual: %d, limit: %d)", len(options.clusterID), cloud.MaxTagValueLength)
+	}
+
+	if len(options.volumeNamePrefix) > cloud.MaxTagValueLength {
+		return fmt.Errorf("Volume name prefix too long (actual: %d, limit: %d)", len(options.volumeNamePrefix), cloud.MaxTagValueLength)
+	}
+
+	if options.disableSnapshots && options.enableSnapshotScheduler {
+		return fmt.Errorf("The snapshot scheduler cannot be enabled when snapshots are disabled")
+	}
+
+	if options.shutdownDrainTimeout < 0 {
+		return fmt.Errorf("The shutdown drain timeout must not be negative")
+	}
+
+	if options.enableVolumeReaper && options.clusterID == "" {
+		return fmt.Errorf("The volume reaper requires a cluster ID")
+	}
+
+	if options.enableAttachmentReconciler && options.clusterID == "" {
+		return fmt.Errorf("The attachment reconciler requires a cluster ID")
+	}
+
+	if options.enableVolumeReaper && options.volumeReaperGracePeriod <= 0 {
+		return fmt.Errorf("The grace period of the volume reaper must be positive")
+	}
+
+	if options.maxAttachesPerNode < 0 {
+		return fmt.Errorf("The maximum number of concurrent attaches per node must not be negative")
+	}
+
+	if options.maxVolumeSize < 0 {
+		return fmt.Errorf("The maximum volume size must not be negative")
+	}
+
+	if options.fsResizeTolerance < 0 {
+		return fmt.Errorf("The filesystem resize tolerance must not be negative")
+	}
+
+	if options.cascadeDeleteEndpoint != "" && !isLoopbackAddress(options.cascadeDeleteEndpoint) {
+		return fmt.Errorf("The cascade delete endpoint must be bound to a loopback address, got %q", options.cascadeDeleteEndpoint)
+	}
+
+	// The volumes are formatted and mounted by FormatAndMount at the target path of the pods without staging
+	if options.disableStaging && options.mountByUUID {
+		return fmt.Errorf("Mounting by filesystem UUID requires staging")
+	}
+
+	if options.disableStaging && options.formatTimeout > 0 {
+		return fmt.Errorf("The format timeout requires staging")
+	}
+
+	if err := validateZoneVolumeTypes(options.zoneVolumeTypes); err != nil {
+		return fmt.Errorf("Invalid zone volume types: %v", err)
+	}
+
 	if err := validateMode(options.mode); err != nil {
 		return fmt.Errorf("Invalid mode: %v", err)
 	}
 
+	if err := validateFSGroupPolicy(options.fsGroupPolicy); err != nil {
+		return fmt.Errorf("Invalid fsGroup policy: %v", err)
+	}
+
 	return nil
 }
 
@@ -61,6 +128,42 @@ func validateExtraVolumeTags(tags map[string]string) error {
 	return nil
 }
 
+func validateExtraSnapshotTags(tags map[string]string) error {
+	if len(tags) > cloud.MaxNumTagsPerResource {
+		return fmt.Errorf("Too many snapshot tags (actual: %d, limit: %d)", len(tags), cloud.MaxNumTagsPerResource)
+	}
+
+	for k, v := range tags {
+		if len(k) > cloud.MaxTagKeyLength {
+			return fmt.Errorf("Snapshot tag key too long (actual: %d, limit: %d)", len(k), cloud.MaxTagKeyLength)
+		}
+		if len(v) > cloud.MaxTagValueLength {
+			return fmt.Errorf("Snapshot tag value too long (actual: %d, limit: %d)", len(v), cloud.MaxTagValueLength)
+		}
+		if k == cloud.SnapshotNameTagKey {
+			return fmt.Errorf("Snapshot tag key '%s' is reserved", cloud.SnapshotNameTagKey)
+		}
+		if strings.HasPrefix(k, cloud.KubernetesTagKeyPrefix) {
+			return fmt.Errorf("Snapshot tag key prefix '%s' is reserved", cloud.KubernetesTagKeyPrefix)
+		}
+		if strings.HasPrefix(k, cloud.OscTagKeyPrefix) {
+			return fmt.Errorf("Snapshot tag key prefix '%s' is reserved", cloud.OscTagKeyPrefix)
+		}
+	}
+
+	return nil
+}
+
+func validateZoneVolumeTypes(types map[string]string) error {
+	supported := []string{cloud.VolumeTypeSTANDARD, cloud.VolumeTypeGP2, cloud.VolumeTypeIO1}
+	for zone, volumeType := range types {
+		if !slices.Contains(supported, volumeType) {
+			return fmt.Errorf("Volume type of zone %s is not supported (actual: %s, supported: %v)", zone, volumeType, supported)
+		}
+	}
+	return nil
+}
+
 func validateMode(mode Mode) error {
 	if mode != AllMode && mode != ControllerMode && mode != NodeMode {
 		return fmt.Errorf("Mode is not supported (actual: %s, supported: %v)", mode, []Mode{AllMode, ControllerMode, NodeMode})
@@ -68,3 +171,45 @@ func validateMode(mode Mode) error {
 
 	return nil
 }
+
+func validateFSGroupPolicy(policy string) error {
+	switch policy {
+	case "", FSGroupPolicyReadWriteOnceWithFSType, FSGroupPolicyFile, FSGroupPolicyNone:
+		return nil
+	}
+	return fmt.Errorf("Policy is not supported (actual: %s, supported: %v)", policy, []string{FSGroupPolicyReadWriteOnceWithFSType, FSGroupPolicyFile, FSGroupPolicyNone})
+}
+
+// validateJournalMode checks that mode is a journaling mode supported by the filesystem fsType.
+func validateJournalMode(mode, fsType string) error {
+	switch mode {
+	case JournalModeJournal, JournalModeOrdered, JournalModeWriteback:
+	default:
+		return fmt.Errorf("Journal mode is not supported (actual: %s, supported: %v)", mode, []string{JournalModeJournal, JournalModeOrdered, JournalModeWriteback})
+	}
+	if fsType != FSTypeExt3 && fsType != FSTypeExt4 {
+		return fmt.Errorf("Journal mode is not supported by fstype %s (supported: %v)", fsType, []string{FSTypeExt3, FSTypeExt4})
+	}
+	return nil
+}
+
+// validatePreallocate checks that the filesystem fsType can be formatted with preallocation.
+func validatePreallocate(fsType string) error {
+	if fsType != FSTypeExt3 && fsType != FSTypeExt4 {
+		return fmt.Errorf("Preallocation is not supported by fstype %s (supported: %v)", fsType, []string{FSTypeExt3, FSTypeExt4})
+	}
+	return nil
+}
+
+// isLoopbackAddress checks that the listen address addr is bound to the loopback interface.
+func isLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
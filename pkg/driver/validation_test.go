@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
 )
@@ -109,6 +110,50 @@ func TestValidateExtraVolumeTags(t *testing.T) {
 	}
 }
 
+func TestValidateExtraSnapshotTags(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tags   map[string]string
+		expErr error
+	}{
+		{
+			name: "valid tags",
+			tags: map[string]string{
+				"extra-tag-key": "extra-tag-value",
+			},
+			expErr: nil,
+		},
+		{
+			name: "invalid tag: reserved CSI key",
+			tags: map[string]string{
+				cloud.SnapshotNameTagKey: "extra-tag-value",
+			},
+			expErr: fmt.Errorf("Snapshot tag key '%s' is reserved", cloud.SnapshotNameTagKey),
+		},
+		{
+			name: "invalid tag: reserved Kubernetes key prefix",
+			tags: map[string]string{
+				cloud.KubernetesTagKeyPrefix + "/cluster": "extra-tag-value",
+			},
+			expErr: fmt.Errorf("Snapshot tag key prefix '%s' is reserved", cloud.KubernetesTagKeyPrefix),
+		},
+		{
+			name:   "invalid tag: too many snapshot tags",
+			tags:   randomStringMap(cloud.MaxNumTagsPerResource + 1),
+			expErr: fmt.Errorf("Too many snapshot tags (actual: %d, limit: %d)", cloud.MaxNumTagsPerResource+1, cloud.MaxNumTagsPerResource),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateExtraSnapshotTags(tc.tags)
+			if !reflect.DeepEqual(err, tc.expErr) {
+				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
+
 func TestValidateMode(t *testing.T) {
 	testCases := []struct {
 		name   string
@@ -152,6 +197,8 @@ func TestValidateDriverOptions(t *testing.T) {
 		name            string
 		mode            Mode
 		extraVolumeTags map[string]string
+		fsGroupPolicy   string
+		zoneVolumeTypes map[string]string
 		expErr          error
 	}{
 		{
@@ -172,6 +219,18 @@ func TestValidateDriverOptions(t *testing.T) {
 			},
 			expErr: fmt.Errorf("Invalid extra volume tags: Volume tag key too long (actual: %d, limit: %d)", cloud.MaxTagKeyLength+1, cloud.MaxTagKeyLength),
 		},
+		{
+			name:          "fail because validateFSGroupPolicy fails",
+			mode:          NodeMode,
+			fsGroupPolicy: "Always",
+			expErr:        fmt.Errorf("Invalid fsGroup policy: Policy is not supported (actual: Always, supported: %v)", []string{FSGroupPolicyReadWriteOnceWithFSType, FSGroupPolicyFile, FSGroupPolicyNone}),
+		},
+		{
+			name:            "fail because validateZoneVolumeTypes fails",
+			mode:            ControllerMode,
+			zoneVolumeTypes: map[string]string{"eu-west-2a": "gp3"},
+			expErr:          fmt.Errorf("Invalid zone volume types: Volume type of zone eu-west-2a is not supported (actual: gp3, supported: %v)", []string{cloud.VolumeTypeSTANDARD, cloud.VolumeTypeGP2, cloud.VolumeTypeIO1}),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -179,6 +238,8 @@ func TestValidateDriverOptions(t *testing.T) {
 			err := ValidateDriverOptions(&DriverOptions{
 				extraVolumeTags: tc.extraVolumeTags,
 				mode:            tc.mode,
+				fsGroupPolicy:   tc.fsGroupPolicy,
+				zoneVolumeTypes: tc.zoneVolumeTypes,
 			})
 			if !reflect.DeepEqual(err, tc.expErr) {
 				t.Fatalf("error not equal\ngot:\n%s\nexpected:\n%s", err, tc.expErr)
@@ -186,3 +247,78 @@ func TestValidateDriverOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateVolumeReaperOptions(t *testing.T) {
+	options := &DriverOptions{
+		mode:                    ControllerMode,
+		enableVolumeReaper:      true,
+		volumeReaperGracePeriod: DefaultVolumeReaperGracePeriod,
+	}
+	if err := ValidateDriverOptions(options); err == nil {
+		t.Fatal("Expected an error without cluster ID, got nothing")
+	}
+
+	options.clusterID = "cluster-test"
+	if err := ValidateDriverOptions(options); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestValidateAttachmentReconcilerOptions(t *testing.T) {
+	options := &DriverOptions{
+		mode:                       ControllerMode,
+		enableAttachmentReconciler: true,
+	}
+	if err := ValidateDriverOptions(options); err == nil {
+		t.Fatal("Expected an error without cluster ID, got nothing")
+	}
+
+	options.clusterID = "cluster-test"
+	if err := ValidateDriverOptions(options); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestValidateDisableStagingOptions(t *testing.T) {
+	options := &DriverOptions{
+		mode:           NodeMode,
+		disableStaging: true,
+		mountByUUID:    true,
+	}
+	if err := ValidateDriverOptions(options); err == nil {
+		t.Fatal("Expected an error with the mount by UUID, got nothing")
+	}
+
+	options.mountByUUID = false
+	options.formatTimeout = time.Minute
+	if err := ValidateDriverOptions(options); err == nil {
+		t.Fatal("Expected an error with the format timeout, got nothing")
+	}
+
+	options.formatTimeout = 0
+	if err := ValidateDriverOptions(options); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestValidateCascadeDeleteOptions(t *testing.T) {
+	for _, endpoint := range []string{":8091", "0.0.0.0:8091", "10.0.0.1:8091", "localhost"} {
+		options := &DriverOptions{
+			mode:                  ControllerMode,
+			cascadeDeleteEndpoint: endpoint,
+		}
+		if err := ValidateDriverOptions(options); err == nil {
+			t.Fatalf("Expected an error for endpoint %q, got nothing", endpoint)
+		}
+	}
+
+	for _, endpoint := range []string{"localhost:8091", "127.0.0.1:8091", "[::1]:8091"} {
+		options := &DriverOptions{
+			mode:                  ControllerMode,
+			cascadeDeleteEndpoint: endpoint,
+		}
+		if err := ValidateDriverOptions(options); err != nil {
+			t.Fatalf("Expected no error for endpoint %q, got %v", endpoint, err)
+		}
+	}
+}
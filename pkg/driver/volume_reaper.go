@@ -0,0 +1,113 @@
+package driver
+
+import (
+	"context"
+	"time"
+
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// volumeReaper deletes the volumes created by the driver but never bound to a PV, which are left behind
+// when the controller is restarted in the middle of a CreateVolume. To stay on the safe side, a volume is
+// only reaped when it carries the cluster ID tag, is not attached, is older than the grace period and no PV
+// of the driver references it. The volumes bound to a PV once are marked with the bound tag, by the reaper
+// or on their first attachment, and never reaped: they are left behind on purpose by the Retain reclaim
+// policy. In dry-run mode, the orphaned volumes are only logged.
+type volumeReaper struct {
+	cloud       cloud.Cloud
+	client      kubernetes.Interface
+	clusterID   string
+	gracePeriod time.Duration
+	interval    time.Duration
+	dryRun      bool
+	now         func() time.Time
+}
+
+// newVolumeReaper creates a reaper using the in-cluster Kubernetes configuration.
+func newVolumeReaper(c cloud.Cloud, driverOptions *DriverOptions) (*volumeReaper, error) {
+	client, err := newInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &volumeReaper{
+		cloud:       c,
+		client:      client,
+		clusterID:   driverOptions.clusterID,
+		gracePeriod: driverOptions.volumeReaperGracePeriod,
+		interval:    DefaultVolumeReaperInterval,
+		dryRun:      driverOptions.volumeReaperDryRun,
+		now:         time.Now,
+	}, nil
+}
+
+// Run reaps the orphaned volumes until the context is cancelled.
+func (r *volumeReaper) Run(ctx context.Context) {
+	klog.Infof("Starting volume reaper with grace period %v (dry-run: %v)", r.gracePeriod, r.dryRun)
+	wait.UntilWithContext(ctx, r.reconcile, r.interval)
+}
+
+func (r *volumeReaper) reconcile(ctx context.Context) {
+	orphans, err := r.findOrphanedVolumes(ctx)
+	if err != nil {
+		klog.Errorf("volumeReaper: could not look for orphaned volumes: %v", err)
+		return
+	}
+
+	for _, disk := range orphans {
+		name := disk.Tags[cloud.VolumeNameTagKey]
+		if r.dryRun {
+			klog.Infof("volumeReaper: volume %s (%s) is not referenced by any PV, not deleted in dry-run mode", disk.VolumeID, name)
+			continue
+		}
+		klog.Infof("volumeReaper: deleting volume %s (%s), not referenced by any PV", disk.VolumeID, name)
+		if _, err := r.cloud.DeleteDisk(ctx, disk.VolumeID); err != nil && err != cloud.ErrNotFound {
+			klog.Errorf("volumeReaper: could not delete volume %s: %v", disk.VolumeID, err)
+		}
+	}
+}
+
+// findOrphanedVolumes returns the volumes of the cluster which can be reaped, and marks with the bound tag
+// the volumes referenced by a PV.
+func (r *volumeReaper) findOrphanedVolumes(ctx context.Context) ([]cloud.Disk, error) {
+	// The volumes are listed before the PVs, so that a volume provisioned in between is always seen with its PV.
+	disks, err := r.cloud.ListDisks(ctx, map[string]string{cloud.ClusterIDTagKey: r.clusterID})
+	if err != nil {
+		return nil, err
+	}
+	pvs, err := r.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool, len(pvs.Items))
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == DriverName {
+			referenced[pv.Spec.CSI.VolumeHandle] = true
+		}
+	}
+
+	now := r.now()
+	var orphans []cloud.Disk
+	for _, disk := range disks {
+		switch {
+		case referenced[disk.VolumeID]:
+			if disk.Tags[cloud.BoundTagKey] == "" {
+				if err := r.cloud.TagDisk(ctx, disk.VolumeID, map[string]string{cloud.BoundTagKey: "true"}); err != nil {
+					klog.Errorf("volumeReaper: could not mark volume %s as bound: %v", disk.VolumeID, err)
+				}
+			}
+		case disk.Tags[cloud.BoundTagKey] != "":
+		case disk.Tags[cloud.VolumeNameTagKey] == "" || disk.Tags[cloud.ClusterIDTagKey] != r.clusterID:
+		case disk.State != "available":
+		case disk.CreationTime.IsZero() || now.Sub(disk.CreationTime) < r.gracePeriod:
+		default:
+			orphans = append(orphans, disk)
+		}
+	}
+	return orphans, nil
+}
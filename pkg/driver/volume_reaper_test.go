@@ -0,0 +1,134 @@
+package driver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/cloud"
+	"github.com/outscale-dev/osc-bsu-csi-driver/pkg/driver/mocks"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newCSIPersistentVolume(name, driver, volumeHandle string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: volumeHandle},
+			},
+		},
+	}
+}
+
+func TestVolumeReaperFindOrphanedVolumes(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	newDisk := func(volumeID, state string, age time.Duration, clusterID string) cloud.Disk {
+		return cloud.Disk{
+			VolumeID:     volumeID,
+			State:        state,
+			CreationTime: now.Add(-age),
+			Tags: map[string]string{
+				cloud.VolumeNameTagKey: "pvc-" + volumeID,
+				cloud.ClusterIDTagKey:  clusterID,
+			},
+		}
+	}
+	disks := []cloud.Disk{
+		newDisk("vol-bound", "available", 48*time.Hour, "cluster-test"),
+		newDisk("vol-other-driver", "available", 48*time.Hour, "cluster-test"),
+		newDisk("vol-orphan", "available", 48*time.Hour, "cluster-test"),
+		newDisk("vol-recent", "available", time.Hour, "cluster-test"),
+		newDisk("vol-attached", "in-use", 48*time.Hour, "cluster-test"),
+		newDisk("vol-other-cluster", "available", 48*time.Hour, "cluster-other"),
+		newDisk("vol-retained", "available", 48*time.Hour, "cluster-test"),
+	}
+	disks[len(disks)-1].Tags[cloud.BoundTagKey] = "true"
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	mockCloud.EXPECT().ListDisks(gomock.Any(), gomock.Eq(map[string]string{cloud.ClusterIDTagKey: "cluster-test"})).Return(disks, nil)
+	mockCloud.EXPECT().TagDisk(gomock.Any(), gomock.Eq("vol-bound"), gomock.Eq(map[string]string{cloud.BoundTagKey: "true"})).Return(nil)
+
+	reaper := &volumeReaper{
+		cloud: mockCloud,
+		client: fake.NewSimpleClientset(
+			newCSIPersistentVolume("pv-bound", DriverName, "vol-bound"),
+			newCSIPersistentVolume("pv-other-driver", "other.csi.driver", "vol-other-driver"),
+		),
+		clusterID:   "cluster-test",
+		gracePeriod: 24 * time.Hour,
+		now:         func() time.Time { return now },
+	}
+
+	orphans, err := reaper.findOrphanedVolumes(context.Background())
+	if err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+	var orphanIDs []string
+	for _, disk := range orphans {
+		orphanIDs = append(orphanIDs, disk.VolumeID)
+	}
+	expected := []string{"vol-other-driver", "vol-orphan"}
+	if !reflect.DeepEqual(orphanIDs, expected) {
+		t.Fatalf("Expected orphaned volumes %v, got %v", expected, orphanIDs)
+	}
+}
+
+func TestVolumeReaperDryRun(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	orphan := cloud.Disk{
+		VolumeID:     "vol-orphan",
+		State:        "available",
+		CreationTime: now.Add(-48 * time.Hour),
+		Tags: map[string]string{
+			cloud.VolumeNameTagKey: "pvc-orphan",
+			cloud.ClusterIDTagKey:  "cluster-test",
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		dryRun bool
+	}{
+		{
+			name:   "dry-run does not delete",
+			dryRun: true,
+		},
+		{
+			name:   "delete orphaned volume",
+			dryRun: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().ListDisks(gomock.Any(), gomock.Any()).Return([]cloud.Disk{orphan}, nil)
+			if tc.dryRun {
+				mockCloud.EXPECT().DeleteDisk(gomock.Any(), gomock.Any()).Times(0)
+			} else {
+				mockCloud.EXPECT().DeleteDisk(gomock.Any(), gomock.Eq("vol-orphan")).Return(true, nil)
+			}
+
+			reaper := &volumeReaper{
+				cloud:       mockCloud,
+				client:      fake.NewSimpleClientset(),
+				clusterID:   "cluster-test",
+				gracePeriod: 24 * time.Hour,
+				dryRun:      tc.dryRun,
+				now:         func() time.Time { return now },
+			}
+			reaper.reconcile(context.Background())
+		})
+	}
+}